@@ -20,8 +20,10 @@ import (
 	_ "github.com/iwooyun/bee/cmd/commands/api"
 	_ "github.com/iwooyun/bee/cmd/commands/bale"
 	_ "github.com/iwooyun/bee/cmd/commands/beefix"
+	_ "github.com/iwooyun/bee/cmd/commands/db"
 	_ "github.com/iwooyun/bee/cmd/commands/dlv"
 	_ "github.com/iwooyun/bee/cmd/commands/dockerize"
+	_ "github.com/iwooyun/bee/cmd/commands/docs"
 	_ "github.com/iwooyun/bee/cmd/commands/generate"
 	_ "github.com/iwooyun/bee/cmd/commands/hprose"
 	_ "github.com/iwooyun/bee/cmd/commands/migrate"
@@ -39,7 +41,8 @@ func IfGenerateDocs(name string, args []string) bool {
 		return false
 	}
 	for _, a := range args {
-		if a == "docs" {
+		// The tests are generated out of the same analysis as the docs
+		if a == "docs" || a == "test" {
 			return true
 		}
 	}
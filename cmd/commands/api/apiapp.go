@@ -16,7 +16,6 @@ package apiapp
 
 import (
 	"fmt"
-	"os"
 	path "path/filepath"
 	"strings"
 
@@ -538,12 +537,14 @@ func init() {
 	CmdApiapp.Flag.Var(&generate.Tables, "tables", "List of table names separated by a comma.")
 	CmdApiapp.Flag.Var(&generate.SQLDriver, "driver", "Database driver. Either mysql, postgres or sqlite.")
 	CmdApiapp.Flag.Var(&generate.SQLConn, "conn", "Connection string used by the driver to connect to a database instance.")
+	CmdApiapp.AddGenFlags()
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdApiapp)
 }
 
 func createAPI(cmd *commands.Command, args []string) int {
 	output := cmd.Out()
 
+	args = cmd.ParseGenFlags(args)
 	if len(args) < 1 {
 		beeLogger.Log.Fatal("Argument [appname] is missing")
 	}
@@ -566,30 +567,34 @@ func createAPI(cmd *commands.Command, args []string) int {
 
 	beeLogger.Log.Info("Creating API...")
 
-	os.MkdirAll(appPath, 0755)
+	utils.MkdirGenerated(appPath)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", appPath, "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "conf"), 0755)
+	if utils.NeedGoMod(appPath) {
+		utils.WriteGoMod(appPath, packPath)
+	}
+	utils.MkdirGenerated(path.Join(appPath, "conf"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf"), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "controllers"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "controllers"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers"), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "tests"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "tests"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "tests"), "\x1b[0m")
 
 	if generate.SQLConn != "" {
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf", "app.conf"), "\x1b[0m")
+		generate.SQLDriver = utils.DocValue(utils.SQLDriverName(generate.SQLDriver.String()))
 		confContent := strings.Replace(apiconf, "{{.Appname}}", appName, -1)
 		confContent = strings.Replace(confContent, "{{.SQLConnStr}}", generate.SQLConn.String(), -1)
-		utils.WriteToFile(path.Join(appPath, "conf", "app.conf"), confContent)
+		utils.WriteGeneratedFile(path.Join(appPath, "conf", "app.conf"), confContent)
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "main.go"), "\x1b[0m")
 		mainGoContent := strings.Replace(apiMainconngo, "{{.Appname}}", packPath, -1)
 		mainGoContent = strings.Replace(mainGoContent, "{{.DriverName}}", string(generate.SQLDriver), -1)
 		if generate.SQLDriver == "mysql" {
 			mainGoContent = strings.Replace(mainGoContent, "{{.DriverPkg}}", `_ "github.com/go-sql-driver/mysql"`, -1)
 		} else if generate.SQLDriver == "postgres" {
 			mainGoContent = strings.Replace(mainGoContent, "{{.DriverPkg}}", `_ "github.com/lib/pq"`, -1)
+		} else if generate.SQLDriver == "sqlite3" {
+			mainGoContent = strings.Replace(mainGoContent, "{{.DriverPkg}}", `_ "github.com/mattn/go-sqlite3"`, -1)
 		}
-		utils.WriteToFile(path.Join(appPath, "main.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "main.go"),
 			strings.Replace(
 				mainGoContent,
 				"{{.conn}}",
@@ -600,44 +605,40 @@ func createAPI(cmd *commands.Command, args []string) int {
 		beeLogger.Log.Infof("Using '%s' as 'driver'", generate.SQLDriver)
 		beeLogger.Log.Infof("Using '%s' as 'conn'", generate.SQLConn)
 		beeLogger.Log.Infof("Using '%s' as 'tables'", generate.Tables)
-		generate.GenerateAppcode(string(generate.SQLDriver), string(generate.SQLConn), "3", string(generate.Tables), appPath)
+		generate.GenerateAppcode(string(generate.SQLDriver), string(generate.SQLConn), "3", string(generate.Tables), "", "", appPath)
 	} else {
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf", "app.conf"), "\x1b[0m")
 		confContent := strings.Replace(apiconf, "{{.Appname}}", appName, -1)
 		confContent = strings.Replace(confContent, "{{.SQLConnStr}}", "", -1)
-		utils.WriteToFile(path.Join(appPath, "conf", "app.conf"), confContent)
+		utils.WriteGeneratedFile(path.Join(appPath, "conf", "app.conf"), confContent)
 
-		os.Mkdir(path.Join(appPath, "models"), 0755)
+		utils.MkdirGenerated(path.Join(appPath, "models"))
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "models"), "\x1b[0m")
-		os.Mkdir(path.Join(appPath, "routers"), 0755)
+		utils.MkdirGenerated(path.Join(appPath, "routers"))
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "routers")+string(path.Separator), "\x1b[0m")
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers", "object.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "controllers", "object.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "controllers", "object.go"),
 			strings.Replace(apiControllers, "{{.Appname}}", packPath, -1))
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers", "user.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "controllers", "user.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "controllers", "user.go"),
 			strings.Replace(apiControllers2, "{{.Appname}}", packPath, -1))
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "tests", "default_test.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "tests", "default_test.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "tests", "default_test.go"),
 			strings.Replace(apiTests, "{{.Appname}}", packPath, -1))
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "routers", "router.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "routers", "router.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "routers", "router.go"),
 			strings.Replace(apirouter, "{{.Appname}}", packPath, -1))
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "models", "object.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "models", "object.go"), APIModels)
+		utils.WriteGeneratedFile(path.Join(appPath, "models", "object.go"), APIModels)
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "models", "user.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "models", "user.go"), APIModels2)
+		utils.WriteGeneratedFile(path.Join(appPath, "models", "user.go"), APIModels2)
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "main.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(appPath, "main.go"),
+		utils.WriteGeneratedFile(path.Join(appPath, "main.go"),
 			strings.Replace(apiMaingo, "{{.Appname}}", packPath, -1))
 	}
+	if utils.IsExist(path.Join(appPath, "go.mod")) {
+		beeLogger.Log.Hint("Run 'go mod tidy' inside the application to resolve its dependencies")
+	}
+	utils.PrintGenSummary()
 	beeLogger.Log.Success("New API successfully created!")
 	return 0
 }
@@ -4,8 +4,10 @@ import (
 	"flag"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
+	beeLogger "github.com/iwooyun/bee/logger"
 	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 )
@@ -92,3 +94,34 @@ func (c *Command) Options() map[string]string {
 	})
 	return options
 }
+
+// AddGenFlags registers the -force, -skip-existing and -dry-run flags of the generators
+func (c *Command) AddGenFlags() {
+	c.Flag.BoolVar(&utils.GenFiles.Force, "force", false, "Overwrite the existing files without asking.")
+	c.Flag.BoolVar(&utils.GenFiles.SkipExisting, "skip-existing", false, "Keep the existing files without asking.")
+	c.Flag.BoolVar(&utils.GenFiles.DryRun, "dry-run", false, "Print the diff of the generated files instead of writing them.")
+}
+
+// ParseGenFlags sets the flags registered by AddGenFlags which are found among args,
+// i.e. after the positional arguments, and returns the other arguments
+func (c *Command) ParseGenFlags(args []string) (rest []string) {
+	for _, arg := range args {
+		name, value := strings.TrimLeft(arg, "-"), "true"
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		f := c.Flag.Lookup(name)
+		if !strings.HasPrefix(arg, "-") || f == nil || (name != "force" && name != "skip-existing" && name != "dry-run") {
+			rest = append(rest, arg)
+			continue
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			beeLogger.Log.Fatalf("Invalid value '%s' of flag -%s", value, name)
+		}
+		f.Value.Set(value)
+	}
+	if utils.GenFiles.Force && utils.GenFiles.SkipExisting {
+		beeLogger.Log.Fatal("Flags -force and -skip-existing cannot be used together")
+	}
+	return
+}
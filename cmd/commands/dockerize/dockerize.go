@@ -98,6 +98,10 @@ func dockerizeApp(cmd *commands.Command, args []string) int {
 	}
 
 	appdir := strings.Replace(dir, gopath, "", 1)
+	if mod := utils.GetModule(dir); mod != nil {
+		// Modules may live anywhere, place the sources under $GOPATH/src by import path
+		appdir = "/src/" + mod.ImportPath(dir)
+	}
 
 	// In case of multiple ports to expose inside the container,
 	// replace all the commas with whitespaces.
@@ -0,0 +1,199 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package docs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iwooyun/bee/cmd/commands"
+	"github.com/iwooyun/bee/generate/swaggergen"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+var CmdDocs = &commands.Command{
+	UsageLine: "docs [command]",
+	Short:     "Manages the published swagger documents",
+	Long: `The command 'docs' works on the swagger documents published by 'bee generate docs'
+to the doc store configured in the 'docs' section of Beefile or bee.json.
+
+  ▶ {{"To list the published versions:"|bold}}
+
+    $ bee docs history
+
+  ▶ {{"To print the document of a published version:"|bold}}
+
+    $ bee docs show [version]
+
+  ▶ {{"To make a previously published version the current one again:"|bold}}
+
+    $ bee docs rollback [version]
+
+  ▶ {{"To list the breaking and non-breaking changes between two documents:"|bold}}
+
+    $ bee docs diff [-json=report.json] [old] [new]
+
+    Documents are either files or published versions. The old document defaults to the latest
+    published version and the new one to swagger/swagger.json. The command exits with status 1
+    when breaking changes are found. Use -json=- to print the JSON report instead of the human one.
+`,
+	Run: RunDocs,
+}
+
+var jsonReport utils.DocValue
+
+func init() {
+	CmdDocs.Flag.Var(&jsonReport, "json", "File the JSON report of 'bee docs diff' is written to, - for the standard output.")
+	commands.AvailableCommands = append(commands.AvailableCommands, CmdDocs)
+}
+
+// RunDocs is the entry point of the docs command
+func RunDocs(cmd *commands.Command, args []string) int {
+	currpath, _ := os.Getwd()
+	if len(args) < 1 {
+		beeLogger.Log.Fatal("Command is missing")
+	}
+
+	if args[0] == "diff" {
+		cmd.Flag.Parse(args[1:])
+		return diff(currpath, cmd.Flag.Args())
+	}
+
+	store, err := swaggergen.NewDocHistory(currpath)
+	if err != nil {
+		beeLogger.Log.Fatalf("%s", err)
+	}
+
+	switch args[0] {
+	case "history":
+		history(store)
+	case "show":
+		if len(args) != 2 {
+			beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help docs")
+		}
+		show(store, args[1])
+	case "rollback":
+		if len(args) != 2 {
+			beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help docs")
+		}
+		rollback(store, args[1])
+	default:
+		beeLogger.Log.Fatal("Command is missing")
+	}
+	return 0
+}
+
+func history(store swaggergen.DocHistory) {
+	versions, err := store.History()
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read the doc history: %s", err)
+	}
+	if len(versions) == 0 {
+		beeLogger.Log.Info("No swagger doc has been published yet")
+		return
+	}
+
+	current := ""
+	for _, v := range versions {
+		if !v.RolledBack {
+			current = v.Version
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tHASH\tPUBLISHED AT\tSTATUS")
+	for _, v := range versions {
+		status := ""
+		if v.RolledBack {
+			status = "rolled back"
+		} else if v.Version == current {
+			status = "current"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Version, v.Hash, v.CreatedAt.Format("2006-01-02 15:04:05"), status)
+	}
+	w.Flush()
+}
+
+func show(store swaggergen.DocHistory, version string) {
+	dt, err := store.Show(version)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read the swagger doc: %s", err)
+	}
+	fmt.Println(string(dt))
+}
+
+func rollback(store swaggergen.DocHistory, version string) {
+	beeLogger.Log.Warnf("Do you want to roll the swagger doc back to version %s? [Yes|No] ", version)
+	if !utils.AskForConfirmation() {
+		return
+	}
+	newer, err := store.Rollback(version)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not roll back the swagger doc: %s", err)
+	}
+	beeLogger.Log.Infof("Rolled back version(s) %s", strings.Join(newer, ", "))
+	beeLogger.Log.Successf("Swagger doc version %s is the current one again", version)
+}
+
+func diff(currpath string, args []string) int {
+	oldRef, newRef := swaggergen.LatestDoc, path.Join("swagger", "swagger.json")
+	switch len(args) {
+	case 0:
+	case 1:
+		oldRef = args[0]
+	case 2:
+		oldRef, newRef = args[0], args[1]
+	default:
+		beeLogger.Log.Fatal("Wrong number of arguments. Run: bee help docs")
+	}
+
+	oldDoc, oldName, err := swaggergen.LoadDoc(currpath, oldRef)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not load the old swagger doc: %s", err)
+	}
+	newDoc, newName, err := swaggergen.LoadDoc(currpath, newRef)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not load the new swagger doc: %s", err)
+	}
+
+	report := swaggergen.DiffDocs(oldDoc, newDoc)
+	report.Old, report.New = oldName, newName
+	switch jsonReport {
+	case "":
+		report.WriteReport(os.Stdout)
+	case "-":
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			beeLogger.Log.Fatalf("%s", err)
+		}
+	default:
+		report.WriteReport(os.Stdout)
+		f, err := os.Create(jsonReport.String())
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not create the JSON report: %s", err)
+		}
+		defer f.Close()
+		if err := report.WriteJSON(f); err != nil {
+			beeLogger.Log.Fatalf("%s", err)
+		}
+	}
+
+	if report.Breaking() > 0 {
+		return 1
+	}
+	return 0
+}
@@ -24,11 +24,17 @@ var CmdHproseapp = &commands.Command{
 
   {{"To scaffold out your application, use:"|bold}}
 
-      $ bee hprose [appname] [-tables=""] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+      $ bee hprose [appname] [-tables=""] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"] [-level=1]
 
   If 'conn' is empty, the command will generate a sample application. Otherwise the command
   will connect to your database and generate models based on the existing tables.
 
+  The 'level' of the generated code is one of:
+
+      1    models publishing their functions, the default
+      2    models, DAOs and po structs of 'bee generate appcode', and a service object per table
+      3    same as 2, along with the client stubs of the services in the 'client' package
+
   The command 'hprose' creates a folder named [appname] with the following structure:
 
 	    ├── main.go
@@ -46,12 +52,15 @@ func init() {
 	CmdHproseapp.Flag.Var(&generate.Tables, "tables", "List of table names separated by a comma.")
 	CmdHproseapp.Flag.Var(&generate.SQLDriver, "driver", "Database driver. Either mysql, postgres or sqlite.")
 	CmdHproseapp.Flag.Var(&generate.SQLConn, "conn", "Connection string used by the driver to connect to a database instance.")
+	CmdHproseapp.Flag.Var(&generate.Level, "level", "Either 1, 2 or 3. i.e. 1=models; 2=models and services; 3=models, services and client stubs.")
+	CmdHproseapp.AddGenFlags()
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdHproseapp)
 }
 
 func createhprose(cmd *commands.Command, args []string) int {
 	output := cmd.Out()
 
+	args = cmd.ParseGenFlags(args)
 	if len(args) != 1 {
 		beeLogger.Log.Fatal("Argument [appname] is missing")
 	}
@@ -67,32 +76,44 @@ func createhprose(cmd *commands.Command, args []string) int {
 	if generate.SQLDriver == "" {
 		generate.SQLDriver = "mysql"
 	}
+	if generate.Level == "" {
+		generate.Level = "1"
+	}
 	beeLogger.Log.Info("Creating Hprose application...")
 
-	os.MkdirAll(apppath, 0755)
+	utils.MkdirGenerated(apppath)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", apppath, "\x1b[0m")
-	os.Mkdir(path.Join(apppath, "conf"), 0755)
+	if utils.NeedGoMod(apppath) {
+		utils.WriteGoMod(apppath, packpath)
+	}
+	utils.MkdirGenerated(path.Join(apppath, "conf"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "conf"), "\x1b[0m")
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "conf", "app.conf"), "\x1b[0m")
-	utils.WriteToFile(path.Join(apppath, "conf", "app.conf"),
+	utils.WriteGeneratedFile(path.Join(apppath, "conf", "app.conf"),
 		strings.Replace(generate.Hproseconf, "{{.Appname}}", args[0], -1))
 
 	if generate.SQLConn != "" {
+		generate.SQLDriver = utils.DocValue(utils.SQLDriverName(generate.SQLDriver.String()))
 		beeLogger.Log.Infof("Using '%s' as 'driver'", generate.SQLDriver)
 		beeLogger.Log.Infof("Using '%s' as 'conn'", generate.SQLConn)
 		beeLogger.Log.Infof("Using '%s' as 'tables'", generate.Tables)
-		generate.GenerateHproseAppcode(string(generate.SQLDriver), string(generate.SQLConn), "1", string(generate.Tables), path.Join(curpath, args[0]))
+		beeLogger.Log.Infof("Using '%s' as 'level'", generate.Level)
+		generate.GenerateHproseAppcode(string(generate.SQLDriver), string(generate.SQLConn), string(generate.Level), string(generate.Tables), path.Join(curpath, args[0]))
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "main.go"), "\x1b[0m")
-		maingoContent := strings.Replace(generate.HproseMainconngo, "{{.Appname}}", packpath, -1)
+		maingoContent := generate.HproseServicesMainconngo
+		if generate.Level == "1" {
+			maingoContent = generate.HproseMainconngo
+		}
+		maingoContent = strings.Replace(maingoContent, "{{.Appname}}", packpath, -1)
 		maingoContent = strings.Replace(maingoContent, "{{.DriverName}}", string(generate.SQLDriver), -1)
 		maingoContent = strings.Replace(maingoContent, "{{HproseFunctionList}}", strings.Join(generate.HproseAddFunctions, ""), -1)
 		if generate.SQLDriver == "mysql" {
 			maingoContent = strings.Replace(maingoContent, "{{.DriverPkg}}", `_ "github.com/go-sql-driver/mysql"`, -1)
 		} else if generate.SQLDriver == "postgres" {
 			maingoContent = strings.Replace(maingoContent, "{{.DriverPkg}}", `_ "github.com/lib/pq"`, -1)
+		} else if generate.SQLDriver == "sqlite3" {
+			maingoContent = strings.Replace(maingoContent, "{{.DriverPkg}}", `_ "github.com/mattn/go-sqlite3"`, -1)
 		}
-		utils.WriteToFile(path.Join(apppath, "main.go"),
+		utils.WriteGeneratedFile(path.Join(apppath, "main.go"),
 			strings.Replace(
 				maingoContent,
 				"{{.conn}}",
@@ -101,19 +122,20 @@ func createhprose(cmd *commands.Command, args []string) int {
 			),
 		)
 	} else {
-		os.Mkdir(path.Join(apppath, "models"), 0755)
+		utils.MkdirGenerated(path.Join(apppath, "models"))
 		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models"), "\x1b[0m")
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models", "object.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "models", "object.go"), apiapp.APIModels)
+		utils.WriteGeneratedFile(path.Join(apppath, "models", "object.go"), apiapp.APIModels)
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "models", "user.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "models", "user.go"), apiapp.APIModels2)
+		utils.WriteGeneratedFile(path.Join(apppath, "models", "user.go"), apiapp.APIModels2)
 
-		fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(apppath, "main.go"), "\x1b[0m")
-		utils.WriteToFile(path.Join(apppath, "main.go"),
+		utils.WriteGeneratedFile(path.Join(apppath, "main.go"),
 			strings.Replace(generate.HproseMaingo, "{{.Appname}}", packpath, -1))
 	}
+	if utils.IsExist(path.Join(apppath, "go.mod")) {
+		beeLogger.Log.Hint("Run 'go mod tidy' inside the application to resolve its dependencies")
+	}
+	utils.PrintGenSummary()
 	beeLogger.Log.Success("New Hprose application successfully created!")
 	return 0
 }
@@ -0,0 +1,259 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+)
+
+// migrationLockName names the lock taken by a run of migrations
+const migrationLockName = "bee_migrations"
+
+// SQLITEMigrationLockDDL is the table of the lock row of SQLite, which has no advisory locks
+const SQLITEMigrationLockDDL = `
+CREATE TABLE IF NOT EXISTS migrations_lock (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	host varchar(255) NOT NULL,
+	pid integer NOT NULL,
+	locked_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// migrationLock is the lock a run of migrations holds from start to end, so that runs
+// started together, i.e. by several deploys, apply the migrations once. MySQL and PostgreSQL
+// hold the lock for the session of conn, which ends with bee. SQLite holds it in a row of
+// the migrations_lock table.
+type migrationLock struct {
+	driver string
+	db     *sql.DB
+	conn   *sql.Conn
+	name   string // name of the MySQL lock
+}
+
+// lockMigrations takes the migrations lock, waiting at most timeout for the run holding it
+func lockMigrations(db *sql.DB, driver string, timeout time.Duration) *migrationLock {
+	l := &migrationLock{driver: driver, db: db}
+	if driver == "sqlite3" {
+		l.lockRow(timeout)
+		return l
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not connect to database: %s", err)
+	}
+	l.conn = conn
+	if driver == "postgres" {
+		l.lockPostgres(ctx, timeout)
+	} else {
+		l.lockMySQL(ctx, timeout)
+	}
+	return l
+}
+
+// Release releases the migrations lock
+func (l *migrationLock) Release() {
+	var err error
+	ctx := context.Background()
+	switch l.driver {
+	case "sqlite3":
+		host, pid := lockHolder()
+		_, err = l.db.Exec("DELETE FROM migrations_lock WHERE id = 1 AND host = ? AND pid = ?", host, pid)
+	case "postgres":
+		_, err = l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey())
+	default:
+		_, err = l.conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", l.name)
+	}
+	if err != nil {
+		beeLogger.Log.Warnf("Could not release the migrations lock: %s", err)
+	}
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+// lockMySQL takes the named lock of the database with GET_LOCK
+func (l *migrationLock) lockMySQL(ctx context.Context, timeout time.Duration) {
+	var database sql.NullString
+	if err := l.conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+	}
+	// the MySQL locks are global to the server, and their name at most 64 characters long
+	l.name = migrationLockName + "." + database.String
+	if len(l.name) > 64 {
+		l.name = l.name[:64]
+	}
+	getLock := func(seconds int) bool {
+		var locked sql.NullInt64
+		if err := l.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.name, seconds).Scan(&locked); err != nil {
+			beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+		}
+		return locked.Int64 == 1
+	}
+	if getLock(0) {
+		return
+	}
+	holder := l.mysqlHolder(ctx)
+	beeLogger.Log.Infof("Migrations are locked by %s, waiting up to %s", holder, timeout)
+	if !getLock(int(math.Ceil(timeout.Seconds()))) {
+		beeLogger.Log.Fatalf("Migrations are still locked by %s after %s", l.mysqlHolder(ctx), timeout)
+	}
+}
+
+// mysqlHolder describes the connection holding the MySQL lock
+func (l *migrationLock) mysqlHolder(ctx context.Context) string {
+	var id sql.NullInt64
+	var user, host sql.NullString
+	err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", l.name).Scan(&id)
+	if err != nil || !id.Valid {
+		return "another run"
+	}
+	err = l.conn.QueryRowContext(ctx, "SELECT USER, HOST FROM information_schema.PROCESSLIST WHERE ID = ?", id.Int64).Scan(&user, &host)
+	if err != nil {
+		return fmt.Sprintf("connection %d", id.Int64)
+	}
+	return fmt.Sprintf("connection %d of %s@%s", id.Int64, user.String, host.String)
+}
+
+// lockPostgres takes the advisory lock of the database with pg_advisory_lock
+func (l *migrationLock) lockPostgres(ctx context.Context, timeout time.Duration) {
+	// other runs see who holds the lock in the application name of its session
+	host, pid := lockHolder()
+	name := fmt.Sprintf("bee migrate on %s (pid %d)", host, pid)
+	if _, err := l.conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", name); err != nil {
+		beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+	}
+	var locked bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey()).Scan(&locked); err != nil {
+		beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+	}
+	if locked {
+		return
+	}
+	beeLogger.Log.Infof("Migrations are locked by %s, waiting up to %s", l.postgresHolder(ctx), timeout)
+	// a lock_timeout of 0 waits forever
+	ms := timeout.Nanoseconds() / int64(time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	if _, err := l.conn.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = %d", ms)); err != nil {
+		beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+	}
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey()); err != nil {
+		beeLogger.Log.Fatalf("Migrations are still locked by %s after %s: %s", l.postgresHolder(ctx), timeout, err)
+	}
+}
+
+// postgresHolder describes the session holding the PostgreSQL lock
+func (l *migrationLock) postgresHolder(ctx context.Context) string {
+	var pid int64
+	var name, user, addr sql.NullString
+	err := l.conn.QueryRowContext(ctx, `SELECT a.pid, a.application_name, a.usename, host(a.client_addr)
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.classid = 0 AND l.objid = $1 AND l.objsubid = 1`,
+		migrationLockKey()).Scan(&pid, &name, &user, &addr)
+	if err != nil {
+		return "another run"
+	}
+	if addr.String == "" {
+		addr.String = "local"
+	}
+	if name.String == "" {
+		return fmt.Sprintf("session %d of %s@%s", pid, user.String, addr.String)
+	}
+	return fmt.Sprintf("%s, session %d of %s@%s", name.String, pid, user.String, addr.String)
+}
+
+// migrationLockKey is the key of the PostgreSQL advisory lock. It fits in 32 bits, so that
+// pg_locks shows it as objid.
+func migrationLockKey() int64 {
+	return int64(crc32.ChecksumIEEE([]byte(migrationLockName)))
+}
+
+// lockRow inserts the lock row of SQLite, polling until timeout while another run holds it.
+// A row left by a run of this host which is not running anymore is taken over.
+func (l *migrationLock) lockRow(timeout time.Duration) {
+	if _, err := l.db.Exec(SQLITEMigrationLockDDL); err != nil {
+		beeLogger.Log.Fatalf("Could not create migrations_lock table: %s", err)
+	}
+	host, pid := lockHolder()
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		_, err := l.db.Exec("INSERT INTO migrations_lock (id, host, pid) VALUES (1, ?, ?)", host, pid)
+		if err == nil {
+			return
+		}
+		var holderHost, lockedAt string
+		var holderPid int
+		row := l.db.QueryRow("SELECT host, pid, locked_at FROM migrations_lock WHERE id = 1")
+		switch rowErr := row.Scan(&holderHost, &holderPid, &lockedAt); {
+		case rowErr == sql.ErrNoRows:
+			// released meanwhile, unless the insert failed for another reason
+			if !time.Now().Before(deadline) {
+				beeLogger.Log.Fatalf("Could not lock the migrations: %s", err)
+			}
+		case rowErr != nil:
+			beeLogger.Log.Fatalf("Could not lock the migrations: %s", rowErr)
+		case holderHost == host && !processRunning(holderPid):
+			beeLogger.Log.Warnf("Taking over the migrations lock of pid %d, which is not running anymore", holderPid)
+			l.db.Exec("DELETE FROM migrations_lock WHERE id = 1 AND host = ? AND pid = ?", holderHost, holderPid)
+			continue
+		default:
+			holder := fmt.Sprintf("%s (pid %d) since %s", holderHost, holderPid, lockedAt)
+			if !time.Now().Before(deadline) {
+				beeLogger.Log.Fatalf("Migrations are still locked by %s after %s. If that run is over, delete the row of table 'migrations_lock'", holder, timeout)
+			}
+			if !waiting {
+				beeLogger.Log.Infof("Migrations are locked by %s, waiting up to %s", holder, timeout)
+				waiting = true
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// lockHolder returns the host and the pid identifying this run as the holder of the lock
+func lockHolder() (string, int) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host, os.Getpid()
+}
+
+// processRunning reports whether the process pid of this host is running
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// FindProcess fails on Windows for a process which is not running
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
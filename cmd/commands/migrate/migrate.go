@@ -51,6 +51,30 @@ var CmdMigrate = &commands.Command{
   ▶ {{"To update your schema:"|bold}}
 
     $ bee migrate refresh [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"] [-dir="path/to/migration"]
+
+  ▶ {{"To list the migrations with their state, applied, pending or rolled back, and their drift:"|bold}}
+
+    $ bee migrate status [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"] [-dir="path/to/migration"]
+
+  ▶ {{"To apply the pending migrations up to a migration, or the next N ones:"|bold}}
+
+    $ bee migrate up [-to=002_add_user_email] [-steps=N] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+
+  ▶ {{"To roll back the migrations applied after a migration, or the last N ones:"|bold}}
+
+    $ bee migrate down [-to=001_create_user] [-steps=N] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+
+  ▶ {{"To print the migrations a command would run and their SQL, without changing the database:"|bold}}
+
+    $ bee migrate [rollback|reset|refresh|up|down] -dry-run [-out=plan.sql]
+
+  The migrations are either Go files, built and run by a temporary binary, or SQL files named
+  NNN_name.up.sql and NNN_name.down.sql, run by bee in the order of their number.
+  A run holds a lock on the database, GET_LOCK on MySQL, pg_advisory_lock on PostgreSQL and a row
+  of the migrations_lock table on SQLite, so that concurrent runs apply the migrations once. A run
+  waits for the lock up to -lock-timeout, i.e. -lock-timeout=5m, one minute by default.
+  The -to and -steps options need SQL migrations. With -out, the plan of -dry-run is written
+  to a file for review. The SQL of the Go migrations is captured from their m.SQL calls.
 `,
 	PreRun: func(cmd *commands.Command, args []string) { version.ShowShortVersionBanner() },
 	Run:    RunMigration,
@@ -59,11 +83,21 @@ var CmdMigrate = &commands.Command{
 var mDriver utils.DocValue
 var mConn utils.DocValue
 var mDir utils.DocValue
+var mTo utils.DocValue
+var mSteps int
+var mDryRun bool
+var mOut utils.DocValue
+var mLockTimeout time.Duration
 
 func init() {
 	CmdMigrate.Flag.Var(&mDriver, "driver", "Database driver. Either mysql, postgres or sqlite.")
 	CmdMigrate.Flag.Var(&mConn, "conn", "Connection string used by the driver to connect to a database instance.")
 	CmdMigrate.Flag.Var(&mDir, "dir", "The directory where the migration files are stored")
+	CmdMigrate.Flag.Var(&mTo, "to", "Migration, by name or number, up to which 'up' applies or after which 'down' rolls back")
+	CmdMigrate.Flag.IntVar(&mSteps, "steps", 0, "Number of migrations 'up' applies or 'down' rolls back")
+	CmdMigrate.Flag.BoolVar(&mDryRun, "dry-run", false, "Print the migrations to run and their SQL, without changing the database")
+	CmdMigrate.Flag.Var(&mOut, "out", "File to write the plan of -dry-run to, for review")
+	CmdMigrate.Flag.DurationVar(&mLockTimeout, "lock-timeout", time.Minute, "How long to wait for another run of the migrations holding the lock")
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdMigrate)
 }
 
@@ -71,15 +105,6 @@ func init() {
 func RunMigration(cmd *commands.Command, args []string) int {
 	currpath, _ := os.Getwd()
 
-	gps := utils.GetGOPATHs()
-	if len(gps) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
-
-	gopath := gps[0]
-
-	beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
-
 	// Getting command line arguments
 	if len(args) != 0 {
 		cmd.Flag.Parse(args[1:])
@@ -114,6 +139,25 @@ func RunMigration(cmd *commands.Command, args []string) int {
 	if dirRune[0] != '/' && dirRune[1] != ':' {
 		dirStr = path.Join(currpath, dirStr)
 	}
+	if mTo != "" && mSteps != 0 {
+		beeLogger.Log.Fatal("Options -to and -steps cannot be used together")
+	}
+	if mSteps < 0 {
+		beeLogger.Log.Fatal("Option -steps must be positive")
+	}
+
+	if mDryRun || mOut != "" {
+		goal := "upgrade"
+		if len(args) != 0 {
+			goal = args[0]
+		}
+		switch goal {
+		case "upgrade", "rollback", "reset", "refresh", "up", "down":
+			beeLogger.Log.Infof("Planning '%s' without changing the database", goal)
+			MigratePlan(goal, currpath, driverStr, connStr, dirStr, mTo.String(), mSteps, mOut.String())
+			return 0
+		}
+	}
 
 	if len(args) == 0 {
 		// run all outstanding migrations
@@ -131,6 +175,15 @@ func RunMigration(cmd *commands.Command, args []string) int {
 		case "refresh":
 			beeLogger.Log.Info("Refreshing all migrations")
 			MigrateRefresh(currpath, driverStr, connStr, dirStr)
+		case "up":
+			beeLogger.Log.Info("Running the outstanding migrations")
+			MigrateUp(currpath, driverStr, connStr, dirStr, mTo.String(), mSteps)
+		case "down":
+			beeLogger.Log.Info("Rolling back migrations")
+			MigrateDown(currpath, driverStr, connStr, dirStr, mTo.String(), mSteps)
+		case "status":
+			MigrateStatus(currpath, driverStr, connStr, dirStr)
+			return 0
 		default:
 			beeLogger.Log.Fatal("Command is missing")
 		}
@@ -139,8 +192,10 @@ func RunMigration(cmd *commands.Command, args []string) int {
 	return 0
 }
 
-// migrate generates source code, build it, and invoke the binary who does the actual migration
-func migrate(goal, currpath, driver, connStr, dir string) {
+// migrate runs the SQL migrations of dir, or generates source code, build it, and invoke
+// the binary who does the actual migration of the Go ones
+func migrate(goal, currpath, driver, connStr, dir string, target migrationTarget) {
+	driver = utils.SQLDriverName(driver)
 	if dir == "" {
 		dir = path.Join(currpath, "database", "migrations")
 	}
@@ -158,28 +213,78 @@ func migrate(goal, currpath, driver, connStr, dir string) {
 	}
 	defer db.Close()
 
+	// the migrations are read and applied by one run at a time
+	lock := lockMigrations(db, driver, mLockTimeout)
+	defer lock.Release()
+
 	checkForSchemaUpdateTable(db, driver)
+
+	// SQL migrations run from bee itself, Go migrations from a binary built in the directory
+	sqlMigrations, err := readSQLMigrations(dir)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read migrations: %s", err)
+	}
+	if len(sqlMigrations) > 0 {
+		if hasGoMigrations(dir) {
+			beeLogger.Log.Fatalf("Directory '%s' holds both SQL and Go migrations, which cannot be ordered together", dir)
+		}
+		migrateSQL(goal, db, driver, sqlMigrations, target)
+		return
+	}
+	switch {
+	case target.To != "" || target.Steps != 0:
+		beeLogger.Log.Fatal("Options -to and -steps need SQL migrations, beego migrations cannot stop at a given one")
+	case goal == "up":
+		goal = "upgrade"
+	case goal == "down":
+		goal = "rollback"
+	}
+	checkGoEnv(currpath)
+
 	latestName, latestTime := getLatestMigration(db, goal)
 	writeMigrationSourceFile(dir, source, driver, connStr, latestTime, latestName, goal)
 	buildMigrationBinary(dir, binary)
 	runMigrationBinary(dir, binary)
 	removeTempFile(dir, source)
 	removeTempFile(dir, binary)
+	recordGoChecksums(db, driver, dir)
+}
+
+// migrationTarget is where 'up' and 'down' stop, all the pending migrations for 'up' and
+// the last applied one for 'down' if unset
+type migrationTarget struct {
+	To    string // name or number of the last migration applied by up, or kept by down
+	Steps int    // number of migrations applied or rolled back
+}
+
+// checkGoEnv checks that the Go migrations can be built, inside a module or the GOPATH
+func checkGoEnv(currpath string) {
+	if mod := utils.GetModule(currpath); mod != nil {
+		beeLogger.Log.Debugf("Module: %s", utils.FILE(), utils.LINE(), mod.Path)
+	} else {
+		gps := utils.GetGOPATHs()
+		if len(gps) == 0 {
+			beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
+		}
+
+		gopath := gps[0]
+
+		beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
+	}
 }
 
 // checkForSchemaUpdateTable checks the existence of migrations table.
 // It checks for the proper table structures and creates the table using MYSQL_MIGRATION_DDL if it does not exist.
 func checkForSchemaUpdateTable(db *sql.DB, driver string) {
-	showTableSQL := showMigrationsTableSQL(driver)
-	if rows, err := db.Query(showTableSQL); err != nil {
-		beeLogger.Log.Fatalf("Could not show migrations table: %s", err)
-	} else if !rows.Next() {
+	if hasMigrationsTable(db, driver) {
+		addChecksumColumn(db)
+	} else {
 		// No migrations table, create new ones
 		createTableSQL := createMigrationsTableSQL(driver)
 
 		beeLogger.Log.Infof("Creating 'migrations' table...")
 
-		if _, err := db.Query(createTableSQL); err != nil {
+		if _, err := db.Exec(createTableSQL); err != nil {
 			beeLogger.Log.Fatalf("Could not create migrations table: %s", err)
 		}
 	}
@@ -189,6 +294,7 @@ func checkForSchemaUpdateTable(db *sql.DB, driver string) {
 	if rows, err := db.Query(selectTableSQL); err != nil {
 		beeLogger.Log.Fatalf("Could not show columns of migrations table: %s", err)
 	} else {
+		defer rows.Close()
 		for rows.Next() {
 			var fieldBytes, typeBytes, nullBytes, keyBytes, defaultBytes, extraBytes []byte
 			if err := rows.Scan(&fieldBytes, &typeBytes, &nullBytes, &keyBytes, &defaultBytes, &extraBytes); err != nil {
@@ -216,12 +322,24 @@ func checkForSchemaUpdateTable(db *sql.DB, driver string) {
 	}
 }
 
+// hasMigrationsTable reports whether the migrations table exists
+func hasMigrationsTable(db *sql.DB, driver string) bool {
+	rows, err := db.Query(showMigrationsTableSQL(driver))
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not show migrations table: %s", err)
+	}
+	defer rows.Close()
+	return rows.Next()
+}
+
 func driverImportStatement(driver string) string {
 	switch driver {
 	case "mysql":
 		return "github.com/go-sql-driver/mysql"
 	case "postgres":
 		return "github.com/lib/pq"
+	case "sqlite3":
+		return "github.com/mattn/go-sqlite3"
 	default:
 		return "github.com/go-sql-driver/mysql"
 	}
@@ -233,6 +351,8 @@ func showMigrationsTableSQL(driver string) string {
 		return "SHOW TABLES LIKE 'migrations'"
 	case "postgres":
 		return "SELECT * FROM pg_catalog.pg_tables WHERE tablename = 'migrations';"
+	case "sqlite3":
+		return "SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'migrations'"
 	default:
 		return "SHOW TABLES LIKE 'migrations'"
 	}
@@ -244,6 +364,8 @@ func createMigrationsTableSQL(driver string) string {
 		return MYSQLMigrationDDL
 	case "postgres":
 		return POSTGRESMigrationDDL
+	case "sqlite3":
+		return SQLITEMigrationDDL
 	default:
 		return MYSQLMigrationDDL
 	}
@@ -255,6 +377,8 @@ func selectMigrationsTableSQL(driver string) string {
 		return "DESC migrations"
 	case "postgres":
 		return "SELECT * FROM migrations WHERE false ORDER BY id_migration;"
+	case "sqlite3":
+		return "SELECT * FROM migrations WHERE 0 ORDER BY id_migration"
 	default:
 		return "DESC migrations"
 	}
@@ -417,6 +541,7 @@ CREATE TABLE migrations (
 	statements longtext COMMENT 'SQL statements for this migration',
 	rollback_statements longtext COMMENT 'SQL statment for rolling back migration',
 	status ENUM('update', 'rollback') COMMENT 'update indicates it is a normal migration while rollback means this migration is rolled back',
+	checksum varchar(64) DEFAULT NULL COMMENT 'SHA-256 of the migration files when migrated',
 	PRIMARY KEY (id_migration)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8
 `
@@ -430,26 +555,48 @@ CREATE TABLE migrations (
 	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	statements text,
 	rollback_statements text,
-	status migrations_status
+	status migrations_status,
+	checksum varchar(64) DEFAULT NULL
+)`
+	// SQLITEMigrationDDL SQLite migration SQL
+	SQLITEMigrationDDL = `
+CREATE TABLE migrations (
+	id_migration INTEGER PRIMARY KEY AUTOINCREMENT,
+	name varchar(255) DEFAULT NULL,
+	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	statements text,
+	rollback_statements text,
+	status varchar(8) CHECK (status IN ('update', 'rollback')),
+	checksum varchar(64) DEFAULT NULL
 )`
 )
 
 // MigrateUpdate does the schema update
 func MigrateUpdate(currpath, driver, connStr, dir string) {
-	migrate("upgrade", currpath, driver, connStr, dir)
+	migrate("upgrade", currpath, driver, connStr, dir, migrationTarget{})
 }
 
 // MigrateRollback rolls back the latest migration
 func MigrateRollback(currpath, driver, connStr, dir string) {
-	migrate("rollback", currpath, driver, connStr, dir)
+	migrate("rollback", currpath, driver, connStr, dir, migrationTarget{})
 }
 
 // MigrateReset rolls back all migrations
 func MigrateReset(currpath, driver, connStr, dir string) {
-	migrate("reset", currpath, driver, connStr, dir)
+	migrate("reset", currpath, driver, connStr, dir, migrationTarget{})
 }
 
 // MigrateRefresh rolls back all migrations and start over again
 func MigrateRefresh(currpath, driver, connStr, dir string) {
-	migrate("refresh", currpath, driver, connStr, dir)
+	migrate("refresh", currpath, driver, connStr, dir, migrationTarget{})
+}
+
+// MigrateUp applies the pending migrations up to the migration to, or the next steps ones
+func MigrateUp(currpath, driver, connStr, dir, to string, steps int) {
+	migrate("up", currpath, driver, connStr, dir, migrationTarget{To: to, Steps: steps})
+}
+
+// MigrateDown rolls back the migrations applied after the migration to, or the last steps ones
+func MigrateDown(currpath, driver, connStr, dir, to string, steps int) {
+	migrate("down", currpath, driver, connStr, dir, migrationTarget{To: to, Steps: steps})
 }
@@ -0,0 +1,320 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package migrate
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// planStep is a migration the plan applies or rolls back, with the SQL it runs
+type planStep struct {
+	Name       string
+	Down       bool
+	Statements []string
+	Note       string // why the statements are unknown, if so
+}
+
+// MigratePlan prints the migrations the goal would apply or roll back, in order, with the SQL
+// each runs, without changing the database. The plan is written to out instead, if set.
+func MigratePlan(goal, currpath, driver, connStr, dir, to string, steps int, out string) {
+	driver = utils.SQLDriverName(driver)
+	if dir == "" {
+		dir = filepath.Join(currpath, "database", "migrations")
+	}
+	// the Go migrations are captured from dir
+	if out != "" && !filepath.IsAbs(out) {
+		out = filepath.Join(currpath, out)
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not connect to database using '%s': %s", connStr, err)
+	}
+	defer db.Close()
+
+	// the migrations table is read but never created, without it nothing is applied
+	history := db
+	if !hasMigrationsTable(db, driver) {
+		history = nil
+	}
+	target := migrationTarget{To: to, Steps: steps}
+
+	var plan []planStep
+	sqlMigrations, err := readSQLMigrations(dir)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read migrations: %s", err)
+	}
+	if len(sqlMigrations) > 0 {
+		if hasGoMigrations(dir) {
+			beeLogger.Log.Fatalf("Directory '%s' holds both SQL and Go migrations, which cannot be ordered together", dir)
+		}
+		plan = planSQLStatements(driver, planSQL(goal, history, sqlMigrations, target))
+	} else {
+		switch {
+		case target.To != "" || target.Steps != 0:
+			beeLogger.Log.Fatal("Options -to and -steps need SQL migrations, beego migrations cannot stop at a given one")
+		case goal == "up":
+			goal = "upgrade"
+		case goal == "down":
+			goal = "rollback"
+		}
+		plan = planGo(goal, currpath, history, dir)
+	}
+
+	if len(plan) == 0 {
+		if goal == "down" || goal == "rollback" {
+			beeLogger.Log.Info("There is nothing to rollback")
+		} else {
+			beeLogger.Log.Info("There is nothing to migrate")
+		}
+		return
+	}
+	content := formatPlan(goal, driver, plan)
+	if out == "" {
+		fmt.Print(content)
+		return
+	}
+	if err := ioutil.WriteFile(out, []byte(content), 0644); err != nil {
+		beeLogger.Log.Fatalf("Could not write the migration plan: %s", err)
+	}
+	beeLogger.Log.Infof("Migration plan of %d migrations written to '%s'", len(plan), out)
+}
+
+// planSQLStatements reads the statements of the SQL migrations of steps
+func planSQLStatements(driver string, steps []sqlStep) []planStep {
+	plan := make([]planStep, 0, len(steps))
+	for _, step := range steps {
+		p := planStep{Name: step.Migration.Name, Down: step.Down}
+		file := step.Migration.Up
+		if step.Down {
+			file = step.Migration.Down
+		}
+		if file == "" {
+			beeLogger.Log.Warnf("Migration '%s' has no down file, it cannot be rolled back", p.Name)
+			p.Note = "no down file, the migration cannot be rolled back"
+			plan = append(plan, p)
+			continue
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not read migration file: %s", err)
+		}
+		p.Statements = splitSQLStatements(driver, string(content))
+		plan = append(plan, p)
+	}
+	return plan
+}
+
+// planGo returns the Go migrations the goal, one of upgrade, rollback, reset or refresh,
+// runs in the order beego runs them, and captures the SQL their Up and Down add
+func planGo(goal, currpath string, history *sql.DB, dir string) []planStep {
+	files := readGoMigrations(dir)
+	sort.SliceStable(files, func(i, j int) bool { return goMigrationTime(files[i].Name) < goMigrationTime(files[j].Name) })
+
+	latest := ""
+	if history != nil {
+		latest, _ = getLatestMigration(history, goal)
+	} else if goal == "rollback" {
+		beeLogger.Log.Fatal("There is nothing to rollback")
+	}
+	status := migrationStatus(history)
+
+	var plan []planStep
+	up := func() {
+		for _, f := range files {
+			if latest == "" || goMigrationTime(f.Name) > goMigrationTime(latest) {
+				plan = append(plan, planStep{Name: f.Name})
+			}
+		}
+	}
+	// beego rolls back every migration which is not rolled back, applied or not
+	reset := func() {
+		for i := len(files) - 1; i >= 0; i-- {
+			if status[files[i].Name] != "rollback" {
+				plan = append(plan, planStep{Name: files[i].Name, Down: true})
+			}
+		}
+	}
+	switch goal {
+	case "upgrade":
+		up()
+	case "rollback":
+		plan = append(plan, planStep{Name: latest, Down: true})
+	case "reset":
+		reset()
+	case "refresh":
+		reset()
+		latest = ""
+		up()
+	}
+	if len(plan) == 0 {
+		return nil
+	}
+
+	captured := captureGoMigrations(currpath, dir)
+	for i := range plan {
+		sqls, ok := captured[plan[i].Name]
+		if !ok {
+			beeLogger.Log.Fatalf("Migration '%s' is not registered by the migrations of '%s'", plan[i].Name, dir)
+		}
+		if plan[i].Down {
+			plan[i].Statements = sqls["down"]
+		} else {
+			plan[i].Statements = sqls["up"]
+		}
+	}
+	return plan
+}
+
+// captureGoMigrations builds and runs a binary calling the Up and Down of each Go migration
+// of dir, and returns the SQL they add with m.SQL, by migration name then by direction
+func captureGoMigrations(currpath, dir string) map[string]map[string][]string {
+	checkGoEnv(currpath)
+	binary := "m"
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	source, capture := binary+".go", "m.json"
+
+	changeDir(dir)
+	if err := ioutil.WriteFile(source, []byte(MigrationCaptureTPL), 0666); err != nil {
+		beeLogger.Log.Fatalf("Could not create file: %s", err)
+	}
+	buildMigrationBinary(dir, binary)
+	removeTempFile(dir, source)
+
+	// the output of the migrations would mix with the printed plan
+	var output bytes.Buffer
+	cmd := exec.Command("./"+binary, capture)
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err := cmd.Run()
+	removeTempFile(dir, binary)
+	if err != nil {
+		formatShellErrOutput(output.String())
+		beeLogger.Log.Fatalf("Could not capture the SQL of the migrations: %s", err)
+	}
+	content, err := ioutil.ReadFile(capture)
+	removeTempFile(dir, capture)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read the SQL of the migrations: %s", err)
+	}
+	captured := make(map[string]map[string][]string)
+	if err := json.Unmarshal(content, &captured); err != nil {
+		beeLogger.Log.Fatalf("Could not read the SQL of the migrations: %s", err)
+	}
+	return captured
+}
+
+// formatPlan returns the plan as an SQL script: a header listing the migrations in order,
+// then the statements of each
+func formatPlan(goal, driver string, plan []planStep) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Migration plan of '%s', generated by bee on %s\n", goal, time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "-- Driver: %s\n--\n", driver)
+	for i, p := range plan {
+		fmt.Fprintf(&b, "-- %3d. %s (%s)\n", i+1, p.Name, direction(p.Down))
+	}
+	for _, p := range plan {
+		fmt.Fprintf(&b, "\n-- %s (%s)\n", p.Name, direction(p.Down))
+		switch {
+		case p.Note != "":
+			fmt.Fprintf(&b, "-- %s\n", p.Note)
+		case len(p.Statements) == 0:
+			b.WriteString("-- no statement\n")
+		}
+		for _, s := range p.Statements {
+			b.WriteString(strings.TrimRight(strings.TrimSpace(s), ";") + ";\n")
+		}
+	}
+	return b.String()
+}
+
+// direction names the method of a migration a step runs
+func direction(down bool) string {
+	if down {
+		return "down"
+	}
+	return "up"
+}
+
+// MigrationCaptureTPL is the source of the binary capturing the SQL of the Go migrations. It
+// reaches the migrations registered to beego through its unexported migrationMap, and calls
+// their Up and Down without connecting to the database.
+const MigrationCaptureTPL = `package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	_ "unsafe"
+
+	"github.com/astaxie/beego/migration"
+)
+
+//go:linkname migrationMap github.com/astaxie/beego/migration.migrationMap
+var migrationMap map[string]migration.Migrationer
+
+func main() {
+	captured := make(map[string]map[string][]string)
+	for name, m := range migrationMap {
+		m.Reset()
+		m.Up()
+		up := statements(m)
+		m.Reset()
+		m.Down()
+		captured[name] = map[string][]string{"up": up, "down": statements(m)}
+	}
+	content, err := json.Marshal(captured)
+	if err == nil {
+		err = ioutil.WriteFile(os.Args[1], content, 0644)
+	}
+	if err != nil {
+		os.Stderr.WriteString(err.Error())
+		os.Exit(2)
+	}
+}
+
+// statements returns the SQL added by m.SQL, which beego keeps in the unexported sqls field
+func statements(m migration.Migrationer) []string {
+	var sqls []string
+	v := reflect.Indirect(reflect.ValueOf(m))
+	if v.Kind() != reflect.Struct {
+		return sqls
+	}
+	field := v.FieldByName("sqls")
+	if field.Kind() != reflect.Slice {
+		return sqls
+	}
+	for i := 0; i < field.Len(); i++ {
+		if s := field.Index(i).String(); s != "" {
+			sqls = append(sqls, s)
+		}
+	}
+	return sqls
+}
+`
@@ -0,0 +1,426 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+)
+
+// sqlMigrationFile matches the files of the SQL migrations, i.e. 001_create_user.up.sql
+var sqlMigrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// sqlMigration is a migration made of an up and an optional down SQL file
+type sqlMigration struct {
+	Version int64  // number prefixing the files, which orders the migrations
+	Name    string // name recorded in the migrations table, i.e. 001_create_user
+	Up      string // path of the up file
+	Down    string // path of the down file, empty if the migration cannot be rolled back
+}
+
+// Checksum returns the SHA-256 of the files of m, which are read when applying it
+func (m *sqlMigration) Checksum() string {
+	paths := []string{m.Up}
+	if m.Down != "" {
+		paths = append(paths, m.Down)
+	}
+	return fileChecksum(paths...)
+}
+
+// readSQLMigrations returns the SQL migrations of dir, ordered by version
+func readSQLMigrations(dir string) ([]*sqlMigration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*sqlMigration)
+	versions := make(map[int64]string)
+	for _, f := range files {
+		match := sqlMigrationFile.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of '%s': %s", f.Name(), err)
+		}
+		name := match[1] + "_" + match[2]
+		m, ok := byName[name]
+		if !ok {
+			if other, ok := versions[version]; ok {
+				return nil, fmt.Errorf("migrations '%s' and '%s' have the same version", other, name)
+			}
+			m = &sqlMigration{Version: version, Name: name}
+			byName[name], versions[version] = m, name
+		}
+		if match[3] == "up" {
+			m.Up = filepath.Join(dir, f.Name())
+		} else {
+			m.Down = filepath.Join(dir, f.Name())
+		}
+	}
+
+	migrations := make([]*sqlMigration, 0, len(byName))
+	for _, m := range byName {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration '%s' has a down file but no up file", m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// hasGoMigrations reports whether dir holds Go migrations, besides the source of the
+// migration binary
+func hasGoMigrations(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, f := range files {
+		if filepath.Base(f) != "m.go" {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateSQL runs the goal, one of upgrade, rollback, reset, refresh, up or down, with the SQL
+// migrations. As with the Go migrations, each applied migration is recorded in the
+// migrations table with the status 'update', which becomes 'rollback' once rolled back.
+func migrateSQL(goal string, db *sql.DB, driver string, migrations []*sqlMigration, target migrationTarget) {
+	steps := planSQL(goal, db, migrations, target)
+	if len(steps) == 0 {
+		if goal == "down" {
+			beeLogger.Log.Info("There is nothing to rollback")
+		} else if goal != "reset" {
+			beeLogger.Log.Info("There is nothing to migrate")
+		}
+	}
+	for _, step := range steps {
+		if step.Down {
+			downSQL(db, driver, step.Migration)
+		} else {
+			upSQL(db, driver, step.Migration)
+		}
+	}
+}
+
+// sqlStep is a migration to apply, or to roll back
+type sqlStep struct {
+	Migration *sqlMigration
+	Down      bool
+}
+
+// planSQL returns the migrations the goal applies and rolls back, in order. The migrations
+// table is only read, and db is nil if it doesn't exist yet.
+func planSQL(goal string, db *sql.DB, migrations []*sqlMigration, target migrationTarget) (steps []sqlStep) {
+	up := func(list []*sqlMigration) {
+		for _, m := range list {
+			steps = append(steps, sqlStep{Migration: m})
+		}
+	}
+	// the latest applied first
+	down := func(list []*sqlMigration) {
+		for i := len(list) - 1; i >= 0; i-- {
+			steps = append(steps, sqlStep{Migration: list[i], Down: true})
+		}
+	}
+	switch goal {
+	case "upgrade":
+		up(pendingSQLMigrations(db, migrations))
+	case "rollback":
+		applied := appliedSQLMigrations(db, migrations)
+		if len(applied) == 0 {
+			beeLogger.Log.Fatal("There is nothing to rollback")
+		}
+		down(applied[len(applied)-1:])
+	case "reset":
+		down(appliedSQLMigrations(db, migrations))
+	case "refresh":
+		// once all rolled back, all the migrations are pending
+		down(appliedSQLMigrations(db, migrations))
+		up(migrations)
+	case "up":
+		pending := pendingSQLMigrations(db, migrations)
+		if target.To != "" {
+			to := findSQLMigration(migrations, target.To)
+			for i, m := range pending {
+				if m.Version > to.Version {
+					pending = pending[:i]
+					break
+				}
+			}
+		} else if target.Steps > 0 && target.Steps < len(pending) {
+			pending = pending[:target.Steps]
+		}
+		up(pending)
+	case "down":
+		applied := appliedSQLMigrations(db, migrations)
+		var rollback []*sqlMigration
+		switch {
+		case target.To != "":
+			to := findSQLMigration(migrations, target.To)
+			found := false
+			for _, m := range applied {
+				if m.Version > to.Version {
+					rollback = append(rollback, m)
+				}
+				found = found || m == to
+			}
+			if !found {
+				beeLogger.Log.Fatalf("Migration '%s' is not applied", to.Name)
+			}
+		default:
+			steps := target.Steps
+			if steps == 0 {
+				steps = 1
+			}
+			if steps > len(applied) {
+				steps = len(applied)
+			}
+			rollback = applied[len(applied)-steps:]
+		}
+		down(rollback)
+	}
+	return
+}
+
+// findSQLMigration returns the migration named name, i.e. 002_add_user_email, or numbered
+// name, i.e. 2 or 002
+func findSQLMigration(migrations []*sqlMigration, name string) *sqlMigration {
+	version, err := strconv.ParseInt(name, 10, 64)
+	for _, m := range migrations {
+		if m.Name == name || err == nil && m.Version == version {
+			return m
+		}
+	}
+	beeLogger.Log.Fatalf("Migration '%s' does not exist", name)
+	return nil
+}
+
+// pendingSQLMigrations returns the migrations which are not applied, in order
+func pendingSQLMigrations(db *sql.DB, migrations []*sqlMigration) []*sqlMigration {
+	status := migrationStatus(db)
+	var pending []*sqlMigration
+	for _, m := range migrations {
+		if status[m.Name] != "update" {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// appliedSQLMigrations returns the applied migrations, in the order they were applied in.
+// Rolling back a migration sets the status of all its rows to 'rollback', so a migration
+// having a row of status 'update' is applied. It fails when an applied migration has no
+// file anymore. Without migrations table, db is nil and none is applied.
+func appliedSQLMigrations(db *sql.DB, migrations []*sqlMigration) []*sqlMigration {
+	if db == nil {
+		return nil
+	}
+	byName := make(map[string]*sqlMigration, len(migrations))
+	for _, m := range migrations {
+		byName[m.Name] = m
+	}
+	rows, err := db.Query("SELECT name FROM migrations WHERE status = 'update' ORDER BY id_migration")
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not retrieve migrations: %s", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			beeLogger.Log.Fatalf("Could not read migrations in database: %s", err)
+		}
+		for i, n := range names {
+			if n == name.String {
+				names = append(names[:i], names[i+1:]...)
+				break
+			}
+		}
+		names = append(names, name.String)
+	}
+
+	applied := make([]*sqlMigration, 0, len(names))
+	for _, name := range names {
+		m, ok := byName[name]
+		if !ok {
+			beeLogger.Log.Fatalf("Migration '%s' is applied but has no file", name)
+		}
+		applied = append(applied, m)
+	}
+	return applied
+}
+
+// migrationStatus returns the latest status of each migration of the migrations table,
+// none if db is nil
+func migrationStatus(db *sql.DB) map[string]string {
+	status := make(map[string]string)
+	if db == nil {
+		return status
+	}
+	rows, err := db.Query("SELECT name, status FROM migrations ORDER BY id_migration")
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not retrieve migrations: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, st sql.NullString
+		if err := rows.Scan(&name, &st); err != nil {
+			beeLogger.Log.Fatalf("Could not read migrations in database: %s", err)
+		}
+		status[name.String] = st.String
+	}
+	return status
+}
+
+// upSQL applies the migration m and records it, in a single transaction
+func upSQL(db *sql.DB, driver string, m *sqlMigration) {
+	beeLogger.Log.Infof("Upgrading '%s'", m.Name)
+	statements := execSQLFile(db, driver, m.Up, func(tx *sql.Tx, statements []string) error {
+		_, err := tx.Exec(rebind(driver, "INSERT INTO migrations (name, statements, status, checksum) VALUES (?, ?, ?, ?)"),
+			m.Name, strings.Join(statements, "; "), "update", m.Checksum())
+		return err
+	})
+	beeLogger.Log.Infof("Upgraded '%s' (%d statements)", m.Name, len(statements))
+}
+
+// downSQL rolls back the migration m and records it, in a single transaction
+func downSQL(db *sql.DB, driver string, m *sqlMigration) {
+	if m.Down == "" {
+		beeLogger.Log.Fatalf("Migration '%s' has no down file, it cannot be rolled back", m.Name)
+	}
+	beeLogger.Log.Infof("Rolling back '%s'", m.Name)
+	statements := execSQLFile(db, driver, m.Down, func(tx *sql.Tx, statements []string) error {
+		_, err := tx.Exec(rebind(driver, "UPDATE migrations SET status = ?, rollback_statements = ?, created_at = CURRENT_TIMESTAMP WHERE name = ?"),
+			"rollback", strings.Join(statements, "; "), m.Name)
+		return err
+	})
+	beeLogger.Log.Infof("Rolled back '%s' (%d statements)", m.Name, len(statements))
+}
+
+// execSQLFile runs the statements of the file fpath, then record, in a transaction.
+// MySQL commits its DDL statements implicitly, so a failing migration may be partly
+// applied there.
+func execSQLFile(db *sql.DB, driver, fpath string, record func(*sql.Tx, []string) error) []string {
+	content, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read migration file: %s", err)
+	}
+	statements := splitSQLStatements(driver, string(content))
+	tx, err := db.Begin()
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not start transaction: %s", err)
+	}
+	for _, s := range statements {
+		formatShellOutput(s)
+		if _, err := tx.Exec(s); err != nil {
+			tx.Rollback()
+			beeLogger.Log.Fatalf("Could not execute statement of '%s': %s", filepath.Base(fpath), err)
+		}
+	}
+	if err := record(tx, statements); err != nil {
+		tx.Rollback()
+		beeLogger.Log.Fatalf("Could not record migration: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		beeLogger.Log.Fatalf("Could not commit migration: %s", err)
+	}
+	return statements
+}
+
+// rebind replaces the ? placeholders of query by $1, $2... for PostgreSQL
+func rebind(driver, query string) string {
+	if driver != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// splitSQLStatements splits content into its statements separated by semicolons. Semicolons
+// within quotes, comments and PostgreSQL dollar-quoted strings don't end a statement, and
+// quotes are escaped by a backslash in MySQL. Statements made of comments only are dropped.
+func splitSQLStatements(driver, content string) []string {
+	var statements []string
+	start, code := 0, false
+	flush := func(end int) {
+		if code {
+			statements = append(statements, strings.TrimSpace(content[start:end]))
+		}
+		start, code = end+1, false
+	}
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == ';':
+			flush(i)
+			continue
+		case c == '-' && strings.HasPrefix(content[i:], "--"):
+			if end := strings.IndexByte(content[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(content)
+			}
+			continue
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			if end := strings.Index(content[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(content)
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' && driver == "mysql" {
+					i++
+				}
+			}
+		case c == '$':
+			if tag := dollarQuoteTag.FindString(content[i:]); tag != "" {
+				if end := strings.Index(content[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(content)
+				}
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		}
+		code = true
+	}
+	flush(len(content))
+	return statements
+}
+
+// dollarQuoteTag matches the opening tag of a PostgreSQL dollar-quoted string, i.e. $$ or $body$
+var dollarQuoteTag = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z0-9_]*)?\$`)
@@ -0,0 +1,236 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package migrate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// goMigrationName matches the registration of a Go migration, i.e.
+// migration.Register("CreatePost_20060102_150405", m)
+var goMigrationName = regexp.MustCompile(`migration\.Register\(\s*"([^"]+)"`)
+
+// migrationFile is a migration of the migrations directory
+type migrationFile struct {
+	Name     string // name recorded in the migrations table
+	Checksum string // SHA-256 of its files
+}
+
+// migrationRecord is the latest row of a migration in the migrations table
+type migrationRecord struct {
+	Name      string
+	Status    string // update or rollback
+	CreatedAt string // date migrated or rolled back
+	Checksum  string // SHA-256 of the files when migrated, empty if unknown
+}
+
+// MigrateStatus lists the migrations of dir and of the migrations table, with their state
+// and their drift: files changed since they were applied, and records without a file
+func MigrateStatus(currpath, driver, connStr, dir string) {
+	driver = utils.SQLDriverName(driver)
+	if dir == "" {
+		dir = filepath.Join(currpath, "database", "migrations")
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not connect to database using '%s': %s", connStr, err)
+	}
+	defer db.Close()
+	checkForSchemaUpdateTable(db, driver)
+
+	files := readMigrationFiles(dir)
+	records, names := readMigrationRecords(db)
+	known := make(map[string]bool, len(files))
+	for _, f := range files {
+		known[f.Name] = true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tSTATUS\tMIGRATED AT\tDRIFT")
+	applied, pending, rolledBack, drifts := 0, 0, 0, 0
+	for _, f := range files {
+		r, ok := records[f.Name]
+		status, at, drift := "pending", "", ""
+		switch {
+		case !ok:
+			pending++
+		case r.Status == "update":
+			status, at = "applied", r.CreatedAt
+			applied++
+			if r.Checksum != "" && r.Checksum != f.Checksum {
+				drift = "changed since applied"
+				drifts++
+			}
+		default:
+			status, at = "rolled back", r.CreatedAt
+			rolledBack++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Name, status, at, drift)
+	}
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		r := records[name]
+		status := "applied"
+		if r.Status == "update" {
+			applied++
+		} else {
+			status = "rolled back"
+			rolledBack++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, status, r.CreatedAt, "no file")
+		drifts++
+	}
+	w.Flush()
+
+	beeLogger.Log.Infof("%d applied, %d pending, %d rolled back", applied, pending, rolledBack)
+	if drifts > 0 {
+		beeLogger.Log.Warnf("%d migrations drifted from their files", drifts)
+	}
+}
+
+// readMigrationFiles returns the SQL migrations of dir in order or, without any, its Go
+// migrations ordered by their creation time
+func readMigrationFiles(dir string) []migrationFile {
+	sqlMigrations, err := readSQLMigrations(dir)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not read migrations: %s", err)
+	}
+	var files []migrationFile
+	for _, m := range sqlMigrations {
+		files = append(files, migrationFile{Name: m.Name, Checksum: m.Checksum()})
+	}
+	if len(files) > 0 {
+		return files
+	}
+	files = readGoMigrations(dir)
+	sort.SliceStable(files, func(i, j int) bool { return goMigrationTime(files[i].Name) < goMigrationTime(files[j].Name) })
+	return files
+}
+
+// readGoMigrations returns the Go migrations of dir, named as they are registered
+func readGoMigrations(dir string) []migrationFile {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var files []migrationFile
+	for _, p := range paths {
+		if filepath.Base(p) == "m.go" {
+			continue
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not read migration file: %s", err)
+		}
+		if match := goMigrationName.FindSubmatch(content); match != nil {
+			files = append(files, migrationFile{Name: string(match[1]), Checksum: fileChecksum(p)})
+		}
+	}
+	return files
+}
+
+// goMigrationTime returns the creation time ending the name of a Go migration, i.e.
+// 20060102_150405, or the name itself if it doesn't end with one
+func goMigrationTime(name string) string {
+	if len(name) >= 15 {
+		if _, err := time.Parse("20060102_150405", name[len(name)-15:]); err == nil {
+			return name[len(name)-15:]
+		}
+	}
+	return name
+}
+
+// readMigrationRecords returns the latest record of each migration of the migrations table,
+// along with the names of the migrations in the order they were first migrated in
+func readMigrationRecords(db *sql.DB) (map[string]migrationRecord, []string) {
+	rows, err := db.Query("SELECT name, status, created_at, checksum FROM migrations ORDER BY id_migration")
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not retrieve migrations: %s", err)
+	}
+	defer rows.Close()
+	records := make(map[string]migrationRecord)
+	var names []string
+	for rows.Next() {
+		var name, status, checksum sql.NullString
+		var createdAt interface{}
+		if err := rows.Scan(&name, &status, &createdAt, &checksum); err != nil {
+			beeLogger.Log.Fatalf("Could not read migrations in database: %s", err)
+		}
+		if _, ok := records[name.String]; !ok {
+			names = append(names, name.String)
+		}
+		r := migrationRecord{Name: name.String, Status: status.String, Checksum: checksum.String}
+		switch t := createdAt.(type) {
+		case time.Time:
+			r.CreatedAt = t.Format("2006-01-02 15:04:05")
+		case []byte:
+			r.CreatedAt = string(t)
+		case string:
+			r.CreatedAt = t
+		}
+		records[name.String] = r
+	}
+	return records, names
+}
+
+// addChecksumColumn adds the checksum column to a migrations table created by a former
+// version of bee
+func addChecksumColumn(db *sql.DB) {
+	rows, err := db.Query("SELECT checksum FROM migrations WHERE 1 = 0")
+	if err == nil {
+		rows.Close()
+		return
+	}
+	beeLogger.Log.Infof("Adding 'checksum' column to 'migrations' table...")
+	if _, err := db.Exec("ALTER TABLE migrations ADD COLUMN checksum varchar(64) DEFAULT NULL"); err != nil {
+		beeLogger.Log.Fatalf("Could not add checksum column to migrations table: %s", err)
+	}
+}
+
+// recordGoChecksums sets the checksum of the Go migrations applied by the migration binary,
+// which records them without
+func recordGoChecksums(db *sql.DB, driver, dir string) {
+	for _, f := range readGoMigrations(dir) {
+		if _, err := db.Exec(rebind(driver, "UPDATE migrations SET checksum = ? WHERE name = ? AND status = 'update' AND checksum IS NULL"),
+			f.Checksum, f.Name); err != nil {
+			beeLogger.Log.Warnf("Could not record the checksum of '%s': %s", f.Name, err)
+		}
+	}
+}
+
+// fileChecksum returns the hex SHA-256 of the content of the files
+func fileChecksum(paths ...string) string {
+	h := sha256.New()
+	for _, p := range paths {
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not read migration file: %s", err)
+		}
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
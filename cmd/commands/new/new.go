@@ -245,11 +245,13 @@ var reloadJsClient = `function b(a){var c=new WebSocket(a);c.onclose=function(){
 `
 
 func init() {
+	CmdNew.AddGenFlags()
 	commands.AvailableCommands = append(commands.AvailableCommands, CmdNew)
 }
 
 func CreateApp(cmd *commands.Command, args []string) int {
 	output := cmd.Out()
+	args = cmd.ParseGenFlags(args)
 	if len(args) != 1 {
 		beeLogger.Log.Fatal("Argument [appname] is missing")
 	}
@@ -259,7 +261,7 @@ func CreateApp(cmd *commands.Command, args []string) int {
 		beeLogger.Log.Fatalf("%s", err)
 	}
 
-	if utils.IsExist(appPath) {
+	if utils.IsExist(appPath) && !utils.GenUnattended() {
 		beeLogger.Log.Errorf(colors.Bold("Application '%s' already exists"), appPath)
 		beeLogger.Log.Warn(colors.Bold("Do you want to overwrite it? [Yes|No] "))
 		if !utils.AskForConfirmation() {
@@ -269,47 +271,48 @@ func CreateApp(cmd *commands.Command, args []string) int {
 
 	beeLogger.Log.Info("Creating application...")
 
-	os.MkdirAll(appPath, 0755)
+	utils.MkdirGenerated(appPath)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", appPath+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "conf"), 0755)
+	if utils.NeedGoMod(appPath) {
+		utils.WriteGoMod(appPath, packPath)
+	}
+	utils.MkdirGenerated(path.Join(appPath, "conf"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "controllers"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "controllers"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "models"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "models"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "models")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "routers"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "routers"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "routers")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "tests"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "tests"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "tests")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "static"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "static"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "static", "js"), 0755)
-	utils.WriteToFile(path.Join(appPath, "static", "js", "reload.min.js"), reloadJsClient)
+	utils.MkdirGenerated(path.Join(appPath, "static", "js"))
+	utils.WriteGeneratedFile(path.Join(appPath, "static", "js", "reload.min.js"), reloadJsClient)
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static", "js")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "static", "css"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "static", "css"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static", "css")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "static", "img"), 0755)
+	utils.MkdirGenerated(path.Join(appPath, "static", "img"))
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "static", "img")+string(path.Separator), "\x1b[0m")
 	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "views")+string(path.Separator), "\x1b[0m")
-	os.Mkdir(path.Join(appPath, "views"), 0755)
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "conf", "app.conf"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "conf", "app.conf"), strings.Replace(appconf, "{{.Appname}}", path.Base(args[0]), -1))
+	utils.MkdirGenerated(path.Join(appPath, "views"))
+	utils.WriteGeneratedFile(path.Join(appPath, "conf", "app.conf"), strings.Replace(appconf, "{{.Appname}}", path.Base(args[0]), -1))
 
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "controllers", "default.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "controllers", "default.go"), controllers)
+	utils.WriteGeneratedFile(path.Join(appPath, "controllers", "default.go"), controllers)
 
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "views", "index.tpl"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "views", "index.tpl"), indextpl)
+	utils.WriteGeneratedFile(path.Join(appPath, "views", "index.tpl"), indextpl)
 
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "routers", "router.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "routers", "router.go"), strings.Replace(router, "{{.Appname}}", packPath, -1))
+	utils.WriteGeneratedFile(path.Join(appPath, "routers", "router.go"), strings.Replace(router, "{{.Appname}}", packPath, -1))
 
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "tests", "default_test.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "tests", "default_test.go"), strings.Replace(test, "{{.Appname}}", packPath, -1))
+	utils.WriteGeneratedFile(path.Join(appPath, "tests", "default_test.go"), strings.Replace(test, "{{.Appname}}", packPath, -1))
 
-	fmt.Fprintf(output, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", path.Join(appPath, "main.go"), "\x1b[0m")
-	utils.WriteToFile(path.Join(appPath, "main.go"), strings.Replace(maingo, "{{.Appname}}", packPath, -1))
+	utils.WriteGeneratedFile(path.Join(appPath, "main.go"), strings.Replace(maingo, "{{.Appname}}", packPath, -1))
 
+	if utils.IsExist(path.Join(appPath, "go.mod")) {
+		beeLogger.Log.Hint("Run 'go mod tidy' inside the application to resolve its dependencies")
+	}
+	utils.PrintGenSummary()
 	beeLogger.Log.Success("New application successfully created!")
 	return 0
 }
@@ -108,6 +108,9 @@ func RunApp(cmd *commands.Command, args []string) int {
 				return 0
 			}
 		}
+	} else if utils.IsInModule(appPath) {
+		appname = path.Base(appPath)
+		currentGoPath = appPath
 	} else {
 		beeLogger.Log.Warn("Running application outside of GOPATH")
 		appname = path.Base(appPath)
@@ -142,6 +145,8 @@ func RunApp(cmd *commands.Command, args []string) int {
 		for _, packagePath := range extraPackages {
 			if found, _, _fullPath := utils.SearchGOPATHs(packagePath); found {
 				readAppDirectories(_fullPath, &paths)
+			} else if pkgDir := utils.ResolvePackageDir(appPath, packagePath); pkgDir != "" {
+				readAppDirectories(pkgDir, &paths)
 			} else {
 				beeLogger.Log.Warnf("No extra package '%s' found in your GOPATH", packagePath)
 			}
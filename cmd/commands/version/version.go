@@ -122,6 +122,12 @@ func GetBeegoVersion() string {
 	if err != nil {
 		return ""
 	}
+	// Inside a Go module, the version required by go.mod is the one in use
+	if currpath, err := os.Getwd(); err == nil {
+		if mod := utils.GetModule(currpath); mod != nil && mod.BeegoVersion() != "" {
+			return mod.BeegoVersion()
+		}
+	}
 	wgopath := utils.GetGOPATHs()
 	if len(wgopath) == 0 {
 		beeLogger.Log.Error("You need to set GOPATH environment variable")
@@ -35,9 +35,12 @@ var Conf = struct {
 	Envs               []string
 	Bale               bale
 	Database           database
+	Docs               docs
+	Appcode            appcode
 	EnableReload       bool              `json:"enable_reload" yaml:"enable_reload"`
 	EnableNotification bool              `json:"enable_notification" yaml:"enable_notification"`
 	Scripts            map[string]string `json:"scripts" yaml:"scripts"`
+	Templates          string            // Directory the templates of the code generators are overridden from
 }{
 	WatchExts:       []string{".go"},
 	WatchExtsStatic: []string{".html", ".tpl", ".js", ".css"},
@@ -54,6 +57,9 @@ var Conf = struct {
 	Database: database{
 		Driver: "mysql",
 	},
+	Docs: docs{
+		Dir: "swagger_history",
+	},
 	EnableNotification: true,
 	Scripts:            map[string]string{},
 }
@@ -75,9 +81,55 @@ type bale struct {
 
 // database holds the database connection information
 type database struct {
-	Driver string
+	Driver  string
+	Conn    string
+	Dir     string
+	Aliases map[string]dbAlias // Other databases, by orm alias, the appcode is generated from
+}
+
+// dbAlias holds the connection information of a database registered as an orm alias
+type dbAlias struct {
+	Driver string // Driver of the database, the one of database if empty
 	Conn   string
-	Dir    string
+	Pkg    string // Package of the generated models, models/<alias> if empty
+}
+
+// docs holds the swagger documents publishing information
+type docs struct {
+	Publisher string            // Either local, http or mongodb. Documents are not published if empty.
+	Dir       string            // Directory of the local versioned document store
+	URL       string            `json:"url" yaml:"url"` // Endpoint the documents are POSTed to
+	Headers   map[string]string // Extra HTTP headers, e.g. authentication tokens
+	Mongo     mongo
+}
+
+// appcode holds the Go types of the columns in the generated structs
+type appcode struct {
+	Nullable string            // Type of the nullable columns: pointer or sql (database/sql Null types). Plain values if empty.
+	Decimal  string            // Go type of the decimal, numeric and money columns, float64 if empty
+	RawJSON  bool              `json:"raw_json" yaml:"raw_json"` // Whether json columns are json.RawMessage instead of string
+	Arrays   bool              // Whether PostgreSQL arrays are slices, i.e. []int, instead of string
+	Types    map[string]string // Go type by SQL type, overriding the built-in mapping
+	Columns  map[string]string // Go type by table.column, overriding any other mapping
+	Naming   naming
+}
+
+// naming holds the rules turning table names into the names of the generated code
+type naming struct {
+	StripPrefixes []string          `json:"strip_prefixes" yaml:"strip_prefixes"` // Prefixes removed from the table names, i.e. t_
+	StripSuffixes []string          `json:"strip_suffixes" yaml:"strip_suffixes"` // Suffixes removed from the table names, i.e. _tab
+	Singular      bool              // Whether the Go names are singular, i.e. UserInfo for user_infos
+	Routes        string            // Case of the route segments: snake or kebab. The table name is used as is if empty.
+	Tables        map[string]string // Go name by table, or by alias.table, overriding the other rules
+}
+
+// mongo holds the MongoDB doc center connection information
+type mongo struct {
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
 }
 
 // LoadConfig loads the bee tool configuration.
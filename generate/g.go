@@ -22,3 +22,9 @@ var Level utils.DocValue
 var Tables utils.DocValue
 var Fields utils.DocValue
 var DDL utils.DocValue
+var Bump utils.DocValue
+var OpenAPI utils.DocValue
+var Schema utils.DocValue
+var Alias utils.DocValue
+var ModelPkg utils.DocValue
+var AutoMigration bool
@@ -16,19 +16,21 @@ package generate
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
-	"os"
+	"go/token"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/iwooyun/bee/generate/templates"
 	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
-	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
@@ -53,10 +55,15 @@ type MysqlDB struct {
 type PostgresDB struct {
 }
 
+// SqliteDB is the SQLite version of DbTransformer
+type SqliteDB struct {
+}
+
 // dbDriver maps a DBMS name to its version of DbTransformer
 var dbDriver = map[string]DbTransformer{
 	"mysql":    &MysqlDB{},
 	"postgres": &PostgresDB{},
+	"sqlite3":  &SqliteDB{},
 }
 
 type MvcPath struct {
@@ -103,6 +110,7 @@ var typeMappingMysql = map[string]string{
 	"binary":             "string", // binary
 	"varbinary":          "string",
 	"year":               "int16",
+	"json":               "string", // json
 }
 
 // typeMappingPostgres maps SQL data type to corresponding Go data type
@@ -139,67 +147,114 @@ var typeMappingPostgres = map[string]string{
 	"inet":                        "string",  // ip address
 }
 
+// typeMappingSqlite maps SQLite declared types to corresponding Go data type,
+// other declared types are mapped by their SQLite type affinity
+var typeMappingSqlite = map[string]string{
+	"int":              "int", // int
+	"integer":          "int",
+	"tinyint":          "int8",
+	"smallint":         "int16",
+	"mediumint":        "int32",
+	"bigint":           "int64",
+	"unsigned big int": "uint64",
+	"int2":             "int16",
+	"int8":             "int64",
+	"bool":             "bool", // bool
+	"boolean":          "bool",
+	"char":             "string", // string
+	"varchar":          "string",
+	"nchar":            "string",
+	"nvarchar":         "string",
+	"text":             "string",
+	"clob":             "string",
+	"json":             "string",
+	"blob":             "string",    // blob
+	"date":             "time.Time", // time
+	"datetime":         "time.Time",
+	"timestamp":        "time.Time",
+	"time":             "time.Time",
+	"real":             "float64", // float & decimal
+	"float":            "float64",
+	"double":           "float64",
+	"double precision": "float64",
+	"decimal":          "float64",
+	"numeric":          "float64",
+}
+
 // Table represent a table in a database
 type Table struct {
-	Name          string
-	Pk            string
-	Uk            []string
-	Fk            map[string]*ForeignKey
-	Columns       []*Column
-	ImportTimePkg bool
+	Name          string                 `json:"name"`
+	Pk            string                 `json:"pk,omitempty"`
+	Uk            []string               `json:"uk,omitempty"`
+	Fk            map[string]*ForeignKey `json:"fk,omitempty"`
+	Columns       []*Column              `json:"columns"`
+	Relations     []*Relation            `json:"-"` // relation fields of the model, see resolveRelations
+	GoName        string                 `json:"-"` // name of the model, see nameTables
+	Route         string                 `json:"-"` // path segment of the routes of the model
+	FileName      string                 `json:"-"` // name of the source files of the model, without extension
+	ImportTimePkg bool                   `json:"import_time_pkg,omitempty"`
 }
 
 // Column reprsents a column for a table
 type Column struct {
-	Name string
-	Type string
-	Tag  *OrmTag
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	SQLType string  `json:"sql_type,omitempty"` // data type in the database, i.e. decimal or integer[] for arrays
+	Import  string  `json:"-"`                  // import path of Type, i.e. github.com/shopspring/decimal
+	Tag     *OrmTag `json:"tag"`
 }
 
 // ForeignKey represents a foreign key column for a table
 type ForeignKey struct {
-	Name      string
-	RefSchema string
-	RefTable  string
-	RefColumn string
+	Name      string `json:"name"`
+	RefSchema string `json:"ref_schema,omitempty"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
 }
 
 // OrmTag contains Beego ORM tag information for a column
 type OrmTag struct {
-	Auto        bool
-	Pk          bool
-	Null        bool
-	Index       bool
-	Unique      bool
-	Column      string
-	Size        string
-	Decimals    string
-	Digits      string
-	AutoNow     bool
-	AutoNowAdd  bool
-	Type        string
-	Default     string
-	RelOne      bool
-	ReverseOne  bool
-	RelFk       bool
-	ReverseMany bool
-	RelM2M      bool
-	Comment     string // column comment
+	Auto        bool   `json:"auto,omitempty"`
+	Pk          bool   `json:"pk,omitempty"`
+	Null        bool   `json:"null,omitempty"`
+	Index       bool   `json:"index,omitempty"`
+	Unique      bool   `json:"unique,omitempty"`
+	Column      string `json:"column"`
+	Size        string `json:"size,omitempty"`
+	Decimals    string `json:"decimals,omitempty"`
+	Digits      string `json:"digits,omitempty"`
+	AutoNow     bool   `json:"auto_now,omitempty"`
+	AutoNowAdd  bool   `json:"auto_now_add,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Default     string `json:"default,omitempty"`
+	RelOne      bool   `json:"rel_one,omitempty"`
+	ReverseOne  bool   `json:"reverse_one,omitempty"`
+	RelFk       bool   `json:"rel_fk,omitempty"`
+	ReverseMany bool   `json:"reverse_many,omitempty"`
+	RelM2M      bool   `json:"rel_m2m,omitempty"`
+	RelTable    string `json:"-"`                 // junction table of a m2m relation
+	RelThrough  string `json:"-"`                 // junction model of a m2m relation, i.e. github.com/user/app/models.PostTag
+	OnDelete    string `json:"-"`                 // what the orm does to the rows of a deleted related row
+	Ignore      bool   `json:"-"`                 // the column is mapped by a relation field instead
+	Comment     string `json:"comment,omitempty"` // column comment
 }
 
 // String returns the source code string for the Table struct
 func (tb *Table) String() string {
-	rv := fmt.Sprintf("type %s struct {\n", utils.CamelCase(tb.Name))
+	rv := fmt.Sprintf("type %s struct {\n", tb.typeName())
 	for _, v := range tb.Columns {
 		rv += v.String() + "\n"
 	}
+	for _, v := range tb.Relations {
+		rv += v.String() + "\n"
+	}
 	rv += "}\n"
 	return rv
 }
 
 // String returns the source code string for the Table struct
 func (tb *Table) StringWithSuffix(suffix string, withOrm bool) string {
-	rv := fmt.Sprintf("type %s struct {\n", utils.CamelCase(tb.Name)+suffix)
+	rv := fmt.Sprintf("type %s struct {\n", tb.typeName()+suffix)
 	for _, v := range tb.Columns {
 		if withOrm {
 			rv += v.String() + "\n"
@@ -212,6 +267,14 @@ func (tb *Table) StringWithSuffix(suffix string, withOrm bool) string {
 	return rv
 }
 
+// typeName returns the Go name of the model, the table name in camel case unless named by nameTables
+func (tb *Table) typeName() string {
+	if tb.GoName != "" {
+		return tb.GoName
+	}
+	return utils.CamelCase(tb.Name)
+}
+
 // String returns the source code string of a field in Table struct
 // It maps to a column in database table. e.g. Id int `orm:"column(id);auto"`
 func (col *Column) String() string {
@@ -224,6 +287,25 @@ func (col *Column) StringWithoutOrmTag() string {
 
 // String returns the ORM tag string for a column
 func (tag *OrmTag) String() string {
+	if tag.Ignore {
+		if tag.Comment != "" {
+			return fmt.Sprintf("`orm:\"-\" description:\"%s\" json:\"%s\"`", tag.Comment, tag.Column)
+		}
+		return fmt.Sprintf("`orm:\"-\"  json:\"%s\"`", tag.Column)
+	}
+	ormOptions := tag.options()
+	if len(ormOptions) == 0 {
+		return ""
+	}
+	if tag.Comment != "" {
+		return fmt.Sprintf("`orm:\"%s\" description:\"%s\" json:\"%s\"`",
+			strings.Join(ormOptions, ";"), tag.Comment, tag.Column)
+	}
+	return fmt.Sprintf("`orm:\"%s\"  json:\"%s\"`", strings.Join(ormOptions, ";"), tag.Column)
+}
+
+// options returns the options of the ORM tag, i.e. column(id) and auto
+func (tag *OrmTag) options() []string {
 	var ormOptions []string
 	if tag.Column != "" {
 		ormOptions = append(ormOptions, fmt.Sprintf("column(%s)", tag.Column))
@@ -264,6 +346,15 @@ func (tag *OrmTag) String() string {
 	if tag.RelM2M {
 		ormOptions = append(ormOptions, "rel(m2m)")
 	}
+	if tag.RelTable != "" {
+		ormOptions = append(ormOptions, fmt.Sprintf("rel_table(%s)", tag.RelTable))
+	}
+	if tag.RelThrough != "" {
+		ormOptions = append(ormOptions, fmt.Sprintf("rel_through(%s)", tag.RelThrough))
+	}
+	if tag.OnDelete != "" {
+		ormOptions = append(ormOptions, fmt.Sprintf("on_delete(%s)", tag.OnDelete))
+	}
 	if tag.Pk {
 		ormOptions = append(ormOptions, "pk")
 	}
@@ -273,15 +364,7 @@ func (tag *OrmTag) String() string {
 	if tag.Default != "" {
 		ormOptions = append(ormOptions, fmt.Sprintf("default(%s)", tag.Default))
 	}
-
-	if len(ormOptions) == 0 {
-		return ""
-	}
-	if tag.Comment != "" {
-		return fmt.Sprintf("`orm:\"%s\" description:\"%s\" json:\"%s\"`",
-			strings.Join(ormOptions, ";"), tag.Comment, tag.Column)
-	}
-	return fmt.Sprintf("`orm:\"%s\"  json:\"%s\"`", strings.Join(ormOptions, ";"), tag.Column)
+	return ormOptions
 }
 
 // StringWithoutOrm returns the tag string for a column without orm
@@ -293,8 +376,105 @@ func (tag *OrmTag) StringWithoutOrm() string {
 	return fmt.Sprintf("`json:\"%s\"`", tag.Column)
 }
 
-func GenerateAppcode(driver, connStr, level, tables, currpath string) {
-	var mode byte
+// GenerateAppcode generates the application code of the tables of the database registered
+// as the orm alias, default if empty, with its models in the package pkg of the application,
+// models for the default database and models/<alias> for the others if empty
+func GenerateAppcode(driver, connStr, level, tables, alias, pkg, currpath string) {
+	mode := appcodeMode(level)
+	selectedTables := selectTables(tables)
+	models := newModelPackage(alias, pkg)
+	checkDriver(driver)
+	gen(utils.SQLDriverName(driver), connStr, mode, selectedTables, models, currpath)
+}
+
+// GenerateAppcodeFromSchema generates the application code of the tables of a schema file
+// instead of a live database. The schema is either a snapshot written by 'bee db snapshot'
+// (*.json) or a SQL file of CREATE TABLE statements in the dialect of driver
+func GenerateAppcodeFromSchema(driver, schemaFile, level, tables, alias, pkg, currpath string) {
+	mode := appcodeMode(level)
+	selectedTables := selectTables(tables)
+	models := newModelPackage(alias, pkg)
+	checkDriver(driver)
+	beeLogger.Log.Info("Analyzing schema tables...")
+	schemaTables, err := LoadSchema(utils.SQLDriverName(driver), schemaFile, selectedTables)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not load schema '%s': %s", schemaFile, err)
+	}
+	writeAppcode(schemaTables, mode, models, currpath)
+}
+
+// modelPackage is the package the models of a database are generated into
+type modelPackage struct {
+	Alias string // orm alias of the database
+	Dir   string // directory of the package in the application, i.e. models/orders
+	Name  string // name of the package, i.e. orders
+}
+
+// newModelPackage returns the package of the models of the database alias, see GenerateAppcode
+func newModelPackage(alias, pkg string) *modelPackage {
+	if alias == "" {
+		alias = "default"
+	}
+	if pkg == "" {
+		pkg = "models"
+		if alias != "default" {
+			pkg = path.Join("models", alias)
+		}
+	}
+	pkg = path.Clean(filepath.ToSlash(pkg))
+	if path.IsAbs(pkg) || pkg == ".." || strings.HasPrefix(pkg, "../") {
+		beeLogger.Log.Fatalf("Invalid package '%s'. It must be a directory of the application, i.e. models/%s", pkg, alias)
+	}
+	name := path.Base(pkg)
+	if !isIdentifier(name) {
+		beeLogger.Log.Fatalf("Invalid package '%s'. Its name '%s' is not a Go identifier", pkg, name)
+	}
+	return &modelPackage{Alias: alias, Dir: pkg, Name: name}
+}
+
+// isIdentifier reports whether name is a Go identifier, and not a keyword
+func isIdentifier(name string) bool {
+	if name == "" || token.Lookup(name).IsKeyword() {
+		return false
+	}
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// controllerModels maps the models served by the controllers generated so far to their package
+var controllerModels = make(map[string]*modelPackage)
+
+// checkControllerNames stops the generation when the controllers of the models of tables,
+// which share the controllers package whatever the models package, clash with those of
+// another database generated by the same command
+func checkControllerNames(tables []*Table, models *modelPackage) {
+	for _, tb := range tables {
+		if tb.Pk == "" {
+			continue
+		}
+		if other, ok := controllerModels[tb.GoName]; ok && other.Dir != models.Dir {
+			beeLogger.Log.Fatalf("Model %s of %s and the one of %s share the same controller. Name one of them in the naming tables of the Beefile, i.e. %s.%s.",
+				tb.GoName, models.Dir, other.Dir, models.Alias, tb.Name)
+		}
+		controllerModels[tb.GoName] = models
+	}
+}
+
+// Base returns the qualifier of IBaseDao and BaseDao, which belong to the models package.
+// It is empty for the models package itself.
+func (p *modelPackage) Base() string {
+	if p.Dir == "models" {
+		return ""
+	}
+	return "models."
+}
+
+// appcodeMode returns the generated parts of a level
+func appcodeMode(level string) (mode byte) {
 	switch level {
 	case "1":
 		mode = OModel
@@ -305,6 +485,11 @@ func GenerateAppcode(driver, connStr, level, tables, currpath string) {
 	default:
 		beeLogger.Log.Fatal("Invalid level value. Must be either \"1\", \"2\", or \"3\"")
 	}
+	return
+}
+
+// selectTables returns the set of a comma separated list of tables, nil for all tables
+func selectTables(tables string) map[string]bool {
 	var selectedTables map[string]bool
 	if tables != "" {
 		selectedTables = make(map[string]bool)
@@ -312,46 +497,66 @@ func GenerateAppcode(driver, connStr, level, tables, currpath string) {
 			selectedTables[v] = true
 		}
 	}
+	return selectedTables
+}
+
+func checkDriver(driver string) {
 	switch driver {
 	case "mysql":
 	case "postgres":
-	case "sqlite":
-		beeLogger.Log.Fatal("Generating app code from SQLite database is not supported yet.")
+	case "sqlite", "sqlite3":
 	default:
 		beeLogger.Log.Fatal("Unknown database driver. Must be either \"mysql\", \"postgres\" or \"sqlite\"")
 	}
-	gen(driver, connStr, mode, selectedTables, currpath)
 }
 
 // Generate takes table, column and foreign key information from database connection
 // and generate corresponding golang source files
-func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, apppath string) {
+func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, models *modelPackage, apppath string) {
+	tables := readTables(dbms, connStr, selectedTableNames)
+	writeAppcode(tables, mode, models, apppath)
+}
+
+// readTables takes table, column and foreign key information of the selected tables,
+// or of all the tables, from database connection
+func readTables(dbms, connStr string, selectedTableNames map[string]bool) []*Table {
 	db, err := sql.Open(dbms, connStr)
 	if err != nil {
 		beeLogger.Log.Fatalf("Could not connect to '%s' database using '%s': %s", dbms, connStr, err)
 	}
 	defer db.Close()
-	if trans, ok := dbDriver[dbms]; ok {
-		beeLogger.Log.Info("Analyzing database tables...")
-		var tableNames []string
-		if len(selectedTableNames) != 0 {
-			for tableName := range selectedTableNames {
-				tableNames = append(tableNames, tableName)
-			}
-		} else {
-			tableNames = trans.GetTableNames(db)
+	trans, ok := dbDriver[dbms]
+	if !ok {
+		beeLogger.Log.Fatalf("Generating app code from '%s' database is not supported yet.", dbms)
+	}
+	beeLogger.Log.Info("Analyzing database tables...")
+	var tableNames []string
+	if len(selectedTableNames) != 0 {
+		for tableName := range selectedTableNames {
+			tableNames = append(tableNames, tableName)
 		}
-		tables := getTableObjects(tableNames, db, trans)
-		mvcPath := new(MvcPath)
-		mvcPath.ModelPath = path.Join(apppath, "models")
-		mvcPath.ControllerPath = path.Join(apppath, "controllers")
-		mvcPath.RouterPath = path.Join(apppath, "routers")
-		createPaths(mode, mvcPath)
-		pkgPath := getPackagePath(apppath)
-		writeSourceFiles(pkgPath, tables, mode, mvcPath)
+		sort.Strings(tableNames)
 	} else {
-		beeLogger.Log.Fatalf("Generating app code from '%s' database is not supported yet.", dbms)
+		tableNames = trans.GetTableNames(db)
 	}
+	return getTableObjects(tableNames, db, trans)
+}
+
+// writeAppcode generates the source files of tables in the application
+func writeAppcode(tables []*Table, mode byte, models *modelPackage, apppath string) {
+	mvcPath := new(MvcPath)
+	mvcPath.ModelPath = path.Join(apppath, models.Dir)
+	mvcPath.ControllerPath = path.Join(apppath, "controllers")
+	mvcPath.RouterPath = path.Join(apppath, "routers")
+	createPaths(mode, mvcPath)
+	pkgPath := getPackagePath(apppath)
+	nameTables(tables, models.Alias)
+	if mode&OController == OController {
+		checkControllerNames(tables, models)
+	}
+	resolveRelations(tables, pkgPath+"/"+models.Dir)
+	mapColumnTypes(tables)
+	writeSourceFiles(pkgPath, tables, mode, models, mvcPath)
 }
 
 // GetTableNames returns a slice of table names in the current database
@@ -472,6 +677,7 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 		if err != nil {
 			beeLogger.Log.Fatalf("%s", err)
 		}
+		col.SQLType = dataType
 
 		// Tag info
 		tag := new(OrmTag)
@@ -486,57 +692,44 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 				tag.Pk = true
 			}
 		} else {
-			fkCol, isFk := table.Fk[colName]
-			isBl := false
-			if isFk {
-				_, isBl = blackList[fkCol.RefTable]
+			// if the name of column is Id, and it's not primary key
+			if colName == "id" {
+				col.Name = "Id_RENAME"
 			}
-			// check if the current column is a foreign key
-			if isFk && !isBl {
-				tag.RelFk = true
-				refStructName := fkCol.RefTable
-				col.Name = utils.CamelCase(colName)
-				col.Type = "*" + utils.CamelCase(refStructName)
-			} else {
-				// if the name of column is Id, and it's not primary key
-				if colName == "id" {
-					col.Name = "Id_RENAME"
-				}
-				if isNullable == "YES" {
-					tag.Null = true
-				}
-				if isSQLSignedIntType(dataType) {
-					sign := extractIntSignness(columnType)
-					if sign == "unsigned" && extra != "auto_increment" {
-						col.Type, err = mysqlDB.GetGoDataType(dataType + " " + sign)
-						if err != nil {
-							beeLogger.Log.Fatalf("%s", err)
-						}
-					}
-				}
-				if isSQLStringType(dataType) {
-					tag.Size = extractColSize(columnType)
-				}
-				if isSQLTemporalType(dataType) {
-					tag.Type = dataType
-					// check auto_now, auto_now_add
-					if columnDefault == "CURRENT_TIMESTAMP" && extra == "on update CURRENT_TIMESTAMP" {
-						tag.AutoNow = true
-					} else if columnDefault == "CURRENT_TIMESTAMP" {
-						tag.AutoNowAdd = true
+			if isNullable == "YES" {
+				tag.Null = true
+			}
+			if isSQLSignedIntType(dataType) {
+				sign := extractIntSignness(columnType)
+				if sign == "unsigned" && extra != "auto_increment" {
+					col.Type, err = mysqlDB.GetGoDataType(dataType + " " + sign)
+					if err != nil {
+						beeLogger.Log.Fatalf("%s", err)
 					}
-					// need to import time package
-					table.ImportTimePkg = true
-				}
-				if isSQLDecimal(dataType) {
-					tag.Digits, tag.Decimals = extractDecimal(columnType)
 				}
-				if isSQLBinaryType(dataType) {
-					tag.Size = extractColSize(columnType)
-				}
-				if isSQLBitType(dataType) {
-					tag.Size = extractColSize(columnType)
+			}
+			if isSQLStringType(dataType) {
+				tag.Size = extractColSize(columnType)
+			}
+			if isSQLTemporalType(dataType) {
+				tag.Type = dataType
+				// check auto_now, auto_now_add
+				if columnDefault == "CURRENT_TIMESTAMP" && extra == "on update CURRENT_TIMESTAMP" {
+					tag.AutoNow = true
+				} else if columnDefault == "CURRENT_TIMESTAMP" {
+					tag.AutoNowAdd = true
 				}
+				// need to import time package
+				table.ImportTimePkg = true
+			}
+			if isSQLDecimal(dataType) {
+				tag.Digits, tag.Decimals = extractDecimal(columnType)
+			}
+			if isSQLBinaryType(dataType) {
+				tag.Size = extractColSize(columnType)
+			}
+			if isSQLBitType(dataType) {
+				tag.Size = extractColSize(columnType)
 			}
 		}
 		col.Tag = tag
@@ -645,7 +838,8 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 			END AS column_type,
 			is_nullable,
 			column_default,
-			'' AS extra
+			'' AS extra,
+			udt_name
 		FROM
 			information_schema.columns
 		WHERE
@@ -659,12 +853,12 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 
 	for colDefRows.Next() {
 		// datatype as bytes so that SQL <null> values can be retrieved
-		var colNameBytes, dataTypeBytes, columnTypeBytes, isNullableBytes, columnDefaultBytes, extraBytes []byte
-		if err := colDefRows.Scan(&colNameBytes, &dataTypeBytes, &columnTypeBytes, &isNullableBytes, &columnDefaultBytes, &extraBytes); err != nil {
+		var colNameBytes, dataTypeBytes, columnTypeBytes, isNullableBytes, columnDefaultBytes, extraBytes, udtNameBytes []byte
+		if err := colDefRows.Scan(&colNameBytes, &dataTypeBytes, &columnTypeBytes, &isNullableBytes, &columnDefaultBytes, &extraBytes, &udtNameBytes); err != nil {
 			beeLogger.Log.Fatalf("Could not query INFORMATION_SCHEMA for column information: %s", err)
 		}
-		colName, dataType, columnType, isNullable, columnDefault, extra :=
-			string(colNameBytes), string(dataTypeBytes), string(columnTypeBytes), string(isNullableBytes), string(columnDefaultBytes), string(extraBytes)
+		colName, dataType, columnType, isNullable, columnDefault, extra, udtName :=
+			string(colNameBytes), string(dataTypeBytes), string(columnTypeBytes), string(isNullableBytes), string(columnDefaultBytes), string(extraBytes), string(udtNameBytes)
 		// Create a column
 		col := new(Column)
 		col.Name = utils.CamelCase(colName)
@@ -672,6 +866,11 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 		if err != nil {
 			beeLogger.Log.Fatalf("%s", err)
 		}
+		col.SQLType = dataType
+		if dataType == "ARRAY" {
+			// the type of the elements is named after an underscore, i.e. _int4
+			col.SQLType = postgresTypeName(strings.TrimPrefix(udtName, "_")) + "[]"
+		}
 
 		// Tag info
 		tag := new(OrmTag)
@@ -685,48 +884,35 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 				tag.Pk = true
 			}
 		} else {
-			fkCol, isFk := table.Fk[colName]
-			isBl := false
-			if isFk {
-				_, isBl = blackList[fkCol.RefTable]
+			// if the name of column is Id, and it's not primary key
+			if colName == "id" {
+				col.Name = "Id_RENAME"
 			}
-			// check if the current column is a foreign key
-			if isFk && !isBl {
-				tag.RelFk = true
-				refStructName := fkCol.RefTable
-				col.Name = utils.CamelCase(colName)
-				col.Type = "*" + utils.CamelCase(refStructName)
-			} else {
-				// if the name of column is Id, and it's not primary key
-				if colName == "id" {
-					col.Name = "Id_RENAME"
-				}
-				if isNullable == "YES" {
-					tag.Null = true
-				}
-				if isSQLStringType(dataType) {
-					tag.Size = extractColSize(columnType)
-				}
-				if isSQLTemporalType(dataType) || strings.HasPrefix(dataType, "timestamp") {
-					tag.Type = dataType
-					// check auto_now, auto_now_add
-					if columnDefault == "CURRENT_TIMESTAMP" && extra == "on update CURRENT_TIMESTAMP" {
-						tag.AutoNow = true
-					} else if columnDefault == "CURRENT_TIMESTAMP" {
-						tag.AutoNowAdd = true
-					}
-					// need to import time package
-					table.ImportTimePkg = true
-				}
-				if isSQLDecimal(dataType) {
-					tag.Digits, tag.Decimals = extractDecimal(columnType)
-				}
-				if isSQLBinaryType(dataType) {
-					tag.Size = extractColSize(columnType)
-				}
-				if isSQLStrangeType(dataType) {
-					tag.Type = dataType
+			if isNullable == "YES" {
+				tag.Null = true
+			}
+			if isSQLStringType(dataType) {
+				tag.Size = extractColSize(columnType)
+			}
+			if isSQLTemporalType(dataType) || strings.HasPrefix(dataType, "timestamp") {
+				tag.Type = dataType
+				// check auto_now, auto_now_add
+				if columnDefault == "CURRENT_TIMESTAMP" && extra == "on update CURRENT_TIMESTAMP" {
+					tag.AutoNow = true
+				} else if columnDefault == "CURRENT_TIMESTAMP" {
+					tag.AutoNowAdd = true
 				}
+				// need to import time package
+				table.ImportTimePkg = true
+			}
+			if isSQLDecimal(dataType) {
+				tag.Digits, tag.Decimals = extractDecimal(columnType)
+			}
+			if isSQLBinaryType(dataType) {
+				tag.Size = extractColSize(columnType)
+			}
+			if isSQLStrangeType(dataType) {
+				tag.Type = dataType
 			}
 		}
 		col.Tag = tag
@@ -742,36 +928,197 @@ func (*PostgresDB) GetGoDataType(sqlType string) (string, error) {
 	return "", fmt.Errorf("data type '%s' not found", sqlType)
 }
 
+// GetTableNames for SQLite
+func (*SqliteDB) GetTableNames(db *sql.DB) (tables []string) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not show tables: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			beeLogger.Log.Fatalf("Could not show tables: %s", err)
+		}
+		tables = append(tables, name)
+	}
+	return
+}
+
+// GetConstraints for SQLite reads the primary key, single column unique indexes
+// and foreign keys of a table with PRAGMA statements
+func (*SqliteDB) GetConstraints(db *sql.DB, table *Table, blackList map[string]bool) {
+	var pks []string
+	for _, col := range sqlitePragma(db, "table_info", table.Name) {
+		if col["pk"] != "0" {
+			pks = append(pks, col["name"])
+		}
+	}
+	if len(pks) == 1 {
+		table.Pk = pks[0]
+	} else if len(pks) > 1 {
+		// add table to blacklist so that other struct will not reference it, because we are not
+		// registering blacklisted tables
+		blackList[table.Name] = true
+	}
+
+	for _, index := range sqlitePragma(db, "index_list", table.Name) {
+		if index["unique"] != "1" || index["origin"] == "pk" {
+			continue
+		}
+		if cols := sqlitePragma(db, "index_info", index["name"]); len(cols) == 1 {
+			table.Uk = append(table.Uk, cols[0]["name"])
+		}
+	}
+
+	for _, ref := range sqlitePragma(db, "foreign_key_list", table.Name) {
+		fk := new(ForeignKey)
+		fk.Name = ref["from"]
+		fk.RefSchema = "main"
+		fk.RefTable = ref["table"]
+		fk.RefColumn = ref["to"]
+		table.Fk[fk.Name] = fk
+	}
+}
+
+// GetColumns for SQLite
+func (sqliteDB *SqliteDB) GetColumns(db *sql.DB, table *Table, blackList map[string]bool) {
+	for _, def := range sqlitePragma(db, "table_info", table.Name) {
+		colName, isNullable, columnDefault := def["name"], def["notnull"] == "0", def["dflt_value"]
+		// declared types are free-form, i.e. VARCHAR(255) or "unsigned big int"
+		columnType := strings.ToLower(strings.Join(strings.Fields(def["type"]), " "))
+		dataType := columnType
+		if i := strings.Index(dataType, "("); i >= 0 {
+			dataType = strings.TrimSpace(dataType[:i])
+			columnType = dataType + strings.Replace(columnType[i:], " ", "", -1)
+		}
+
+		// create a column
+		col := new(Column)
+		col.Name = utils.CamelCase(colName)
+		col.Type, _ = sqliteDB.GetGoDataType(dataType)
+		col.SQLType = dataType
+
+		// Tag info
+		tag := new(OrmTag)
+		tag.Column = colName
+		if table.Pk == colName {
+			col.Name = "Id"
+			col.Type = "int"
+			// an INTEGER PRIMARY KEY column is an alias of the rowid
+			if dataType == "integer" {
+				tag.Auto = true
+			} else {
+				tag.Pk = true
+			}
+		} else {
+			// if the name of column is Id, and it's not primary key
+			if colName == "id" {
+				col.Name = "Id_RENAME"
+			}
+			if isNullable {
+				tag.Null = true
+			}
+			if isSQLStringType(dataType) && columnType != dataType {
+				tag.Size = extractColSize(columnType)
+			}
+			if isSQLTemporalType(dataType) {
+				tag.Type = dataType
+				// check auto_now_add
+				if strings.ToUpper(columnDefault) == "CURRENT_TIMESTAMP" {
+					tag.AutoNowAdd = true
+				}
+				// need to import time package
+				table.ImportTimePkg = true
+			}
+			if isSQLDecimal(dataType) && strings.Contains(columnType, ",") {
+				tag.Digits, tag.Decimals = extractDecimal(columnType)
+			}
+		}
+		col.Tag = tag
+		table.Columns = append(table.Columns, col)
+	}
+}
+
+// GetGoDataType returns the Go type from the SQLite declared type. Unknown types
+// follow the affinity rules of SQLite, so any declared type has a Go type
+func (*SqliteDB) GetGoDataType(sqlType string) (string, error) {
+	if v, ok := typeMappingSqlite[sqlType]; ok {
+		return v, nil
+	}
+	switch {
+	case strings.Contains(sqlType, "int"):
+		return "int64", nil
+	case strings.Contains(sqlType, "char"), strings.Contains(sqlType, "clob"), strings.Contains(sqlType, "text"):
+		return "string", nil
+	case sqlType == "", strings.Contains(sqlType, "blob"):
+		return "string", nil
+	}
+	return "float64", nil
+}
+
+// sqlitePragma runs a PRAGMA statement on a table or an index and returns its
+// rows as maps of column name to value
+func sqlitePragma(db *sql.DB, pragma, name string) (rows []map[string]string) {
+	query := fmt.Sprintf(`PRAGMA %s("%s")`, pragma, strings.Replace(name, `"`, `""`, -1))
+	res, err := db.Query(query)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not query '%s': %s", query, err)
+	}
+	defer res.Close()
+
+	cols, err := res.Columns()
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not query '%s': %s", query, err)
+	}
+	for res.Next() {
+		// values as bytes so that SQL <null> values can be retrieved
+		values := make([]sql.RawBytes, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := res.Scan(dest...); err != nil {
+			beeLogger.Log.Fatalf("Could not read '%s': %s", query, err)
+		}
+		row := make(map[string]string, len(cols))
+		for i, col := range cols {
+			row[col] = string(values[i])
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
 // deleteAndRecreatePaths removes several directories completely
 func createPaths(mode byte, paths *MvcPath) {
 	if (mode & OModel) == OModel {
-		os.Mkdir(paths.ModelPath, 0777)
-		os.Mkdir(path.Join(paths.ModelPath, "po"), 0777)
+		utils.MkdirGenerated(path.Join(paths.ModelPath, "po"))
 	}
 	if (mode & OController) == OController {
-		os.Mkdir(paths.ControllerPath, 0777)
-		os.Mkdir(path.Join(paths.ControllerPath, "vo"), 0777)
+		utils.MkdirGenerated(path.Join(paths.ControllerPath, "vo"))
 	}
 	if (mode & ORouter) == ORouter {
-		os.Mkdir(paths.RouterPath, 0777)
+		utils.MkdirGenerated(paths.RouterPath)
 	}
 }
 
 // writeSourceFiles generates source files for model/controller/router
 // It will wipe the following directories and recreate them:./models, ./controllers, ./routers
 // Newly geneated files will be inside these folders.
-func writeSourceFiles(pkgPath string, tables []*Table, mode byte, paths *MvcPath) {
+func writeSourceFiles(pkgPath string, tables []*Table, mode byte, models *modelPackage, paths *MvcPath) {
 	if (OModel & mode) == OModel {
 		beeLogger.Log.Info("Creating model files...")
-		writeModelFiles(tables, paths.ModelPath, pkgPath)
+		writeModelFiles(tables, paths.ModelPath, pkgPath, models)
 		beeLogger.Log.Info("Creating po files...")
-		writePoFiles(tables, filepath.Join(paths.ModelPath, "po"))
+		writePoFiles(tables, filepath.Join(paths.ModelPath, "po"), pkgPath, models)
 	}
 	if (OController & mode) == OController {
 		beeLogger.Log.Info("Creating controller files...")
-		writeControllerFiles(tables, paths.ControllerPath, pkgPath)
+		writeControllerFiles(tables, paths.ControllerPath, pkgPath, models)
 		beeLogger.Log.Info("Creating vo files...")
-		writeVoFiles(tables, filepath.Join(paths.ControllerPath, "vo"), pkgPath)
+		writeVoFiles(tables, filepath.Join(paths.ControllerPath, "vo"), pkgPath, models)
 	}
 	if (ORouter & mode) == ORouter {
 		beeLogger.Log.Info("Creating router files...")
@@ -779,189 +1126,79 @@ func writeSourceFiles(pkgPath string, tables []*Table, mode byte, paths *MvcPath
 	}
 }
 
-// writeModelFiles generates model files
-func writeModelFiles(tables []*Table, mPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
+// appcodeData is the data the appcode templates are executed with
+type appcodeData struct {
+	PkgPath string        // import path of the application, e.g. github.com/user/app
+	Models  *modelPackage // package of the models
+	Name    string        // Go name of the table, e.g. UserAccount for user_account
+	Table   *Table        // table of the generated file, unset for the router
+	Tables  []*Table      // tables served by the router, i.e. those having a primary key
+}
 
+// writeModelFiles generates model files
+func writeModelFiles(tables []*Table, mPath string, pkgPath string, models *modelPackage) {
 	for _, tb := range tables {
-		filename := "db_" + getFileName(tb.Name)
-		fpath := path.Join(mPath, filename+".go")
-		f, err := openFile(fpath)
-		if err != nil {
-			continue
-		}
-		var template string
+		filename := "db_" + getFileName(tb.FileName)
+		name := "appcode/model.go.tpl"
 		if tb.Pk == "" {
-			template = StructModelTPL
-		} else {
-			template = ModelTPL
-		}
-
-		fileStr := strings.Replace(template, "{{modelName}}", utils.CamelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{tableName}}", tb.Name, -1)
-
-		// If table contains time field, import time.Time package
-		importTimePkg := ""
-		if tb.ImportTimePkg {
-			importTimePkg = "import \"time\"\n"
+			name = "appcode/struct_model.go.tpl"
 		}
-
-		fileStr = strings.Replace(fileStr, "{{pkgPath}}", pkgPath, -1)
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Errorf("Could not write model file to '%s': %s", fpath, err)
-			continue
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		writeAppcodeFile(path.Join(mPath, filename+".go"), name, appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb})
 	}
 }
 
-// writePoFiles generates model files
-func writePoFiles(tables []*Table, poPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
+// writePoFiles generates po files
+func writePoFiles(tables []*Table, poPath string, pkgPath string, models *modelPackage) {
 	for _, tb := range tables {
 		if tb.Pk == "" {
 			continue
 		}
-		fpath := path.Join(poPath, getFileName(tb.Name)+"_po.go")
-		f, err := openFile(fpath)
-		if err != nil {
-			continue
-		}
-
-		fileStr := strings.Replace(PoTPL, "{{poStruct}}", tb.StringWithSuffix("Po", true), 1)
-		// If table contains time field, import time.Time package
-		importTimePkg := ""
-		if tb.ImportTimePkg {
-			importTimePkg = "import \"time\"\n"
-		}
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Errorf("Could not write po file to '%s': %s", fpath, err)
-			continue
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		fpath := path.Join(poPath, getFileName(tb.FileName)+"_po.go")
+		writeAppcodeFile(fpath, "appcode/po.go.tpl", appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb})
 	}
 }
 
 // writeControllerFiles generates controller files
-func writeControllerFiles(tables []*Table, cPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
+func writeControllerFiles(tables []*Table, cPath string, pkgPath string, models *modelPackage) {
 	for _, tb := range tables {
 		if tb.Pk == "" {
 			continue
 		}
-		filename := getFileName(tb.Name) + "_controller"
+		filename := getFileName(tb.FileName) + "_controller"
 		fpath := path.Join(cPath, filename+".go")
-		f, err := openFile(fpath)
-		if err != nil {
-			continue
-		}
-
-		fileStr := strings.Replace(CtrlTPL, "{{ctrlName}}", utils.CamelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{pkgPath}}", pkgPath, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Errorf("Could not write controller file to '%s': %s", fpath, err)
-			continue
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		writeAppcodeFile(fpath, "appcode/controller.go.tpl", appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb})
 	}
 }
 
-// writeControllerFiles generates vo files
-func writeVoFiles(tables []*Table, voPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
+// writeVoFiles generates vo files
+func writeVoFiles(tables []*Table, voPath string, pkgPath string, models *modelPackage) {
 	for _, tb := range tables {
 		if tb.Pk == "" {
 			continue
 		}
-		fpath := path.Join(voPath, getFileName(tb.Name)+"_vo.go")
-		f, err := openFile(fpath)
-		if err != nil {
-			continue
-		}
-
-		fileStr := strings.Replace(VoTPL, "{{voName}}", utils.CamelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{voStruct}}", tb.StringWithSuffix("Vo", false), -1)
-
-		// If table contains time field, import time.Time package
-		importTimePkg := ""
-		if tb.ImportTimePkg {
-			importTimePkg = "import \"time\"\n"
-		}
-
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Errorf("Could not write model file to '%s': %s", fpath, err)
-			continue
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		fpath := path.Join(voPath, getFileName(tb.FileName)+"_vo.go")
+		writeAppcodeFile(fpath, "appcode/vo.go.tpl", appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb})
 	}
 }
 
 // writeRouterFile generates router file
 func writeRouterFile(tables []*Table, rPath string, pkgPath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
-	var nameSpaces []string
+	var served []*Table
 	for _, tb := range tables {
-		if tb.Pk == "" {
-			continue
+		if tb.Pk != "" {
+			served = append(served, tb)
 		}
-		// Add namespaces
-		nameSpace := strings.Replace(NamespaceTPL, "{{nameSpace}}", tb.Name, -1)
-		nameSpace = strings.Replace(nameSpace, "{{ctrlName}}", utils.CamelCase(tb.Name), -1)
-		nameSpaces = append(nameSpaces, nameSpace)
 	}
-	// Add export controller
+	// The namespaces of an existing router are merged rather than overwritten
 	fpath := filepath.Join(rPath, "router.go")
-	routerStr := strings.Replace(RouterTPL, "{{nameSpaces}}", strings.Join(nameSpaces, ""), 1)
-	routerStr = strings.Replace(routerStr, "{{pkgPath}}", pkgPath, 1)
-	f, err := openFile(fpath)
-	if err != nil {
-		return
-	}
-	if _, err := f.WriteString(routerStr); err != nil {
-		beeLogger.Log.Errorf("Could not write router file to '%s': %s", fpath, err)
-		return
-	}
-	utils.CloseFile(f)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	utils.FormatSourceCode(fpath)
+	content := templates.MustExecute("appcode/router.go.tpl", appcodeData{PkgPath: pkgPath, Tables: served})
+	utils.WriteMergedFile(fpath, content, routerMerger(served, pkgPath))
 }
 
-func openFile(fpath string) (f *os.File, err error) {
-	if utils.IsExist(fpath) {
-		beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-		if utils.AskForConfirmation() {
-			f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				return f, err
-			}
-		} else {
-			beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-			return f, errors.New(fmt.Sprintf("Skipped create file '%s'", fpath))
-		}
-	} else {
-		f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-		if err != nil {
-			beeLogger.Log.Warnf("%s", err)
-			return f, err
-		}
-	}
-	return f, err
+// writeAppcodeFile executes the template name with data into fpath, replacing only the
+// generated regions of an existing file
+func writeAppcodeFile(fpath, name string, data appcodeData) {
+	utils.WriteMergedFile(fpath, templates.MustExecute(name, data), utils.MergeRegions)
 }
 
 func isSQLTemporalType(t string) bool {
@@ -1022,99 +1259,278 @@ func getFileName(tbName string) (filename string) {
 }
 
 func getPackagePath(curpath string) (packpath string) {
-	gopath := os.Getenv("GOPATH")
-	if gopath == "" {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
-
-	beeLogger.Log.Debugf("GOPATH: %s", utils.FILE(), utils.LINE(), gopath)
-
-	appsrcpath := ""
-	haspath := false
-	wgopath := filepath.SplitList(gopath)
-
-	for _, wg := range wgopath {
-		wg, _ = filepath.EvalSymlinks(filepath.Join(wg, "src"))
-		if strings.HasPrefix(strings.ToLower(curpath), strings.ToLower(wg)) {
-			haspath = true
-			appsrcpath = wg
-			break
-		}
-	}
-
-	if !haspath {
-		beeLogger.Log.Fatalf("Cannot generate application code outside of GOPATH '%s' compare with CWD '%s'", gopath, curpath)
+	packpath, err := utils.GetPackagePath(curpath)
+	if err != nil {
+		beeLogger.Log.Fatalf("Cannot generate application code: %s", err)
 	}
-
-	if curpath == appsrcpath {
+	if packpath == "" || packpath == "." {
 		beeLogger.Log.Fatal("Cannot generate application code outside of application path")
 	}
 
-	packpath = strings.Join(strings.Split(curpath[len(appsrcpath)+1:], string(filepath.Separator)), "/")
+	beeLogger.Log.Debugf("Package path: %s", utils.FILE(), utils.LINE(), packpath)
 	return
 }
 
 const (
-	StructModelTPL = `package models
-{{importTimePkg}}
-{{modelStruct}}
+	StructModelTPL = `package {{.Models.Name}}
+
+// bee:generated begin model
+
+{{with .Table.Imports}}{{if eq (len .) 1}}import {{printf "%q" (index . 0)}}
+{{else}}import (
+{{range .}}	{{printf "%q" .}}
+{{end}})
+{{end}}{{end}}{{.Table.String}}
+// bee:generated end model
 `
-	ModelTPL = `package models
+	ModelTPL = `package {{.Models.Name}}
 
 import (
-	"github.com/astaxie/beego/orm"
-	"{{pkgPath}}/models/po"
+	"errors"
 	"reflect"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+{{- if .Models.Base}}
+	"{{.PkgPath}}/models"
+{{- end}}
+	"{{.PkgPath}}/{{.Models.Dir}}/po"
 )
 
-var {{modelName}}DaoImpl I{{modelName}}Dao
+var {{.Name}}DaoImpl I{{.Name}}Dao
+
+type I{{.Name}}Dao interface {
+	{{.Models.Base}}IBaseDao
+	Add{{.Name}}(m *{{.Name}}) (int64, error)
+	Get{{.Name}}ById(id int) (*{{.Name}}, error)
+	GetAll{{.Name}}(query map[string]string, sortby []string, order []string, offset int64, limit int64) ([]{{.Name}}, int64, error)
+	Update{{.Name}}ById(m *{{.Name}}) error
+	Delete{{.Name}}(id int) error
+{{- range .Table.Relations}}
+	Load{{$.Name}}{{.Name}}(m *{{$.Name}}) error
+{{- end}}
+}
+
+type {{.Name}}Dao struct {
+	{{.Models.Base}}BaseDao
+}
+
+// bee:generated begin model
+
+type {{.Name}} struct {
+	po.{{.Name}}Po
+{{- range .Table.Relations}}
+	{{.String}}
+{{- end}}
+}
+
+// bee:generated end model
+
+func New{{.Name}}Dao() I{{.Name}}Dao {
+	if {{.Name}}DaoImpl == nil {
+		{{.Name}}DaoImpl = &{{.Name}}Dao{ {{- .Models.Base}}BaseDao{EntityType: reflect.TypeOf(new({{.Name}})).Elem()}}
+	}
+	return {{.Name}}DaoImpl
+}
+
+func (t *{{.Name}}) GetTableName() string {
+	return "{{.Table.Name}}"
+}
+
+func (t *{{.Name}}) GetDBName() string {
+	return "{{.Models.Alias}}"
+}
+{{- if .Table.FkRelations}}
+
+// setRelations sets the relation fields of m from its foreign key columns
+func (m *{{.Name}}) setRelations() {
+{{- range .Table.FkRelations}}
+	m.{{.Name}} = nil
+	if m.{{.Column.Name}} != 0 {
+		m.{{.Name}} = &{{.Model}}{}
+		m.{{.Name}}.Id = int(m.{{.Column.Name}})
+	}
+{{- end}}
+}
+
+// setForeignKeys sets the foreign key columns of m from its relation fields
+func (m *{{.Name}}) setForeignKeys() {
+{{- range .Table.FkRelations}}
+	m.{{.Column.Name}} = 0
+	if m.{{.Name}} != nil {
+		m.{{.Column.Name}} = {{.Column.Type}}(m.{{.Name}}.Id)
+	}
+{{- end}}
+}
+{{- end}}
+
+// ormer returns an Ormer using the database of {{.Name}}
+func (d *{{.Name}}Dao) ormer() orm.Ormer {
+	o := orm.NewOrm()
+	o.Using(new({{.Name}}).GetDBName())
+	return o
+}
 
-type I{{modelName}}Dao interface {
-	IBaseDao
+// Add{{.Name}} inserts a new {{.Name}} into the database and returns its Id
+func (d *{{.Name}}Dao) Add{{.Name}}(m *{{.Name}}) (int64, error) {
+{{- if .Table.FkRelations}}
+	m.setRelations()
+{{- end}}
+	return d.ormer().Insert(m)
 }
 
-type {{modelName}}Dao struct {
-	BaseDao
+// Get{{.Name}}ById retrieves {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (d *{{.Name}}Dao) Get{{.Name}}ById(id int) (*{{.Name}}, error) {
+	m := &{{.Name}}{}
+	m.Id = id
+	if err := d.ormer().Read(m); err != nil {
+		return nil, err
+	}
+{{- if .Table.FkRelations}}
+	m.setForeignKeys()
+{{- end}}
+	return m, nil
 }
 
-type {{modelName}} struct {
-	po.{{modelName}}Po
+// GetAll{{.Name}} retrieves a page of {{.Name}} matching the query, along with
+// the number of matching records. Query keys use the dot notation, i.e. name.contains
+func (d *{{.Name}}Dao) GetAll{{.Name}}(query map[string]string, sortby []string, order []string,
+	offset int64, limit int64) (ml []{{.Name}}, total int64, err error) {
+	qs := d.ormer().QueryTable(new({{.Name}}))
+	for k, v := range query {
+		// rewrite dot-notation to Object__Attribute
+		k = strings.Replace(k, ".", "__", -1)
+		qs = qs.Filter(k, v)
+	}
+
+	// order by:
+	var sortFields []string
+	if len(sortby) != 0 {
+		if len(sortby) == len(order) {
+			// 1) for each sort field, there is an associated order
+			for i, v := range sortby {
+				switch order[i] {
+				case "desc":
+					sortFields = append(sortFields, "-"+v)
+				case "asc":
+					sortFields = append(sortFields, v)
+				default:
+					return nil, 0, errors.New("Error: Invalid order. Must be either [asc|desc]")
+				}
+			}
+		} else if len(order) == 1 {
+			// 2) there is exactly one order, all the sorted fields will be sorted by this order
+			for _, v := range sortby {
+				switch order[0] {
+				case "desc":
+					sortFields = append(sortFields, "-"+v)
+				case "asc":
+					sortFields = append(sortFields, v)
+				default:
+					return nil, 0, errors.New("Error: Invalid order. Must be either [asc|desc]")
+				}
+			}
+		} else {
+			return nil, 0, errors.New("Error: 'sortby', 'order' sizes mismatch or 'order' size is not 1")
+		}
+	} else if len(order) != 0 {
+		return nil, 0, errors.New("Error: unused 'order' fields")
+	}
+
+	if total, err = qs.Count(); err != nil {
+		return nil, 0, err
+	}
+	if _, err = qs.OrderBy(sortFields...).Limit(limit, offset).All(&ml); err != nil {
+		return nil, 0, err
+	}
+{{- if .Table.FkRelations}}
+	for i := range ml {
+		ml[i].setForeignKeys()
+	}
+{{- end}}
+	return ml, total, nil
 }
 
-func New{{modelName}}Dao() I{{modelName}}Dao {
-	if {{modelName}}DaoImpl == nil {
-		{{modelName}}DaoImpl = &{{modelName}}Dao{BaseDao{EntityType: reflect.TypeOf(new({{modelName}})).Elem()}}
+// Update{{.Name}}ById updates {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (d *{{.Name}}Dao) Update{{.Name}}ById(m *{{.Name}}) error {
+	o := d.ormer()
+	v := {{.Name}}{}
+	v.Id = m.Id
+	if err := o.Read(&v); err != nil {
+		return err
 	}
-	return {{modelName}}DaoImpl
+{{- if .Table.FkRelations}}
+	m.setRelations()
+{{- end}}
+	_, err := o.Update(m)
+	return err
 }
 
-func (t *{{modelName}}) GetTableName() string {
-	return "{{tableName}}"
+// Delete{{.Name}} deletes {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (d *{{.Name}}Dao) Delete{{.Name}}(id int) error {
+	o := d.ormer()
+	v := {{.Name}}{}
+	v.Id = id
+	if err := o.Read(&v); err != nil {
+		return err
+	}
+	_, err := o.Delete(&v)
+	return err
 }
+{{- range .Table.Relations}}
 
-func (t *{{modelName}}) GetDBName() string {
-	return "default"
+// Load{{$.Name}}{{.Name}} loads the {{.Name}} of m from the database
+func (d *{{$.Name}}Dao) Load{{$.Name}}{{.Name}}(m *{{$.Name}}) error {
+	_, err := d.ormer().LoadRelated(m, "{{.Name}}")
+	return err
 }
+{{- end}}
 
 func init() {
-	orm.RegisterModel(new({{modelName}}))
+	orm.RegisterModel(new({{.Name}}))
 }
 `
 	PoTPL = `package po
-{{importTimePkg}}
-{{poStruct}}
+
+// bee:generated begin po
+
+{{with .Table.Imports}}{{if eq (len .) 1}}import {{printf "%q" (index . 0)}}
+{{else}}import (
+{{range .}}	{{printf "%q" .}}
+{{end}})
+{{end}}{{end}}{{.Table.StringWithSuffix "Po" true}}
+// bee:generated end po
 `
 	CtrlTPL = `package controllers
 
-// @TagName {{ctrlName}}
-// @Description {{ctrlName}}Controller operations for {{ctrlName}}
-type {{ctrlName}}Controller struct {
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+	"{{.PkgPath}}/controllers/vo"
+	"{{.PkgPath}}/{{.Models.Dir}}"
+	"{{.PkgPath}}/{{.Models.Dir}}/po"
+)
+
+// @TagName {{.Name}}
+// @Description {{.Name}}Controller operations for {{.Name}}
+type {{.Name}}Controller struct {
 	BaseController
 }
 
 // URLMapping ...
-func (c *{{ctrlName}}Controller) URLMapping() {
+func (c *{{.Name}}Controller) URLMapping() {
+	// bee:generated begin urlmapping
 	c.Mapping("Index", c.Index)
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+	// bee:generated end urlmapping
 }
 
 // @Title Index
@@ -1124,34 +1540,207 @@ func (c *{{ctrlName}}Controller) URLMapping() {
 // @Success 200 {object} vo.ApiResponse
 // @Failure 500 system error
 // @router /index [get]
-func (c *{{ctrlName}}Controller) Index() {
+func (c *{{.Name}}Controller) Index() {
 	c.SuccessWithoutData()
 }
+
+// @Title Post
+// @Summary Create {{.Name}}
+// @Description create {{.Name}}
+// @Param body body vo.{{.Name}}Vo true "body for {{.Name}} content"
+// @Success 201 {object} vo.{{.Name}}SingleVo
+// @Failure 400 invalid body
+// @Failure 500 system error
+// @router / [post]
+func (c *{{.Name}}Controller) Post() {
+	var v vo.{{.Name}}Vo
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	m := &{{.Models.Name}}.{{.Name}}{ {{.Name}}Po: po.{{.Name}}Po(v)}
+	if _, err := {{.Models.Name}}.New{{.Name}}Dao().Add{{.Name}}(m); err != nil {
+		c.CustomAbort(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	c.Data["json"] = vo.{{.Name}}SingleVo{Data: vo.{{.Name}}Vo(m.{{.Name}}Po)}
+	c.ServeJSON()
+}
+
+// @Title GetOne
+// @Summary Get {{.Name}}
+// @Description get {{.Name}} by id
+// @Param id path int true "The id of the {{.Name}}"
+// @Success 200 {object} vo.{{.Name}}SingleVo
+// @Failure 400 invalid id
+// @Failure 404 {{.Name}} not found
+// @Failure 500 system error
+// @router /:id [get]
+func (c *{{.Name}}Controller) GetOne() {
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	m, err := {{.Models.Name}}.New{{.Name}}Dao().Get{{.Name}}ById(id)
+	if err != nil {
+		c.failOnRead(err)
+		return
+	}
+	c.Data["json"] = vo.{{.Name}}SingleVo{Data: vo.{{.Name}}Vo(m.{{.Name}}Po)}
+	c.ServeJSON()
+}
+
+// @Title GetAll
+// @Summary List {{.Name}}
+// @Description get a page of {{.Name}}
+// @Param query query string false "Filter. e.g. col1:v1,col2.contains:v2 ..."
+// @Param sortby query string false "Sorted-by fields. e.g. col1,col2 ..."
+// @Param order query string false "Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Number of records per page, 10 by default"
+// @Success 200 {object} vo.{{.Name}}PageListVo
+// @Failure 400 invalid query
+// @Failure 500 system error
+// @router / [get]
+func (c *{{.Name}}Controller) GetAll() {
+	var sortby, order []string
+	query := make(map[string]string)
+	page, _ := c.GetInt64("page", 1)
+	pageSize, _ := c.GetInt64("page_size", 10)
+	if page < 1 || pageSize < 1 {
+		c.CustomAbort(http.StatusBadRequest, "Error: page and page_size must be positive")
+		return
+	}
+	if v := c.GetString("sortby"); v != "" {
+		sortby = strings.Split(v, ",")
+	}
+	if v := c.GetString("order"); v != "" {
+		order = strings.Split(v, ",")
+	}
+	// query: k:v,k:v
+	if v := c.GetString("query"); v != "" {
+		for _, cond := range strings.Split(v, ",") {
+			kv := strings.SplitN(cond, ":", 2)
+			if len(kv) != 2 {
+				c.CustomAbort(http.StatusBadRequest, "Error: invalid query key/value pair")
+				return
+			}
+			query[kv[0]] = kv[1]
+		}
+	}
+
+	ml, total, err := {{.Models.Name}}.New{{.Name}}Dao().GetAll{{.Name}}(query, sortby, order, (page-1)*pageSize, pageSize)
+	if err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	list := make([]vo.{{.Name}}Vo, 0, len(ml))
+	for _, m := range ml {
+		list = append(list, vo.{{.Name}}Vo(m.{{.Name}}Po))
+	}
+	c.Data["json"] = vo.{{.Name}}PageListVo{
+		Data: vo.{{.Name}}PageList{
+			Pagination: vo.Pagination{Page: page, PageSize: pageSize, Total: total},
+			List:       list,
+		},
+	}
+	c.ServeJSON()
+}
+
+// @Title Put
+// @Summary Update {{.Name}}
+// @Description update the {{.Name}}
+// @Param id path int true "The id of the {{.Name}}"
+// @Param body body vo.{{.Name}}Vo true "body for {{.Name}} content"
+// @Success 200 {object} vo.{{.Name}}SingleVo
+// @Failure 400 invalid id or body
+// @Failure 404 {{.Name}} not found
+// @Failure 500 system error
+// @router /:id [put]
+func (c *{{.Name}}Controller) Put() {
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	var v vo.{{.Name}}Vo
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	m := &{{.Models.Name}}.{{.Name}}{ {{.Name}}Po: po.{{.Name}}Po(v)}
+	m.Id = id
+	if err := {{.Models.Name}}.New{{.Name}}Dao().Update{{.Name}}ById(m); err != nil {
+		c.failOnRead(err)
+		return
+	}
+	c.Data["json"] = vo.{{.Name}}SingleVo{Data: vo.{{.Name}}Vo(m.{{.Name}}Po)}
+	c.ServeJSON()
+}
+
+// @Title Delete
+// @Summary Delete {{.Name}}
+// @Description delete the {{.Name}}
+// @Param id path int true "The id of the {{.Name}}"
+// @Success 200 {object} vo.ApiResponse
+// @Failure 400 invalid id
+// @Failure 404 {{.Name}} not found
+// @Failure 500 system error
+// @router /:id [delete]
+func (c *{{.Name}}Controller) Delete() {
+	id, err := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.CustomAbort(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := {{.Models.Name}}.New{{.Name}}Dao().Delete{{.Name}}(id); err != nil {
+		c.failOnRead(err)
+		return
+	}
+	c.SuccessWithoutData()
+}
+
+// failOnRead responds 404 when the {{.Name}} does not exist, 500 otherwise
+func (c *{{.Name}}Controller) failOnRead(err error) {
+	if err == orm.ErrNoRows {
+		c.CustomAbort(http.StatusNotFound, err.Error())
+		return
+	}
+	c.CustomAbort(http.StatusInternalServerError, err.Error())
+}
 `
 	VoTPL = `package vo
-{{importTimePkg}}
 
-type {{voName}}SingleVo struct {
+// bee:generated begin vo
+
+{{with .Table.Imports}}{{if eq (len .) 1}}import {{printf "%q" (index . 0)}}
+{{else}}import (
+{{range .}}	{{printf "%q" .}}
+{{end}})
+{{end}}{{end}}type {{.Name}}SingleVo struct {
 	ApiResponse
-	Data {{voName}}Vo ` + "`json:\"data\"`" + `
+	Data {{.Name}}Vo ` + "`json:\"data\"`" + `
 }
 
-type {{voName}}ListVo struct {
+type {{.Name}}ListVo struct {
 	ApiResponse
-	Data []{{voName}}Vo ` + "`json:\"data\"`" + `
+	Data []{{.Name}}Vo ` + "`json:\"data\"`" + `
 }
 
-type {{voName}}PageListVo struct {
+type {{.Name}}PageListVo struct {
 	ApiResponse
-	Data {{voName}}PageList ` + "`json:\"data\"`" + `
+	Data {{.Name}}PageList ` + "`json:\"data\"`" + `
 }
 
-type {{voName}}PageList struct {
+type {{.Name}}PageList struct {
 	Pagination
-	List []{{voName}}Vo ` + "`json:\"list\"`" + `
+	List []{{.Name}}Vo ` + "`json:\"list\"`" + `
 }
 
-{{voStruct}}
+{{.Table.StringWithSuffix "Vo" false}}
+// bee:generated end vo
 `
 	RouterTPL = `// @APIVersion 1.0.0
 // @Title beego Test API
@@ -1163,23 +1752,31 @@ type {{voName}}PageList struct {
 package routers
 
 import (
-	"{{pkgPath}}/controllers"
+	"{{.PkgPath}}/controllers"
 
 	"github.com/astaxie/beego"
 )
 
 func init() {
 	ns := beego.NewNamespace("/v1",
-		{{nameSpaces}}
-	)
-	beego.AddNamespace(ns)
-}
-`
-	NamespaceTPL = `
-		beego.NSNamespace("/{{nameSpace}}",
+		{{range .Tables}}
+		beego.NSNamespace("/{{.Route}}",
 			beego.NSInclude(
-				&controllers.{{ctrlName}}Controller{},
+				&controllers.{{.GoName}}Controller{},
 			),
 		),
+{{end}}
+	)
+	beego.AddNamespace(ns)
+}
 `
 )
+
+func init() {
+	templates.Register("appcode/struct_model.go.tpl", StructModelTPL)
+	templates.Register("appcode/model.go.tpl", ModelTPL)
+	templates.Register("appcode/po.go.tpl", PoTPL)
+	templates.Register("appcode/controller.go.tpl", CtrlTPL)
+	templates.Register("appcode/vo.go.tpl", VoTPL)
+	templates.Register("appcode/router.go.tpl", RouterTPL)
+}
@@ -0,0 +1,944 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"database/sql"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// ormImportPaths are the import paths of the beego orm package
+var ormImportPaths = []string{"github.com/astaxie/beego/orm", "github.com/beego/beego/v2/client/orm"}
+
+// sqlMigrationFile matches the files of the SQL migrations run by bee migrate, i.e. 001_create_user.up.sql
+var sqlMigrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// schemaChange is a change of the schema with the statements applying and reverting it
+type schemaChange struct {
+	Up   []string
+	Down []string
+}
+
+// GenerateAutoMigration generates the migration mname making the database of driver and connStr
+// match the orm models of the package pkg, see newModelPackage. Tables missing from the database
+// are created, columns are added, altered or dropped and the indexes of the models are created.
+// The migration is a pair of SQL files if the migrations directory holds SQL migrations.
+func GenerateAutoMigration(mname, driver, connStr, tables, alias, pkg, currpath string) {
+	driver = utils.SQLDriverName(driver)
+	checkDriver(driver)
+	models := newModelPackage(alias, pkg)
+	beeLogger.Log.Infof("Loading the models of '%s'...", models.Dir)
+	modelTables := loadModels(currpath, models.Dir)
+	if selected := selectTables(tables); selected != nil {
+		var kept []*Table
+		for _, tb := range modelTables {
+			if selected[tb.Name] {
+				kept = append(kept, tb)
+			}
+		}
+		modelTables = kept
+	}
+	if len(modelTables) == 0 {
+		beeLogger.Log.Fatalf("No model registered with orm.RegisterModel in '%s'", models.Dir)
+	}
+
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not connect to '%s' database using '%s': %s", driver, connStr, err)
+	}
+	defer db.Close()
+	trans := dbDriver[driver]
+	beeLogger.Log.Info("Analyzing database tables...")
+	existing := make(map[string]bool)
+	for _, name := range trans.GetTableNames(db) {
+		existing[name] = true
+	}
+	var names []string
+	for _, tb := range modelTables {
+		if existing[tb.Name] {
+			names = append(names, tb.Name)
+		}
+	}
+	current := make(map[string]*Table)
+	defs := make(map[*Column]string)
+	indexes := make(map[string]map[string]bool)
+	currentTables := getTableObjects(names, db, trans)
+	for _, tb := range currentTables {
+		current[tb.Name] = tb
+		for _, col := range tb.Columns {
+			defs[col] = currentColumnType(driver, col)
+		}
+		indexes[tb.Name] = readIndexes(db, driver, tb)
+	}
+	// the columns get the Go types the appcode models have, to compare them with the models
+	mapColumnTypes(currentTables)
+
+	dropColumn := canDropColumn(db, driver)
+	var changes []schemaChange
+	for _, tb := range modelTables {
+		if cur, ok := current[tb.Name]; ok {
+			changes = append(changes, alterTable(driver, tb, cur, defs, indexes[tb.Name], dropColumn)...)
+		} else {
+			changes = append(changes, createTable(driver, tb))
+		}
+	}
+	var up, down []string
+	for i := range changes {
+		up = append(up, changes[i].Up...)
+		down = append(down, changes[len(changes)-1-i].Down...)
+	}
+	if len(up) == 0 {
+		beeLogger.Log.Info("The database matches the models, there is nothing to migrate")
+		return
+	}
+	beeLogger.Log.Infof("%d statements make the database match the models", len(up))
+
+	migrationPath := path.Join(currpath, DBPath, MPath)
+	if hasSQLMigrations(migrationPath) {
+		writeSQLMigration(mname, up, down, migrationPath)
+		return
+	}
+	writeGoMigration(mname, "", goMigrationStatements(up), goMigrationStatements(down), currpath)
+}
+
+// canDropColumn reports whether the database supports ALTER TABLE DROP COLUMN, which
+// SQLite does since 3.35.0
+func canDropColumn(db *sql.DB, driver string) bool {
+	if driver != "sqlite3" {
+		return true
+	}
+	var version string
+	if err := db.QueryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+		beeLogger.Log.Fatalf("Could not read the SQLite version: %s", err)
+	}
+	var major, minor int
+	fmt.Sscanf(version, "%d.%d", &major, &minor)
+	return major > 3 || (major == 3 && minor >= 35)
+}
+
+// goMigrationStatements returns the m.SQL calls of the statements of a Go migration
+func goMigrationStatements(stmts []string) string {
+	calls := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		if strings.Contains(stmt, "`") {
+			calls[i] = fmt.Sprintf("m.SQL(%s)", strconv.Quote(stmt))
+		} else {
+			calls[i] = fmt.Sprintf("m.SQL(`%s`)", stmt)
+		}
+	}
+	return strings.Join(calls, "\n")
+}
+
+// hasSQLMigrations reports whether dir holds SQL migrations
+func hasSQLMigrations(dir string) bool {
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		if sqlMigrationFile.MatchString(f.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSQLMigration writes the up and down files of the SQL migration mname, numbered after
+// the last migration of dir with as many digits
+func writeSQLMigration(mname string, up, down []string, dir string) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(mname) {
+		beeLogger.Log.Fatalf("Invalid migration name '%s'. SQL migrations are named with letters, digits and underscores", mname)
+	}
+	last, width := 0, 3
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		match := sqlMigrationFile.FindStringSubmatch(f.Name())
+		if match == nil {
+			continue
+		}
+		if n, _ := strconv.Atoi(match[1]); n >= last {
+			last = n
+			if len(match[1]) > width {
+				width = len(match[1])
+			}
+		}
+	}
+	prefix := fmt.Sprintf("%0*d_%s", width, last+1, mname)
+	utils.WriteGeneratedFile(path.Join(dir, prefix+".up.sql"), strings.Join(up, ";\n")+";\n")
+	utils.WriteGeneratedFile(path.Join(dir, prefix+".down.sql"), strings.Join(down, ";\n")+";\n")
+}
+
+// loadModels returns the tables of the orm models registered by the package dir of the
+// application, with the columns of their fields. The fields of embedded structs of the
+// application, i.e. the po structs of the appcode models, are columns of the model.
+func loadModels(currpath, dir string) []*Table {
+	loader := &modelLoader{currpath: currpath, pkgPath: getPackagePath(currpath), pkgs: make(map[string]*modelSource)}
+	src := loader.load(path.Join(currpath, dir))
+	if src == nil {
+		beeLogger.Log.Fatalf("Could not find the models of '%s'", dir)
+	}
+
+	var tables []*Table
+	byType := make(map[string]*Table)
+	for _, reg := range src.registered {
+		if byType[reg.Type] != nil {
+			continue
+		}
+		st, ok := src.structs[reg.Type]
+		if !ok {
+			beeLogger.Log.Warnf("Could not find the struct of model %s", reg.Type)
+			continue
+		}
+		tb := &Table{Name: reg.Prefix + src.tableName(reg.Type), GoName: reg.Type}
+		loader.addColumns(tb, src, st, make(map[*ast.StructType]bool))
+		if tb.Pk == "" {
+			// beego takes an integer Id field for an auto increment primary key
+			for _, col := range tb.Columns {
+				if col.Name == "Id" && strings.HasPrefix(col.Type, "int") && !col.Tag.RelFk && !col.Tag.RelOne {
+					col.Tag.Auto = true
+					tb.Pk = col.Tag.Column
+				}
+			}
+		}
+		byType[reg.Type] = tb
+		tables = append(tables, tb)
+	}
+
+	// the column of a relation has the type of the primary key of the related model
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if !col.Tag.RelFk && !col.Tag.RelOne {
+				continue
+			}
+			ref := byType[strings.TrimPrefix(col.Type, "*")]
+			col.Type = "int"
+			if ref != nil {
+				for _, refCol := range ref.Columns {
+					if refCol.Tag.Column == ref.Pk {
+						col.Type = refCol.Type
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables
+}
+
+// modelLoader parses the packages of the application holding models and their embedded structs
+type modelLoader struct {
+	currpath string
+	pkgPath  string
+	pkgs     map[string]*modelSource // parsed packages by directory
+}
+
+// modelSource is a parsed package of the application
+type modelSource struct {
+	structs    map[string]*ast.StructType
+	files      map[*ast.StructType]*ast.File // file declaring each struct
+	tableNames map[string]string             // table names returned by the TableName methods
+	registered []registeredModel
+}
+
+// registeredModel is a model registered with orm.RegisterModel or orm.RegisterModelWithPrefix
+type registeredModel struct {
+	Type   string
+	Prefix string
+}
+
+// load parses the package of dir, nil if it has no Go file
+func (l *modelLoader) load(dir string) *modelSource {
+	if src, ok := l.pkgs[dir]; ok {
+		return src
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var src *modelSource
+	fset := token.NewFileSet()
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, 0)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not parse '%s': %s", p, err)
+		}
+		if src == nil {
+			src = &modelSource{structs: make(map[string]*ast.StructType), files: make(map[*ast.StructType]*ast.File), tableNames: make(map[string]string)}
+		}
+		src.parseFile(f)
+	}
+	l.pkgs[dir] = src
+	return src
+}
+
+// parseFile collects the structs, the table names and the registered models of f
+func (src *modelSource) parseFile(f *ast.File) {
+	var ormName string
+	for _, p := range ormImportPaths {
+		if name := importName(f, p); name != "" {
+			ormName = name
+		}
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						src.structs[ts.Name.Name] = st
+						src.files[st] = f
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if name := d.Name.Name; d.Recv != nil && (name == "TableName" || name == "GetTableName") {
+				if recv := receiverType(d); recv != "" {
+					if table := returnedString(d); table != "" && (name == "TableName" || src.tableNames[recv] == "") {
+						src.tableNames[recv] = table
+					}
+				}
+			}
+		}
+	}
+	if ormName == "" {
+		return
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		args, prefix := call.Args, ""
+		switch {
+		case isSelector(call.Fun, ormName, "RegisterModel"):
+		case isSelector(call.Fun, ormName, "RegisterModelWithPrefix") && len(args) > 0:
+			if lit, ok := args[0].(*ast.BasicLit); ok {
+				prefix, _ = strconv.Unquote(lit.Value)
+			}
+			args = args[1:]
+		default:
+			return true
+		}
+		for _, arg := range args {
+			if name := modelTypeName(arg); name != "" {
+				src.registered = append(src.registered, registeredModel{Type: name, Prefix: prefix})
+			}
+		}
+		return true
+	})
+}
+
+// tableName returns the table of the model typeName, named by its TableName method or
+// after the model in snake case
+func (src *modelSource) tableName(typeName string) string {
+	if name, ok := src.tableNames[typeName]; ok {
+		return name
+	}
+	return utils.SnakeString(typeName)
+}
+
+// receiverType returns the name of the type of the receiver of a method
+func receiverType(fn *ast.FuncDecl) string {
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// returnedString returns the string literal returned by a function made of a return statement
+func returnedString(fn *ast.FuncDecl) string {
+	if fn.Body == nil || len(fn.Body.List) != 1 {
+		return ""
+	}
+	ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return ""
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	s, _ := strconv.Unquote(lit.Value)
+	return s
+}
+
+// modelTypeName returns the name of the model of an argument of orm.RegisterModel,
+// i.e. Post for new(Post) or &Post{}
+func modelTypeName(arg ast.Expr) string {
+	switch a := arg.(type) {
+	case *ast.CallExpr:
+		if id, ok := a.Fun.(*ast.Ident); ok && id.Name == "new" && len(a.Args) == 1 {
+			if t, ok := a.Args[0].(*ast.Ident); ok {
+				return t.Name
+			}
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := a.X.(*ast.CompositeLit); ok && a.Op == token.AND {
+			if t, ok := lit.Type.(*ast.Ident); ok {
+				return t.Name
+			}
+		}
+	}
+	return ""
+}
+
+// addColumns adds the columns of the fields of st, a struct of src, to tb
+func (l *modelLoader) addColumns(tb *Table, src *modelSource, st *ast.StructType, seen map[*ast.StructType]bool) {
+	if seen[st] {
+		return
+	}
+	seen[st] = true
+	for _, field := range st.Fields.List {
+		var tagValue string
+		if field.Tag != nil {
+			tag, _ := strconv.Unquote(field.Tag.Value)
+			tagValue = reflect.StructTag(tag).Get("orm")
+		}
+		if tagValue == "-" {
+			continue
+		}
+		if len(field.Names) == 0 {
+			if embeddedSrc, embedded := l.embeddedStruct(src, field.Type); embedded != nil {
+				l.addColumns(tb, embeddedSrc, embedded, seen)
+			} else {
+				beeLogger.Log.Warnf("Could not find the struct %s embedded in model %s", types.ExprString(field.Type), tb.GoName)
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			tag := parseOrmTag(tagValue)
+			if tag.ReverseOne || tag.ReverseMany || tag.RelM2M {
+				continue
+			}
+			if tag.Column == "" {
+				tag.Column = utils.SnakeString(name.Name)
+				if tag.RelFk || tag.RelOne {
+					tag.Column += "_id"
+				}
+			}
+			if tag.Pk || tag.Auto {
+				tb.Pk = tag.Column
+			}
+			tb.Columns = append(tb.Columns, &Column{Name: name.Name, Type: types.ExprString(field.Type), Tag: tag})
+		}
+	}
+}
+
+// embeddedStruct returns the struct of an embedded field, declared in the package of src
+// or in another package of the application, along with its package
+func (l *modelLoader) embeddedStruct(src *modelSource, expr ast.Expr) (*modelSource, *ast.StructType) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return src, src.structs[t.Name]
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil, nil
+		}
+		// the file of the struct embedding the field tells the import path of pkg
+		for _, f := range src.files {
+			for _, spec := range f.Imports {
+				importPath, _ := strconv.Unquote(spec.Path.Value)
+				if importName(f, importPath) != pkg.Name || !strings.HasPrefix(importPath, l.pkgPath+"/") {
+					continue
+				}
+				other := l.load(path.Join(l.currpath, strings.TrimPrefix(importPath, l.pkgPath+"/")))
+				if other == nil {
+					return nil, nil
+				}
+				return other, other.structs[t.Sel.Name]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ormOption matches an option of an orm tag, i.e. size(64) or null
+var ormOption = regexp.MustCompile(`^(\w+)(?:\((.*)\))?$`)
+
+// parseOrmTag returns the options of the orm tag of a model field
+func parseOrmTag(s string) *OrmTag {
+	tag := new(OrmTag)
+	for _, opt := range strings.Split(s, ";") {
+		match := ormOption.FindStringSubmatch(strings.TrimSpace(opt))
+		if match == nil {
+			continue
+		}
+		name, value := match[1], match[2]
+		switch name {
+		case "column":
+			tag.Column = value
+		case "pk":
+			tag.Pk = true
+		case "auto":
+			tag.Auto = true
+		case "null":
+			tag.Null = true
+		case "index":
+			tag.Index = true
+		case "unique":
+			tag.Unique = true
+		case "size":
+			tag.Size = value
+		case "digits":
+			tag.Digits = value
+		case "decimals":
+			tag.Decimals = value
+		case "type":
+			tag.Type = value
+		case "default":
+			tag.Default = value
+		case "auto_now":
+			tag.AutoNow = true
+		case "auto_now_add":
+			tag.AutoNowAdd = true
+		case "rel":
+			tag.RelFk, tag.RelOne, tag.RelM2M = value == "fk", value == "one", value == "m2m"
+		case "reverse":
+			tag.ReverseOne, tag.ReverseMany = value == "one", value == "many"
+		}
+	}
+	return tag
+}
+
+// readIndexes returns the single column indexes of a table, mapping their column to whether
+// they are unique, along with its unique constraints
+func readIndexes(db *sql.DB, driver string, tb *Table) map[string]bool {
+	indexes := make(map[string]bool)
+	var query string
+	args := []interface{}{tb.Name}
+	switch driver {
+	case "mysql":
+		query = `SELECT column_name, MIN(non_unique) = 0 FROM information_schema.statistics
+			WHERE table_schema = database() AND table_name = ? AND index_name <> 'PRIMARY'
+			AND index_name IN (SELECT index_name FROM information_schema.statistics
+				WHERE table_schema = database() AND table_name = ? GROUP BY index_name HAVING COUNT(*) = 1)
+			GROUP BY column_name`
+		args = append(args, tb.Name)
+	case "postgres":
+		query = `SELECT a.attname, bool_or(ix.indisunique) FROM pg_index ix
+			INNER JOIN pg_class t ON t.oid = ix.indrelid
+			INNER JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ix.indkey[0]
+			WHERE t.relname = $1 AND pg_table_is_visible(t.oid) AND NOT ix.indisprimary AND ix.indnatts = 1
+			GROUP BY a.attname`
+	case "sqlite3":
+		for _, index := range sqlitePragma(db, "index_list", tb.Name) {
+			if index["origin"] == "pk" {
+				continue
+			}
+			if cols := sqlitePragma(db, "index_info", index["name"]); len(cols) == 1 {
+				indexes[cols[0]["name"]] = indexes[cols[0]["name"]] || index["unique"] == "1"
+			}
+		}
+	}
+	if query != "" {
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not read the indexes of '%s': %s", tb.Name, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var column string
+			var unique bool
+			if err := rows.Scan(&column, &unique); err != nil {
+				beeLogger.Log.Fatalf("Could not read the indexes of '%s': %s", tb.Name, err)
+			}
+			indexes[column] = unique
+		}
+	}
+	for _, column := range tb.Uk {
+		indexes[column] = true
+	}
+	return indexes
+}
+
+// createTable returns the creation of the table of a model, with its indexes
+func createTable(driver string, tb *Table) schemaChange {
+	var defs []string
+	for _, col := range tb.Columns {
+		def := modelColumnDefinition(driver, tb, col)
+		if def == "" {
+			beeLogger.Log.Fatalf("Column '%s.%s' of Go type %s has no SQL type. Set its type with the type() option of its orm tag.",
+				tb.Name, col.Tag.Column, col.Type)
+		}
+		defs = append(defs, "    "+quoteIdent(driver, col.Tag.Column)+" "+def)
+	}
+	change := schemaChange{
+		Up:   []string{fmt.Sprintf("CREATE TABLE %s (\n%s\n)", quoteIdent(driver, tb.Name), strings.Join(defs, ",\n"))},
+		Down: []string{"DROP TABLE " + quoteIdent(driver, tb.Name)},
+	}
+	for _, col := range tb.Columns {
+		if col.Tag.Index || col.Tag.Unique {
+			change.Up = append(change.Up, createIndex(driver, tb.Name, col.Tag.Column, col.Tag.Unique))
+		}
+	}
+	return change
+}
+
+// alterTable returns the changes making the table cur match the model tb. Columns are added,
+// altered and dropped, and the indexes of the model are created. The indexes missing from
+// the model are kept, as the orm tags of the appcode models don't declare them.
+func alterTable(driver string, tb, cur *Table, defs map[*Column]string, indexes map[string]bool, dropColumn bool) (changes []schemaChange) {
+	table := quoteIdent(driver, tb.Name)
+	current := make(map[string]*Column, len(cur.Columns))
+	for _, col := range cur.Columns {
+		current[col.Tag.Column] = col
+	}
+	modelled := make(map[string]bool, len(tb.Columns))
+	for _, col := range tb.Columns {
+		modelled[col.Tag.Column] = true
+		column := quoteIdent(driver, col.Tag.Column)
+		c, ok := current[col.Tag.Column]
+		if col.Tag.Column == tb.Pk || (ok && c.Tag.Column == cur.Pk) {
+			if !ok || c.Tag.Column != cur.Pk {
+				beeLogger.Log.Warnf("The primary key of '%s' changed, which is left to a hand-written migration", tb.Name)
+			}
+			continue
+		}
+		def := modelColumnDefinition(driver, tb, col)
+		if !ok {
+			if def == "" {
+				beeLogger.Log.Warnf("Column '%s.%s' of Go type %s has no SQL type, it is not added", tb.Name, col.Tag.Column, col.Type)
+				continue
+			}
+			if !col.Tag.Null && col.Tag.Default == "" && driver != "mysql" {
+				beeLogger.Log.Warnf("Column '%s.%s' is NOT NULL without default, adding it fails if the table has rows", tb.Name, col.Tag.Column)
+			}
+			if !dropColumn {
+				beeLogger.Log.Warnf("SQLite before 3.35 cannot drop column '%s.%s', the down migration fails", tb.Name, col.Tag.Column)
+			}
+			changes = append(changes, schemaChange{
+				Up:   []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def)},
+				Down: []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)},
+			})
+			continue
+		}
+		typeChanged, nullChanged := columnChanged(col, c)
+		if !typeChanged && !nullChanged {
+			continue
+		}
+		if def == "" {
+			beeLogger.Log.Warnf("Column '%s.%s' of Go type %s has no SQL type, it is not altered", tb.Name, col.Tag.Column, col.Type)
+			continue
+		}
+		// MySQL restates the type of the column to change its nullability
+		if defs[c] == "" && (typeChanged || driver == "mysql") && driver != "sqlite3" {
+			beeLogger.Log.Warnf("Column '%s.%s' changed but its type can't be restored, it is not altered", tb.Name, col.Tag.Column)
+			continue
+		}
+		switch driver {
+		case "mysql":
+			changes = append(changes, schemaChange{
+				Up:   []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, def)},
+				Down: []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, currentColumnDefinition(defs[c], c))},
+			})
+		case "postgres":
+			var change schemaChange
+			if typeChanged {
+				change.Up = append(change.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, column, modelColumnType(driver, col)))
+				change.Down = append(change.Down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, column, defs[c]))
+			}
+			if nullChanged {
+				set, drop := "SET NOT NULL", "DROP NOT NULL"
+				if col.Tag.Null {
+					set, drop = drop, set
+				}
+				change.Up = append(change.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", table, column, set))
+				change.Down = append(change.Down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", table, column, drop))
+			}
+			changes = append(changes, change)
+		default:
+			beeLogger.Log.Warnf("SQLite cannot alter column '%s.%s', which is left to a hand-written migration rebuilding the table",
+				tb.Name, col.Tag.Column)
+		}
+	}
+
+	for _, c := range cur.Columns {
+		if modelled[c.Tag.Column] || c.Tag.Column == cur.Pk {
+			continue
+		}
+		if !dropColumn {
+			beeLogger.Log.Warnf("Column '%s.%s' has no model field but SQLite before 3.35 cannot drop it, it is left to a hand-written migration rebuilding the table",
+				tb.Name, c.Tag.Column)
+			continue
+		}
+		if defs[c] == "" {
+			beeLogger.Log.Warnf("Column '%s.%s' has no model field but its type can't be restored, it is not dropped", tb.Name, c.Tag.Column)
+			continue
+		}
+		beeLogger.Log.Warnf("Column '%s.%s' has no model field, it is dropped along with its data", tb.Name, c.Tag.Column)
+		column := quoteIdent(driver, c.Tag.Column)
+		change := schemaChange{
+			Up:   []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)},
+			Down: []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, currentColumnDefinition(defs[c], c))},
+		}
+		if unique, ok := indexes[c.Tag.Column]; ok {
+			change.Down = append(change.Down, createIndex(driver, tb.Name, c.Tag.Column, unique))
+		}
+		changes = append(changes, change)
+	}
+
+	for _, col := range tb.Columns {
+		if !col.Tag.Index && !col.Tag.Unique {
+			continue
+		}
+		if unique, ok := indexes[col.Tag.Column]; ok && (unique || !col.Tag.Unique) {
+			continue
+		}
+		changes = append(changes, schemaChange{
+			Up:   []string{createIndex(driver, tb.Name, col.Tag.Column, col.Tag.Unique)},
+			Down: []string{dropIndex(driver, tb.Name, col.Tag.Column, col.Tag.Unique)},
+		})
+	}
+	return
+}
+
+// columnChanged reports whether the type or the nullability of the column cur differs
+// from the field col of the model. The type of a relation is the one of its related model,
+// only its nullability is compared.
+func columnChanged(col, cur *Column) (typeChanged, nullChanged bool) {
+	nullChanged = col.Tag.Null != cur.Tag.Null
+	if col.Tag.RelFk || col.Tag.RelOne {
+		return
+	}
+	switch {
+	// MySQL creates bool columns as tinyint(1), which reads back as int8
+	case baseGoType(col.Type) == "bool" && cur.SQLType == "tinyint" && col.Tag.Type == "":
+	case col.Type != cur.Type && baseGoType(col.Type) != baseGoType(cur.Type):
+		typeChanged = true
+	case col.Tag.Type != "" && !strings.EqualFold(col.Tag.Type, cur.SQLType):
+		typeChanged = true
+	case col.Tag.Size != "" && cur.Tag.Size != "" && col.Tag.Size != cur.Tag.Size:
+		typeChanged = true
+	case col.Tag.Digits != "" && cur.Tag.Digits != "" && (col.Tag.Digits != cur.Tag.Digits || col.Tag.Decimals != cur.Tag.Decimals):
+		typeChanged = true
+	}
+	return
+}
+
+// baseGoType returns the Go type of the values of a nullable type, i.e. string for *string
+// or sql.NullString
+func baseGoType(goType string) string {
+	goType = strings.TrimPrefix(goType, "*")
+	switch goType {
+	case "sql.NullString":
+		return "string"
+	case "sql.NullBool":
+		return "bool"
+	case "sql.NullInt32":
+		return "int32"
+	case "sql.NullInt64":
+		return "int64"
+	case "sql.NullFloat64":
+		return "float64"
+	case "sql.NullTime":
+		return "time.Time"
+	}
+	return goType
+}
+
+// modelColumnDefinition returns the definition of the column of a model field, i.e.
+// varchar(64) NOT NULL, empty if its Go type has no SQL type
+func modelColumnDefinition(driver string, tb *Table, col *Column) string {
+	if col.Tag.Column == tb.Pk {
+		auto := col.Tag.Auto
+		switch goType := baseGoType(col.Type); {
+		case auto && driver == "mysql" && goType == "int64":
+			return "bigint NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		case auto && driver == "mysql":
+			return "int NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		case auto && driver == "postgres" && goType == "int64":
+			return "bigserial NOT NULL PRIMARY KEY"
+		case auto && driver == "postgres":
+			return "serial NOT NULL PRIMARY KEY"
+		case auto:
+			return "integer PRIMARY KEY AUTOINCREMENT"
+		}
+		if t := modelColumnType(driver, col); t != "" {
+			return t + " NOT NULL PRIMARY KEY"
+		}
+		return ""
+	}
+	t := modelColumnType(driver, col)
+	if t == "" {
+		return ""
+	}
+	def := t + " NOT NULL"
+	if col.Tag.Null {
+		def = t + " NULL"
+	}
+	if col.Tag.Default != "" {
+		value := col.Tag.Default
+		if base := baseGoType(col.Type); base == "string" || base == "time.Time" {
+			value = "'" + strings.Replace(value, "'", "''", -1) + "'"
+		}
+		def += " DEFAULT " + value
+	}
+	return def
+}
+
+// modelColumnType returns the SQL type of the column of a model field, given by the
+// type option of its orm tag or mapped from its Go type as beego does
+func modelColumnType(driver string, col *Column) string {
+	tag := col.Tag
+	if tag.Type != "" {
+		if tag.Size != "" && !strings.Contains(tag.Type, "(") {
+			return tag.Type + "(" + tag.Size + ")"
+		}
+		return tag.Type
+	}
+	if tag.Digits != "" {
+		if driver == "postgres" {
+			return fmt.Sprintf("numeric(%s,%s)", tag.Digits, tag.Decimals)
+		}
+		return fmt.Sprintf("decimal(%s,%s)", tag.Digits, tag.Decimals)
+	}
+	goType := baseGoType(col.Type)
+	switch goType {
+	case "string":
+		size := tag.Size
+		if size == "" {
+			size = "255"
+		}
+		return "varchar(" + size + ")"
+	case "[]byte":
+		if driver == "postgres" {
+			return "bytea"
+		}
+		return "blob"
+	case "json.RawMessage":
+		if driver == "postgres" {
+			return "jsonb"
+		}
+		return "json"
+	case "time.Time":
+		if driver == "postgres" {
+			return "timestamp with time zone"
+		}
+		return "datetime"
+	case "bool":
+		if driver == "postgres" {
+			return "boolean"
+		}
+		return "bool"
+	}
+	return columnTypes[driver][goType]
+}
+
+// columnTypes maps the numeric Go types to the SQL types of each driver, see modelColumnType
+var columnTypes = map[string]map[string]string{
+	"mysql": {
+		"int8": "tinyint", "int16": "smallint", "int32": "int", "int": "int", "int64": "bigint",
+		"uint8": "tinyint unsigned", "uint16": "smallint unsigned", "uint32": "int unsigned",
+		"uint": "int unsigned", "uint64": "bigint unsigned", "float32": "float", "float64": "double",
+	},
+	"postgres": {
+		"int8": "smallint", "int16": "smallint", "int32": "integer", "int": "integer", "int64": "bigint",
+		"uint8": "smallint", "uint16": "integer", "uint32": "bigint", "uint": "bigint", "uint64": "bigint",
+		"float32": "real", "float64": "double precision",
+	},
+	"sqlite3": {
+		"int8": "tinyint", "int16": "smallint", "int32": "mediumint", "int": "integer", "int64": "bigint",
+		"uint8": "integer", "uint16": "integer", "uint32": "integer", "uint": "integer", "uint64": "unsigned big int",
+		"float32": "real", "float64": "real",
+	},
+}
+
+// currentColumnType returns the SQL type of a column read from the database, i.e. varchar(64),
+// empty if it can't be written back
+func currentColumnType(driver string, col *Column) string {
+	t := col.SQLType
+	switch {
+	case t == "" || t == "USER-DEFINED" || t == "ARRAY":
+		return ""
+	case col.Tag.Digits != "":
+		t = fmt.Sprintf("%s(%s,%s)", t, col.Tag.Digits, col.Tag.Decimals)
+	case col.Tag.Size != "":
+		t = fmt.Sprintf("%s(%s)", t, col.Tag.Size)
+	}
+	if driver == "mysql" && strings.HasPrefix(col.Type, "uint") && col.SQLType != "bit" {
+		t += " unsigned"
+	}
+	return t
+}
+
+// currentColumnDefinition returns the definition of a column read from the database,
+// with its type t. Defaults other than the current timestamp aren't read, nor restored.
+func currentColumnDefinition(t string, col *Column) string {
+	def := t + " NOT NULL"
+	if col.Tag.Null {
+		def = t + " NULL"
+	}
+	if col.Tag.AutoNowAdd || col.Tag.AutoNow {
+		def += " DEFAULT CURRENT_TIMESTAMP"
+	}
+	return def
+}
+
+// indexName returns the name of the index of a column, i.e. idx_post_title or uk_post_title
+func indexName(table, column string, unique bool) string {
+	if unique {
+		return "uk_" + table + "_" + column
+	}
+	return "idx_" + table + "_" + column
+}
+
+// createIndex returns the creation of the index of a column
+func createIndex(driver, table, column string, unique bool) string {
+	create := "CREATE INDEX"
+	if unique {
+		create = "CREATE UNIQUE INDEX"
+	}
+	return fmt.Sprintf("%s %s ON %s (%s)", create, quoteIdent(driver, indexName(table, column, unique)),
+		quoteIdent(driver, table), quoteIdent(driver, column))
+}
+
+// dropIndex returns the removal of the index of a column created by createIndex
+func dropIndex(driver, table, column string, unique bool) string {
+	if driver == "mysql" {
+		return fmt.Sprintf("DROP INDEX %s ON %s", quoteIdent(driver, indexName(table, column, unique)), quoteIdent(driver, table))
+	}
+	return "DROP INDEX " + quoteIdent(driver, indexName(table, column, unique))
+}
+
+// quoteIdent quotes the name of a table, a column or an index
+func quoteIdent(driver, name string) string {
+	if driver == "mysql" {
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -15,19 +15,23 @@
 package generate
 
 import (
-	"fmt"
 	"os"
 	"path"
 	"strings"
 
+	"github.com/iwooyun/bee/generate/templates"
 	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 )
 
-func GenerateController(cname, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
+// controllerData is the data the controller templates are executed with
+type controllerData struct {
+	Package string // package of the controller, controllers unless the controller name has a path
+	Name    string // name of the controller, e.g. Post for PostController
+	PkgPath string // import path of the application, set when the controller has a matching model
+}
 
+func GenerateController(cname, currpath string) {
 	p, f := path.Split(cname)
 	controllerName := strings.Title(f)
 	packageName := "controllers"
@@ -41,53 +45,35 @@ func GenerateController(cname, currpath string) {
 	beeLogger.Log.Infof("Using '%s' as package name", packageName)
 
 	fp := path.Join(currpath, "controllers", p)
-	if _, err := os.Stat(fp); os.IsNotExist(err) {
-		// Create the controller's directory
-		if err := os.MkdirAll(fp, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create controllers directory: %s", err)
-		}
-	}
 
-	fpath := path.Join(fp, strings.ToLower(controllerName)+".go")
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-
-		modelPath := path.Join(currpath, "models", strings.ToLower(controllerName)+".go")
-
-		var content string
-		if _, err := os.Stat(modelPath); err == nil {
-			beeLogger.Log.Infof("Using matching model '%s'", controllerName)
-			content = strings.Replace(controllerModelTpl, "{{packageName}}", packageName, -1)
-			pkgPath := getPackagePath(currpath)
-			content = strings.Replace(content, "{{pkgPath}}", pkgPath, -1)
-		} else {
-			content = strings.Replace(controllerTpl, "{{packageName}}", packageName, -1)
-		}
+	modelPath := path.Join(currpath, "models", strings.ToLower(controllerName)+".go")
 
-		content = strings.Replace(content, "{{controllerName}}", controllerName, -1)
-		f.WriteString(content)
-
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create controller file: %s", err)
+	name := "controller.go.tpl"
+	data := controllerData{Package: packageName, Name: controllerName}
+	if _, err := os.Stat(modelPath); err == nil {
+		beeLogger.Log.Infof("Using matching model '%s'", controllerName)
+		name = "controller_model.go.tpl"
+		data.PkgPath = getPackagePath(currpath)
 	}
+	content := templates.MustExecute(name, data)
+
+	fpath := path.Join(fp, strings.ToLower(controllerName)+".go")
+	utils.WriteGeneratedFile(fpath, content)
 }
 
-var controllerTpl = `package {{packageName}}
+var controllerTpl = `package {{.Package}}
 
 import (
 	"github.com/astaxie/beego"
 )
 
-// {{controllerName}}Controller operations for {{controllerName}}
-type {{controllerName}}Controller struct {
+// {{.Name}}Controller operations for {{.Name}}
+type {{.Name}}Controller struct {
 	beego.Controller
 }
 
 // URLMapping ...
-func (c *{{controllerName}}Controller) URLMapping() {
+func (c *{{.Name}}Controller) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
@@ -97,70 +83,70 @@ func (c *{{controllerName}}Controller) URLMapping() {
 
 // Post ...
 // @Title Create
-// @Description create {{controllerName}}
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 201 {object} models.{{controllerName}}
+// @Description create {{.Name}}
+// @Param	body		body 	models.{{.Name}}	true		"body for {{.Name}} content"
+// @Success 201 {object} models.{{.Name}}
 // @Failure 403 body is empty
 // @router / [post]
-func (c *{{controllerName}}Controller) Post() {
+func (c *{{.Name}}Controller) Post() {
 
 }
 
 // GetOne ...
 // @Title GetOne
-// @Description get {{controllerName}} by id
+// @Description get {{.Name}} by id
 // @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403 :id is empty
 // @router /:id [get]
-func (c *{{controllerName}}Controller) GetOne() {
+func (c *{{.Name}}Controller) GetOne() {
 
 }
 
 // GetAll ...
 // @Title GetAll
-// @Description get {{controllerName}}
+// @Description get {{.Name}}
 // @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403
 // @router / [get]
-func (c *{{controllerName}}Controller) GetAll() {
+func (c *{{.Name}}Controller) GetAll() {
 
 }
 
 // Put ...
 // @Title Put
-// @Description update the {{controllerName}}
+// @Description update the {{.Name}}
 // @Param	id		path 	string	true		"The id you want to update"
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 200 {object} models.{{controllerName}}
+// @Param	body		body 	models.{{.Name}}	true		"body for {{.Name}} content"
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403 :id is not int
 // @router /:id [put]
-func (c *{{controllerName}}Controller) Put() {
+func (c *{{.Name}}Controller) Put() {
 
 }
 
 // Delete ...
 // @Title Delete
-// @Description delete the {{controllerName}}
+// @Description delete the {{.Name}}
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
 // @router /:id [delete]
-func (c *{{controllerName}}Controller) Delete() {
+func (c *{{.Name}}Controller) Delete() {
 
 }
 `
 
-var controllerModelTpl = `package {{packageName}}
+var controllerModelTpl = `package {{.Package}}
 
 import (
-	"{{pkgPath}}/models"
+	"{{.PkgPath}}/models"
 	"encoding/json"
 	"errors"
 	"strconv"
@@ -169,13 +155,13 @@ import (
 	"github.com/astaxie/beego"
 )
 
-//  {{controllerName}}Controller operations for {{controllerName}}
-type {{controllerName}}Controller struct {
+//  {{.Name}}Controller operations for {{.Name}}
+type {{.Name}}Controller struct {
 	beego.Controller
 }
 
 // URLMapping ...
-func (c *{{controllerName}}Controller) URLMapping() {
+func (c *{{.Name}}Controller) URLMapping() {
 	c.Mapping("Post", c.Post)
 	c.Mapping("GetOne", c.GetOne)
 	c.Mapping("GetAll", c.GetAll)
@@ -185,15 +171,15 @@ func (c *{{controllerName}}Controller) URLMapping() {
 
 // Post ...
 // @Title Post
-// @Description create {{controllerName}}
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 201 {int} models.{{controllerName}}
+// @Description create {{.Name}}
+// @Param	body		body 	models.{{.Name}}	true		"body for {{.Name}} content"
+// @Success 201 {int} models.{{.Name}}
 // @Failure 403 body is empty
 // @router / [post]
-func (c *{{controllerName}}Controller) Post() {
-	var v models.{{controllerName}}
+func (c *{{.Name}}Controller) Post() {
+	var v models.{{.Name}}
 	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-	if _, err := models.Add{{controllerName}}(&v); err == nil {
+	if _, err := models.Add{{.Name}}(&v); err == nil {
 		c.Ctx.Output.SetStatus(201)
 		c.Data["json"] = v
 	} else {
@@ -204,15 +190,15 @@ func (c *{{controllerName}}Controller) Post() {
 
 // GetOne ...
 // @Title Get One
-// @Description get {{controllerName}} by id
+// @Description get {{.Name}} by id
 // @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403 :id is empty
 // @router /:id [get]
-func (c *{{controllerName}}Controller) GetOne() {
+func (c *{{.Name}}Controller) GetOne() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	v, err := models.Get{{controllerName}}ById(id)
+	v, err := models.Get{{.Name}}ById(id)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -223,17 +209,17 @@ func (c *{{controllerName}}Controller) GetOne() {
 
 // GetAll ...
 // @Title Get All
-// @Description get {{controllerName}}
+// @Description get {{.Name}}
 // @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
 // @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
 // @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
 // @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
 // @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
 // @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{controllerName}}
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403
 // @router / [get]
-func (c *{{controllerName}}Controller) GetAll() {
+func (c *{{.Name}}Controller) GetAll() {
 	var fields []string
 	var sortby []string
 	var order []string
@@ -275,7 +261,7 @@ func (c *{{controllerName}}Controller) GetAll() {
 		}
 	}
 
-	l, err := models.GetAll{{controllerName}}(query, fields, sortby, order, offset, limit)
+	l, err := models.GetAll{{.Name}}(query, fields, sortby, order, offset, limit)
 	if err != nil {
 		c.Data["json"] = err.Error()
 	} else {
@@ -286,18 +272,18 @@ func (c *{{controllerName}}Controller) GetAll() {
 
 // Put ...
 // @Title Put
-// @Description update the {{controllerName}}
+// @Description update the {{.Name}}
 // @Param	id		path 	string	true		"The id you want to update"
-// @Param	body		body 	models.{{controllerName}}	true		"body for {{controllerName}} content"
-// @Success 200 {object} models.{{controllerName}}
+// @Param	body		body 	models.{{.Name}}	true		"body for {{.Name}} content"
+// @Success 200 {object} models.{{.Name}}
 // @Failure 403 :id is not int
 // @router /:id [put]
-func (c *{{controllerName}}Controller) Put() {
+func (c *{{.Name}}Controller) Put() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	v := models.{{controllerName}}{Id: id}
+	v := models.{{.Name}}{Id: id}
 	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-	if err := models.Update{{controllerName}}ById(&v); err == nil {
+	if err := models.Update{{.Name}}ById(&v); err == nil {
 		c.Data["json"] = "OK"
 	} else {
 		c.Data["json"] = err.Error()
@@ -307,15 +293,15 @@ func (c *{{controllerName}}Controller) Put() {
 
 // Delete ...
 // @Title Delete
-// @Description delete the {{controllerName}}
+// @Description delete the {{.Name}}
 // @Param	id		path 	string	true		"The id you want to delete"
 // @Success 200 {string} delete success!
 // @Failure 403 id is empty
 // @router /:id [delete]
-func (c *{{controllerName}}Controller) Delete() {
+func (c *{{.Name}}Controller) Delete() {
 	idStr := c.Ctx.Input.Param(":id")
 	id, _ := strconv.ParseInt(idStr, 0, 64)
-	if err := models.Delete{{controllerName}}(id); err == nil {
+	if err := models.Delete{{.Name}}(id); err == nil {
 		c.Data["json"] = "OK"
 	} else {
 		c.Data["json"] = err.Error()
@@ -323,3 +309,8 @@ func (c *{{controllerName}}Controller) Delete() {
 	c.ServeJSON()
 }
 `
+
+func init() {
+	templates.Register("controller.go.tpl", controllerTpl)
+	templates.Register("controller_model.go.tpl", controllerModelTpl)
+}
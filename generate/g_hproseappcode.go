@@ -19,13 +19,11 @@ package generate
 
 import (
 	"database/sql"
-	"fmt"
-	"os"
 	"path"
 	"strings"
 
+	"github.com/iwooyun/bee/generate/templates"
 	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -129,6 +127,55 @@ func main() {
 
 `
 
+// HproseServicesMainconngo is the main.go of an application whose services are generated
+// from the levels 2 and 3, publishing the methods of their service objects
+var HproseServicesMainconngo = `package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"{{.Appname}}/services"
+	"github.com/hprose/hprose-golang/rpc"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/orm"
+	{{.DriverPkg}}
+)
+
+func init() {
+	orm.RegisterDataBase("default", "{{.DriverName}}", "{{.conn}}")
+}
+
+func logInvokeHandler(
+	name string,
+	args []reflect.Value,
+	context rpc.Context,
+	next rpc.NextInvokeHandler) (results []reflect.Value, err error) {
+	fmt.Printf("%s(%v) = ", name, args)
+	results, err = next(name, args, context)
+	fmt.Printf("%v %v\r\n", results, err)
+	return
+}
+
+func main() {
+	// Create WebSocketServer
+	// service := rpc.NewWebSocketService()
+
+	// Create Http Server
+	service := rpc.NewHTTPService()
+
+	// Use Logger Middleware
+	service.AddInvokeHandler(logInvokeHandler)
+
+	{{HproseFunctionList}}
+
+	// Start Service
+	beego.Handler("/", service)
+	beego.Run()
+}
+`
+
 var HproseModels = `package models
 
 import (
@@ -273,6 +320,10 @@ func DeleteUser(uid string) {
 `
 var HproseAddFunctions = []string{}
 
+// GenerateHproseAppcode generates the code of an Hprose application from the tables of a
+// database. Level 1 generates models publishing their functions, level 2 the models and
+// DAOs of the appcode along with service objects using them, and level 3 also a package
+// of client stubs of the services for Go callers.
 func GenerateHproseAppcode(driver, connStr, level, tables, currpath string) {
 	var mode byte
 	switch level {
@@ -295,12 +346,15 @@ func GenerateHproseAppcode(driver, connStr, level, tables, currpath string) {
 	switch driver {
 	case "mysql":
 	case "postgres":
-	case "sqlite":
-		beeLogger.Log.Fatal("Generating app code from SQLite database is not supported yet")
+	case "sqlite", "sqlite3":
 	default:
 		beeLogger.Log.Fatalf("Unknown database driver '%s'. Driver must be one of mysql, postgres or sqlite", driver)
 	}
-	genHprose(driver, connStr, mode, selectedTables, currpath)
+	if mode == OModel {
+		genHprose(utils.SQLDriverName(driver), connStr, mode, selectedTables, currpath)
+		return
+	}
+	genHproseServices(utils.SQLDriverName(driver), connStr, mode, selectedTables, currpath)
 }
 
 // Generate takes table, column and foreign key information from database connection
@@ -315,6 +369,7 @@ func genHprose(dbms, connStr string, mode byte, selectedTableNames map[string]bo
 		beeLogger.Log.Info("Analyzing database tables...")
 		tableNames := trans.GetTableNames(db)
 		tables := getTableObjects(tableNames, db, trans)
+		pointerFks(tables)
 		mvcPath := new(MvcPath)
 		mvcPath.ModelPath = path.Join(currpath, "models")
 		createPaths(mode, mvcPath)
@@ -325,6 +380,59 @@ func genHprose(dbms, connStr string, mode byte, selectedTableNames map[string]bo
 	}
 }
 
+// genHproseServices generates the models of the appcode, the service objects of the tables
+// having a primary key and, at level 3, their client stubs
+func genHproseServices(dbms, connStr string, mode byte, selectedTableNames map[string]bool, currpath string) {
+	tables := readTables(dbms, connStr, selectedTableNames)
+	models := newModelPackage("", "")
+	writeAppcode(tables, OModel, models, currpath)
+	pkgPath := getPackagePath(currpath)
+
+	beeLogger.Log.Info("Creating service files...")
+	servicesPath := path.Join(currpath, "services")
+	utils.MkdirGenerated(servicesPath)
+	for _, tb := range tables {
+		if tb.Pk == "" {
+			continue
+		}
+		data := appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb}
+		fpath := path.Join(servicesPath, getFileName(tb.FileName)+"_service.go")
+		writeAppcodeFile(fpath, "hprose/service.go.tpl", data)
+		HproseAddFunctions = append(HproseAddFunctions, strings.Replace(HproseAddService, "{{modelName}}", tb.GoName, -1))
+	}
+	if mode&ORouter != ORouter {
+		return
+	}
+
+	beeLogger.Log.Info("Creating client files...")
+	clientPath := path.Join(currpath, "client")
+	utils.MkdirGenerated(clientPath)
+	for _, tb := range tables {
+		if tb.Pk == "" {
+			continue
+		}
+		data := appcodeData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Table: tb}
+		writeAppcodeFile(path.Join(clientPath, getFileName(tb.FileName)+"_client.go"), "hprose/client.go.tpl", data)
+	}
+}
+
+// pointerFks turns the foreign key columns referencing a table with a primary key into
+// pointers to its model, i.e. AuthorId *Author `orm:"column(author_id);rel(fk)"`
+func pointerFks(tables []*Table) {
+	pks := make(map[string]bool)
+	for _, tb := range tables {
+		pks[tb.Name] = tb.Pk != ""
+	}
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if fk, ok := tb.Fk[col.Tag.Column]; ok && col.Tag.Column != tb.Pk && pks[fk.RefTable] {
+				col.Type = "*" + utils.CamelCase(fk.RefTable)
+				col.Tag = &OrmTag{Column: col.Tag.Column, Comment: col.Tag.Comment, RelFk: true}
+			}
+		}
+	}
+}
+
 // writeHproseSourceFiles generates source files for model/controller/router
 // It will wipe the following directories and recreate them:./models, ./controllers, ./routers
 // Newly geneated files will be inside these folders.
@@ -337,8 +445,6 @@ func writeHproseSourceFiles(pkgPath string, tables []*Table, mode byte, paths *M
 
 // writeHproseModelFiles generates model files
 func writeHproseModelFiles(tables []*Table, mPath string, selectedTables map[string]bool) {
-	w := colors.NewColorWriter(os.Stdout)
-
 	for _, tb := range tables {
 		// if selectedTables map is not nil and this table is not selected, ignore it
 		if selectedTables != nil {
@@ -348,27 +454,6 @@ func writeHproseModelFiles(tables []*Table, mPath string, selectedTables map[str
 		}
 		filename := getFileName(tb.Name)
 		fpath := path.Join(mPath, filename+".go")
-		var f *os.File
-		var err error
-		if utils.IsExist(fpath) {
-			beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-			if utils.AskForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					beeLogger.Log.Warnf("%s", err)
-					continue
-				}
-			} else {
-				beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-				continue
-			}
-		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				continue
-			}
-		}
 		var template string
 		if tb.Pk == "" {
 			template = HproseStructModelTPL
@@ -387,12 +472,7 @@ func writeHproseModelFiles(tables []*Table, mPath string, selectedTables map[str
 		}
 		fileStr = strings.Replace(fileStr, "{{timePkg}}", timePkg, -1)
 		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			beeLogger.Log.Fatalf("Could not write model file to '%s'", fpath)
-		}
-		utils.CloseFile(f)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-		utils.FormatSourceCode(fpath)
+		utils.WriteGeneratedFile(fpath, fileStr)
 	}
 }
 
@@ -405,6 +485,112 @@ const (
 	service.AddFunction("Update{{modelName}}ById", models.Update{{modelName}}ById)
 	service.AddFunction("Delete{{modelName}}", models.Delete{{modelName}})
 
+`
+	HproseAddService = `
+	// publish the {{modelName}} service, i.e. {{modelName}}_Get
+	service.AddInstanceMethods(&services.{{modelName}}Service{}, rpc.Options{NameSpace: "{{modelName}}"})
+`
+	HproseServiceTPL = `package services
+
+import (
+	"errors"
+
+	"{{.PkgPath}}/{{.Models.Dir}}"
+	"{{.PkgPath}}/{{.Models.Dir}}/po"
+)
+
+// {{.Name}}Service publishes the DAO of {{.Name}}, its methods are named {{.Name}}_<method>
+type {{.Name}}Service struct{}
+
+// {{.Name}}Page is a page of {{.Name}}, along with the number of matching records
+type {{.Name}}Page struct {
+	Page     int64
+	PageSize int64
+	Total    int64
+	List     []po.{{.Name}}Po
+}
+
+// Create inserts v into the database and returns it along with its Id
+func (s *{{.Name}}Service) Create(v *po.{{.Name}}Po) (*po.{{.Name}}Po, error) {
+	m := &{{.Models.Name}}.{{.Name}}{ {{- .Name}}Po: *v}
+	if _, err := {{.Models.Name}}.New{{.Name}}Dao().Add{{.Name}}(m); err != nil {
+		return nil, err
+	}
+	return &m.{{.Name}}Po, nil
+}
+
+// Get retrieves {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (s *{{.Name}}Service) Get(id int) (*po.{{.Name}}Po, error) {
+	m, err := {{.Models.Name}}.New{{.Name}}Dao().Get{{.Name}}ById(id)
+	if err != nil {
+		return nil, err
+	}
+	return &m.{{.Name}}Po, nil
+}
+
+// List retrieves the page of {{.Name}} matching the query, whose keys use the dot notation,
+// i.e. name.contains. Pages start at 1.
+func (s *{{.Name}}Service) List(query map[string]string, sortby []string, order []string,
+	page int64, pageSize int64) (*{{.Name}}Page, error) {
+	if page < 1 || pageSize < 1 {
+		return nil, errors.New("Error: page and pageSize must be positive")
+	}
+	ml, total, err := {{.Models.Name}}.New{{.Name}}Dao().GetAll{{.Name}}(query, sortby, order, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	p := &{{.Name}}Page{Page: page, PageSize: pageSize, Total: total, List: make([]po.{{.Name}}Po, 0, len(ml))}
+	for _, m := range ml {
+		p.List = append(p.List, m.{{.Name}}Po)
+	}
+	return p, nil
+}
+
+// Update updates {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (s *{{.Name}}Service) Update(v *po.{{.Name}}Po) (*po.{{.Name}}Po, error) {
+	m := &{{.Models.Name}}.{{.Name}}{ {{- .Name}}Po: *v}
+	if err := {{.Models.Name}}.New{{.Name}}Dao().Update{{.Name}}ById(m); err != nil {
+		return nil, err
+	}
+	return &m.{{.Name}}Po, nil
+}
+
+// Delete deletes {{.Name}} by Id. Returns orm.ErrNoRows if it doesn't exist
+func (s *{{.Name}}Service) Delete(id int) error {
+	return {{.Models.Name}}.New{{.Name}}Dao().Delete{{.Name}}(id)
+}
+`
+	HproseClientTPL = `package client
+
+import (
+	"{{.PkgPath}}/{{.Models.Dir}}/po"
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+// {{.Name}}Service is the client stub of the {{.Name}} service
+type {{.Name}}Service struct {
+	Create func(v *po.{{.Name}}Po) (*po.{{.Name}}Po, error)
+	Get    func(id int) (*po.{{.Name}}Po, error)
+	List   func(query map[string]string, sortby []string, order []string, page int64, pageSize int64) (*{{.Name}}Page, error)
+	Update func(v *po.{{.Name}}Po) (*po.{{.Name}}Po, error)
+	Delete func(id int) error
+}
+
+// {{.Name}}Page is a page of {{.Name}}, along with the number of matching records
+type {{.Name}}Page struct {
+	Page     int64
+	PageSize int64
+	Total    int64
+	List     []po.{{.Name}}Po
+}
+
+// New{{.Name}}Service returns the stub of the {{.Name}} service of client, i.e.
+// New{{.Name}}Service(rpc.NewClient("http://127.0.0.1:8080/"))
+func New{{.Name}}Service(client rpc.Client) *{{.Name}}Service {
+	s := &{{.Name}}Service{}
+	client.UseService(s, "{{.Name}}")
+	return s
+}
 `
 	HproseStructModelTPL = `package models
 {{importTimePkg}}
@@ -552,3 +738,8 @@ func Delete{{modelName}}(id int) (err error) {
 }
 `
 )
+
+func init() {
+	templates.Register("hprose/service.go.tpl", HproseServiceTPL)
+	templates.Register("hprose/client.go.tpl", HproseClientTPL)
+}
@@ -0,0 +1,115 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+const beegoImportPath = "github.com/astaxie/beego"
+
+// routerMerger returns the merger adding the namespaces of the tables missing from an
+// existing router file to its first beego.NewNamespace call. The routes already
+// registered, and anything else of the file, are left as is.
+func routerMerger(tables []*Table, pkgPath string) utils.Merger {
+	return func(fpath, current, generated string) (string, bool) {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, fpath, current, parser.ParseComments)
+		if err != nil {
+			beeLogger.Log.Warnf("Could not parse '%s': %s", fpath, err)
+			return "", false
+		}
+		beegoName, ctrlName := importName(f, beegoImportPath), importName(f, pkgPath+"/controllers")
+		if beegoName == "" || ctrlName == "" {
+			return "", false
+		}
+
+		var ns *ast.CallExpr
+		registered := make(map[string]bool)
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if isSelector(n.Fun, beegoName, "NewNamespace") && ns == nil {
+					ns = n
+				}
+			case *ast.CompositeLit:
+				if sel, ok := n.Type.(*ast.SelectorExpr); ok && isSelector(sel, ctrlName, sel.Sel.Name) {
+					registered[sel.Sel.Name] = true
+				}
+			}
+			return true
+		})
+		if ns == nil {
+			return "", false
+		}
+
+		var entries []string
+		for _, tb := range tables {
+			if registered[tb.GoName+"Controller"] {
+				continue
+			}
+			entries = append(entries, fmt.Sprintf("%s.NSNamespace(%s,\n%s.NSInclude(\n&%s.%sController{},\n),\n),",
+				beegoName, strconv.Quote("/"+tb.Route), beegoName, ctrlName, tb.GoName))
+		}
+		if len(entries) == 0 {
+			return current, true
+		}
+
+		// The entries go after the last argument, which may lack its trailing comma
+		rparen := fset.Position(ns.Rparen).Offset
+		at := strings.TrimRight(current[:rparen], " \t\r\n")
+		insert := "\n\n" + strings.Join(entries, "\n\n")
+		if last := at[len(at)-1]; last != ',' && last != '(' {
+			insert = "," + insert
+		}
+		if !strings.Contains(current[len(at):rparen], "\n") {
+			insert += "\n"
+		}
+		return at + insert + current[len(at):], true
+	}
+}
+
+// importName returns the name the file f imports the package importPath as,
+// or an empty string if it doesn't
+func importName(f *ast.File, importPath string) string {
+	for _, spec := range f.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != importPath {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return importPath[strings.LastIndex(importPath, "/")+1:]
+	}
+	return ""
+}
+
+// isSelector reports whether expr is the selector pkg.name
+func isSelector(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == pkg
+}
@@ -16,13 +16,12 @@ package generate
 
 import (
 	"fmt"
-	"os"
 	"path"
 	"strings"
 	"time"
 
+	"github.com/iwooyun/bee/generate/templates"
 	"github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 )
 
@@ -174,117 +173,163 @@ func (m postgresqlDriver) getSQLType(ktype string) (tp, tag string) {
 	return "", ""
 }
 
+type sqliteDriver struct{}
+
+func (m sqliteDriver) GenerateCreateUp(tableName string) string {
+	upsql := `m.SQL("CREATE TABLE ` + tableName + "(" + m.generateSQLFromFields(Fields.String()) + `)");`
+	return upsql
+}
+
+func (m sqliteDriver) GenerateCreateDown(tableName string) string {
+	downsql := `m.SQL("DROP TABLE ` + tableName + `")`
+	return downsql
+}
+
+func (m sqliteDriver) generateSQLFromFields(fields string) string {
+	sql := ""
+	fds := strings.Split(fields, ",")
+	for i, v := range fds {
+		kv := strings.SplitN(v, ":", 2)
+		if len(kv) != 2 {
+			beeLogger.Log.Error("Fields format is wrong. Should be: key:type,key:type " + v)
+			return ""
+		}
+		typ := m.getSQLType(kv[1])
+		if typ == "" {
+			beeLogger.Log.Error("Fields format is wrong. Should be: key:type,key:type " + v)
+			return ""
+		}
+		if i == 0 && strings.ToLower(kv[0]) != "id" {
+			sql += "id INTEGER PRIMARY KEY AUTOINCREMENT,"
+		}
+		sql += utils.SnakeString(kv[0]) + " " + typ + ","
+	}
+	return strings.TrimRight(sql, ",")
+}
+
+// getSQLType returns the SQLite declared type of a field. SQLite has no
+// size limits, but the declared sizes are kept for the generated models
+func (m sqliteDriver) getSQLType(ktype string) string {
+	kv := strings.SplitN(ktype, ":", 2)
+	switch kv[0] {
+	case "string":
+		if len(kv) == 2 {
+			return "varchar(" + kv[1] + ") NOT NULL"
+		}
+		return "varchar(128) NOT NULL"
+	case "text":
+		return "TEXT NOT NULL"
+	case "auto", "pk":
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	case "datetime":
+		return "datetime NOT NULL"
+	case "int", "int8", "int16", "int32", "int64":
+		fallthrough
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return "integer DEFAULT NULL"
+	case "bool":
+		return "boolean NOT NULL"
+	case "float32", "float64", "float":
+		return "real NOT NULL"
+	}
+	return ""
+}
+
 func NewDBDriver() DBDriver {
 	switch SQLDriver {
 	case "mysql":
 		return mysqlDriver{}
 	case "postgres":
 		return postgresqlDriver{}
+	case "sqlite", "sqlite3":
+		return sqliteDriver{}
 	default:
 		beeLogger.Log.Fatal("Driver not supported")
 		return nil
 	}
 }
 
+// migrationData is the data the migration template is executed with
+type migrationData struct {
+	Name       string // name of the migration, the table of the DDL migrations
+	StructName string // name of the migration struct, e.g. CreatePost_20060102_150405
+	Created    string // creation time of the migration
+	DDL        string // either create or alter for DDL migrations, empty otherwise
+	UpSQL      string // statements of the Up method
+	DownSQL    string // statements of the Down method
+}
+
 // generateMigration generates migration file template for database schema update.
 // The generated file template consists of an up() method for updating schema and
 // a down() method for reverting the update.
 func GenerateMigration(mname, upsql, downsql, curpath string) {
-	w := colors.NewColorWriter(os.Stdout)
+	writeGoMigration(mname, strings.ToLower(DDL.String()), upsql, downsql, curpath)
+}
+
+// writeGoMigration writes the Go migration mname, a DDL migration if ddl is either create or alter
+func writeGoMigration(mname, ddl, upsql, downsql, curpath string) {
 	migrationFilePath := path.Join(curpath, DBPath, MPath)
-	if _, err := os.Stat(migrationFilePath); os.IsNotExist(err) {
-		// create migrations directory
-		if err := os.MkdirAll(migrationFilePath, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create migration directory: %s", err)
-		}
-	}
 	// create file
 	today := time.Now().Format(MDateFormat)
+	content := templates.MustExecute("migration.go.tpl", migrationData{
+		Name:       mname,
+		StructName: utils.CamelCase(mname) + "_" + today,
+		Created:    today,
+		DDL:        ddl,
+		UpSQL:      upsql,
+		DownSQL:    downsql,
+	})
 	fpath := path.Join(migrationFilePath, fmt.Sprintf("%s_%s.go", today, mname))
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		ddlSpec := ""
-		spec := ""
-		up := ""
-		down := ""
-		if DDL != "" {
-			ddlSpec = "m.ddlSpec()"
-			switch strings.Title(DDL.String()) {
-			case "Create":
-				spec = strings.Replace(DDLSpecCreate, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			case "Alter":
-				spec = strings.Replace(DDLSpecAlter, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			}
-			spec = strings.Replace(spec, "{{tableName}}", mname, -1)
-		} else {
-			up = strings.Replace(MigrationUp, "{{UpSQL}}", upsql, -1)
-			up = strings.Replace(up, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-			down = strings.Replace(MigrationDown, "{{DownSQL}}", downsql, -1)
-			down = strings.Replace(down, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-		}
-
-		header := strings.Replace(MigrationHeader, "{{StructName}}", utils.CamelCase(mname)+"_"+today, -1)
-		header = strings.Replace(header, "{{ddlSpec}}", ddlSpec, -1)
-		header = strings.Replace(header, "{{CurrTime}}", today, -1)
-		f.WriteString(header + spec + up + down)
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create migration file: %s", err)
-	}
+	utils.WriteGeneratedFile(fpath, content)
 }
 
-const (
-	MigrationHeader = `package main
+const migrationTpl = `package main
 						import (
 							"github.com/astaxie/beego/migration"
 						)
 
 						// DO NOT MODIFY
-						type {{StructName}} struct {
+						type {{.StructName}} struct {
 							migration.Migration
 						}
 
 						// DO NOT MODIFY
 						func init() {
-							m := &{{StructName}}{}
-							m.Created = "{{CurrTime}}"
-							{{ddlSpec}}
-							migration.Register("{{StructName}}", m)
+							m := &{{.StructName}}{}
+							m.Created = "{{.Created}}"
+							{{if .DDL}}m.ddlSpec(){{end}}
+							migration.Register("{{.StructName}}", m)
 						}
-					   `
-
-	DDLSpecCreate = `
+					   {{if not .DDL}}
+				// Run the migrations
+				func (m *{{.StructName}}) Up() {
+					// use m.SQL("CREATE TABLE ...") to make schema update
+					{{.UpSQL}}
+				}
+				// Reverse the migrations
+				func (m *{{.StructName}}) Down() {
+					// use m.SQL("DROP TABLE ...") to reverse schema update
+					{{.DownSQL}}
+				}
+				{{else if eq .DDL "create"}}
 				/*
 				refer beego/migration/doc.go
 				*/
-				func(m *{{StructName}}) ddlSpec(){
-				m.CreateTable("{{tableName}}", "InnoDB", "utf8")
+				func(m *{{.StructName}}) ddlSpec(){
+				m.CreateTable("{{.Name}}", "InnoDB", "utf8")
 				m.PriCol("id").SetAuto(true).SetNullable(false).SetDataType("INT(10)").SetUnsigned(true)
 
 				}
-				`
-	DDLSpecAlter = `
+				{{else if eq .DDL "alter"}}
 				/*
 				refer beego/migration/doc.go
 				*/
-				func(m *{{StructName}}) ddlSpec(){
-				m.AlterTable("{{tableName}}")
+				func(m *{{.StructName}}) ddlSpec(){
+				m.AlterTable("{{.Name}}")
 
 				}
-				`
-	MigrationUp = `
-				// Run the migrations
-				func (m *{{StructName}}) Up() {
-					// use m.SQL("CREATE TABLE ...") to make schema update
-					{{UpSQL}}
-				}`
-	MigrationDown = `
-				// Reverse the migrations
-				func (m *{{StructName}}) Down() {
-					// use m.SQL("DROP TABLE ...") to reverse schema update
-					{{DownSQL}}
-				}
-				`
-)
+				{{end}}`
+
+func init() {
+	templates.Register("migration.go.tpl", migrationTpl)
+}
@@ -16,19 +16,23 @@ package generate
 
 import (
 	"errors"
-	"fmt"
-	"os"
 	"path"
 	"strings"
 
+	"github.com/iwooyun/bee/generate/templates"
 	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 )
 
-func GenerateModel(mname, fields, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
+// modelData is the data the model template is executed with
+type modelData struct {
+	Package       string // package of the model, models unless the model name has a path
+	Name          string // name of the model, e.g. Post
+	Struct        string // struct of the model, built from the fields
+	ImportTimePkg bool   // whether a field is a time.Time
+}
 
+func GenerateModel(mname, fields, currpath string) {
 	p, f := path.Split(mname)
 	modelName := strings.Title(f)
 	packageName := "models"
@@ -46,31 +50,16 @@ func GenerateModel(mname, fields, currpath string) {
 	beeLogger.Log.Infof("Using '%s' as package name", packageName)
 
 	fp := path.Join(currpath, "models", p)
-	if _, err := os.Stat(fp); os.IsNotExist(err) {
-		// Create the model's directory
-		if err := os.MkdirAll(fp, 0777); err != nil {
-			beeLogger.Log.Fatalf("Could not create the model directory: %s", err)
-		}
-	}
+
+	content := templates.MustExecute("model.go.tpl", modelData{
+		Package:       packageName,
+		Name:          modelName,
+		Struct:        modelStruct,
+		ImportTimePkg: hastime,
+	})
 
 	fpath := path.Join(fp, strings.ToLower(modelName)+".go")
-	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		content := strings.Replace(modelTpl, "{{packageName}}", packageName, -1)
-		content = strings.Replace(content, "{{modelName}}", modelName, -1)
-		content = strings.Replace(content, "{{modelStruct}}", modelStruct, -1)
-		if hastime {
-			content = strings.Replace(content, "{{timePkg}}", `"time"`, -1)
-		} else {
-			content = strings.Replace(content, "{{timePkg}}", "", -1)
-		}
-		f.WriteString(content)
-		// Run 'gofmt' on the generated source code
-		utils.FormatSourceCode(fpath)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fpath, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create model file: %s", err)
-	}
+	utils.WriteGeneratedFile(fpath, content)
 }
 
 func getStruct(structname, fields string) (string, bool, error) {
@@ -137,48 +126,48 @@ func getType(ktype string) (kt, tag string, hasTime bool) {
 	return "", "", false
 }
 
-var modelTpl = `package {{packageName}}
+var modelTpl = `package {{.Package}}
 
 import (
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
-	{{timePkg}}
+	{{if .ImportTimePkg}}"time"{{end}}
 	"github.com/astaxie/beego/orm"
 )
 
-{{modelStruct}}
+{{.Struct}}
 
 func init() {
-	orm.RegisterModel(new({{modelName}}))
+	orm.RegisterModel(new({{.Name}}))
 }
 
-// Add{{modelName}} insert a new {{modelName}} into database and returns
+// Add{{.Name}} insert a new {{.Name}} into database and returns
 // last inserted Id on success.
-func Add{{modelName}}(m *{{modelName}}) (id int64, err error) {
+func Add{{.Name}}(m *{{.Name}}) (id int64, err error) {
 	o := orm.NewOrm()
 	id, err = o.Insert(m)
 	return
 }
 
-// Get{{modelName}}ById retrieves {{modelName}} by Id. Returns error if
+// Get{{.Name}}ById retrieves {{.Name}} by Id. Returns error if
 // Id doesn't exist
-func Get{{modelName}}ById(id int64) (v *{{modelName}}, err error) {
+func Get{{.Name}}ById(id int64) (v *{{.Name}}, err error) {
 	o := orm.NewOrm()
-	v = &{{modelName}}{Id: id}
-	if err = o.QueryTable(new({{modelName}})).Filter("Id", id).RelatedSel().One(v); err == nil {
+	v = &{{.Name}}{Id: id}
+	if err = o.QueryTable(new({{.Name}})).Filter("Id", id).RelatedSel().One(v); err == nil {
 		return v, nil
 	}
 	return nil, err
 }
 
-// GetAll{{modelName}} retrieves all {{modelName}} matches certain condition. Returns empty list if
+// GetAll{{.Name}} retrieves all {{.Name}} matches certain condition. Returns empty list if
 // no records exist
-func GetAll{{modelName}}(query map[string]string, fields []string, sortby []string, order []string,
+func GetAll{{.Name}}(query map[string]string, fields []string, sortby []string, order []string,
 	offset int64, limit int64) (ml []interface{}, err error) {
 	o := orm.NewOrm()
-	qs := o.QueryTable(new({{modelName}}))
+	qs := o.QueryTable(new({{.Name}}))
 	// query k=v
 	for k, v := range query {
 		// rewrite dot-notation to Object__Attribute
@@ -224,7 +213,7 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 		}
 	}
 
-	var l []{{modelName}}
+	var l []{{.Name}}
 	qs = qs.OrderBy(sortFields...).RelatedSel()
 	if _, err = qs.Limit(limit, offset).All(&l, fields...); err == nil {
 		if len(fields) == 0 {
@@ -247,11 +236,11 @@ func GetAll{{modelName}}(query map[string]string, fields []string, sortby []stri
 	return nil, err
 }
 
-// Update{{modelName}} updates {{modelName}} by Id and returns error if
+// Update{{.Name}} updates {{.Name}} by Id and returns error if
 // the record to be updated doesn't exist
-func Update{{modelName}}ById(m *{{modelName}}) (err error) {
+func Update{{.Name}}ById(m *{{.Name}}) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: m.Id}
+	v := {{.Name}}{Id: m.Id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
@@ -262,18 +251,22 @@ func Update{{modelName}}ById(m *{{modelName}}) (err error) {
 	return
 }
 
-// Delete{{modelName}} deletes {{modelName}} by Id and returns error if
+// Delete{{.Name}} deletes {{.Name}} by Id and returns error if
 // the record to be deleted doesn't exist
-func Delete{{modelName}}(id int64) (err error) {
+func Delete{{.Name}}(id int64) (err error) {
 	o := orm.NewOrm()
-	v := {{modelName}}{Id: id}
+	v := {{.Name}}{Id: id}
 	// ascertain id exists in the database
 	if err = o.Read(&v); err == nil {
 		var num int64
-		if num, err = o.Delete(&{{modelName}}{Id: id}); err == nil {
+		if num, err = o.Delete(&{{.Name}}{Id: id}); err == nil {
 			fmt.Println("Number of records deleted in database:", num)
 		}
 	}
 	return
 }
 `
+
+func init() {
+	templates.Register("model.go.tpl", modelTpl)
+}
@@ -0,0 +1,108 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"strings"
+
+	"github.com/iwooyun/bee/config"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// nameTables sets the Go name, the route segment and the file name of the tables of the
+// database alias following the naming rules of the Beefile. Without rules, all of them
+// are made of the table name, i.e. UserInfo, user_info and user_info for user_info.
+func nameTables(tables []*Table, alias string) {
+	conf := config.Conf.Appcode.Naming
+	switch conf.Routes {
+	case "", "snake", "kebab":
+	default:
+		beeLogger.Log.Fatalf("Unknown route case '%s'. Use either snake or kebab.", conf.Routes)
+	}
+
+	byName := make(map[string]string)
+	for _, tb := range tables {
+		base := stripAffixes(tb.Name, conf.StripPrefixes, conf.StripSuffixes)
+		name := base
+		if conf.Singular {
+			name = singularName(name)
+		}
+		tb.FileName = name
+		tb.GoName = utils.CamelCase(name)
+		goName, ok := conf.Tables[alias+"."+tb.Name]
+		if !ok {
+			goName, ok = conf.Tables[tb.Name]
+		}
+		if ok {
+			tb.GoName = goName
+			tb.FileName = utils.SnakeString(goName)
+		}
+		if other, ok := byName[tb.GoName]; ok {
+			beeLogger.Log.Fatalf("Tables '%s' and '%s' are both named %s. Name one of them in the naming tables of the Beefile.",
+				other, tb.Name, tb.GoName)
+		}
+		byName[tb.GoName] = tb.Name
+
+		switch conf.Routes {
+		case "snake":
+			tb.Route = utils.SnakeString(base)
+		case "kebab":
+			tb.Route = strings.Replace(utils.SnakeString(base), "_", "-", -1)
+		default:
+			tb.Route = base
+		}
+	}
+}
+
+// stripAffixes removes the first matching prefix and suffix from name, unless nothing would be left
+func stripAffixes(name string, prefixes, suffixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			name = name[:len(name)-len(suffix)]
+			break
+		}
+	}
+	return name
+}
+
+// singularName returns name with its last word made singular, i.e. user_info for user_infos
+func singularName(name string) string {
+	i := strings.LastIndex(name, "_") + 1
+	return name[:i] + singular(name[i:])
+}
+
+// singular returns the singular of the English noun s
+func singular(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return s
+	case strings.HasSuffix(lower, "s") && len(s) > 1:
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,404 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/iwooyun/bee/generate/templates"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// protoScalars maps the Go types of the columns to proto scalar types
+var protoScalars = map[string]string{
+	"int":     "int64",
+	"int8":    "int32",
+	"int16":   "int32",
+	"int32":   "int32",
+	"int64":   "int64",
+	"uint":    "uint64",
+	"uint8":   "uint32",
+	"uint16":  "uint32",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"float32": "float",
+	"float64": "double",
+	"bool":    "bool",
+	"string":  "string",
+}
+
+// protoGoTypes maps proto scalar types to the Go types protoc-gen-go generates for them
+var protoGoTypes = map[string]string{
+	"int32":  "int32",
+	"int64":  "int64",
+	"uint32": "uint32",
+	"uint64": "uint64",
+	"float":  "float32",
+	"double": "float64",
+	"bool":   "bool",
+	"string": "string",
+	"bytes":  "[]byte",
+}
+
+// protoWrappers maps proto scalar types to the well-known wrapper of their nullable
+// fields, and to the wrapperspb function making one
+var protoWrappers = map[string][2]string{
+	"int32":  {"google.protobuf.Int32Value", "Int32"},
+	"int64":  {"google.protobuf.Int64Value", "Int64"},
+	"uint32": {"google.protobuf.UInt32Value", "UInt32"},
+	"uint64": {"google.protobuf.UInt64Value", "UInt64"},
+	"float":  {"google.protobuf.FloatValue", "Float"},
+	"double": {"google.protobuf.DoubleValue", "Double"},
+	"bool":   {"google.protobuf.BoolValue", "Bool"},
+	"string": {"google.protobuf.StringValue", "String"},
+}
+
+// sqlNullValues maps the database/sql Null types to their value field and its Go type
+var sqlNullValues = map[string][2]string{
+	"sql.NullBool":    {"Bool", "bool"},
+	"sql.NullInt64":   {"Int64", "int64"},
+	"sql.NullFloat64": {"Float64", "float64"},
+	"sql.NullString":  {"String", "string"},
+}
+
+const (
+	timestamppbPath = "google.golang.org/protobuf/types/known/timestamppb"
+	wrapperspbPath  = "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var protoIdentifier = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// protoData is the data the proto templates are executed with
+type protoData struct {
+	PkgPath      string        // import path of the application, e.g. github.com/user/app
+	Models       *modelPackage // package of the models, whose po structs are converted
+	Name         string        // name of the message, the Go name of the table
+	Plural       string        // plural of the name, i.e. Posts for the List rpc
+	Table        *Table
+	Fields       []*protoField
+	Pk           *protoField // field of the primary key, unset for tables without primary key
+	ProtoImports []string    // files imported by the .proto file
+	StdImports   []string    // standard packages imported by the conversion file
+	Imports      []string    // other packages imported by the conversion file, besides po
+}
+
+// protoField is a field of the message of a table, along with the statements converting
+// it from the po struct p to the message m and back
+type protoField struct {
+	Name   string // name of the field, i.e. author_id
+	Type   string // proto type, i.e. int64, repeated string or google.protobuf.StringValue
+	Number int
+	GoName string // name of the field in the code generated by protoc-gen-go, i.e. AuthorId
+	ToPb   string // statement setting m.GoName from p
+	FromPb string // statement setting the po field of p from m
+}
+
+// GenerateProto generates the protobuf messages and gRPC CRUD services of the tables of a
+// database, or of a schema file if set, along with the functions converting the po structs
+// of the models of the alias, generated into pkg, to the messages and back
+func GenerateProto(driver, connStr, schemaFile, tables, alias, pkg, currpath string) {
+	selectedTables := selectTables(tables)
+	models := newModelPackage(alias, pkg)
+	checkDriver(driver)
+
+	var protoTables []*Table
+	if schemaFile != "" {
+		beeLogger.Log.Info("Analyzing schema tables...")
+		var err error
+		protoTables, err = LoadSchema(utils.SQLDriverName(driver), schemaFile, selectedTables)
+		if err != nil {
+			beeLogger.Log.Fatalf("Could not load schema '%s': %s", schemaFile, err)
+		}
+	} else {
+		protoTables = readTables(utils.SQLDriverName(driver), connStr, selectedTables)
+	}
+
+	// The po structs are those of the appcode, so are their fields
+	pkgPath := getPackagePath(currpath)
+	nameTables(protoTables, models.Alias)
+	resolveRelations(protoTables, pkgPath+"/"+models.Dir)
+	mapColumnTypes(protoTables)
+
+	pbPath := path.Join(currpath, "pb")
+	utils.MkdirGenerated(pbPath)
+	beeLogger.Log.Info("Creating proto files...")
+	for _, tb := range protoTables {
+		data := newProtoData(tb, models, pkgPath)
+		filename := getFileName(tb.FileName)
+		utils.WriteGeneratedFile(path.Join(pbPath, filename+".proto"), templates.MustExecute("proto/message.proto.tpl", data))
+		// Like the services, the po structs are only generated for the tables with a primary key
+		if data.Pk == nil {
+			continue
+		}
+		utils.WriteGeneratedFile(path.Join(pbPath, filename+"_convert.go"), templates.MustExecute("proto/convert.go.tpl", data))
+	}
+}
+
+// newProtoData returns the message of the table tb and its conversions
+func newProtoData(tb *Table, models *modelPackage, pkgPath string) *protoData {
+	data := &protoData{PkgPath: pkgPath, Models: models, Name: tb.GoName, Plural: plural(tb.GoName), Table: tb}
+	protoImports, imports := make(map[string]bool), make(map[string]bool)
+	for _, col := range tb.Columns {
+		name := col.Tag.Column
+		if !protoIdentifier.MatchString(name) {
+			name = utils.SnakeString(col.Name)
+		}
+		field := &protoField{Name: name, Number: len(data.Fields) + 1, GoName: protoGoName(name)}
+		if !convertProtoField(field, col, protoImports, imports) {
+			beeLogger.Log.Warnf("Column '%s.%s' of Go type %s has no proto type, it is left out of the %s message",
+				tb.Name, col.Tag.Column, col.Type, tb.GoName)
+			continue
+		}
+		if tb.Pk != "" && col.Tag.Column == tb.Pk {
+			data.Pk = field
+		}
+		data.Fields = append(data.Fields, field)
+	}
+	if data.Pk != nil {
+		protoImports["google/protobuf/empty.proto"] = true
+	}
+	for p := range protoImports {
+		data.ProtoImports = append(data.ProtoImports, p)
+	}
+	for p := range imports {
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			data.Imports = append(data.Imports, p)
+		} else {
+			data.StdImports = append(data.StdImports, p)
+		}
+	}
+	sort.Strings(data.ProtoImports)
+	sort.Strings(data.StdImports)
+	sort.Strings(data.Imports)
+	return data
+}
+
+// convertProtoField sets the proto type and the conversions of field from the Go type of
+// col, adding the files and packages they need to protoImports and imports. It reports
+// whether the type can be converted: Go types outside of the built-in ones, except
+// time.Time and json.RawMessage, cannot.
+func convertProtoField(field *protoField, col *Column, protoImports, imports map[string]bool) bool {
+	p, m := "p."+col.Name, "m."+field.GoName
+	goType, nullable := col.Type, col.Tag.Null
+	kind := ""
+	switch {
+	case strings.HasPrefix(goType, "sql.Null"):
+		value, ok := sqlNullValues[goType]
+		if !ok {
+			return false
+		}
+		kind, goType, nullable = "sql", value[1], true
+	case strings.HasPrefix(goType, "*"):
+		kind, goType, nullable = "pointer", goType[1:], true
+	case goType == "[]byte" || goType == "json.RawMessage":
+		field.Type = "bytes"
+		field.ToPb, field.FromPb = m+" = "+p, p+" = "+m
+		return true
+	case strings.HasPrefix(goType, "[]"):
+		elem := goType[2:]
+		scalar, ok := protoScalars[elem]
+		if !ok {
+			return false
+		}
+		field.Type = "repeated " + scalar
+		if pbType := protoGoTypes[scalar]; pbType == elem {
+			field.ToPb, field.FromPb = m+" = "+p, p+" = "+m
+		} else {
+			field.ToPb = fmt.Sprintf("for _, v := range %s {\n%s = append(%s, %s(v))\n}", p, m, m, pbType)
+			field.FromPb = fmt.Sprintf("for _, v := range %s {\n%s = append(%s, %s(v))\n}", m, p, p, elem)
+		}
+		return true
+	}
+
+	if goType == "time.Time" {
+		field.Type = "google.protobuf.Timestamp"
+		protoImports["google/protobuf/timestamp.proto"] = true
+		imports[timestamppbPath] = true
+		switch kind {
+		case "pointer":
+			field.ToPb = fmt.Sprintf("if %s != nil {\n%s = timestamppb.New(*%s)\n}", p, m, p)
+			field.FromPb = fmt.Sprintf("if %s != nil {\nt := %s.AsTime()\n%s = &t\n}", m, m, p)
+		default:
+			field.ToPb = fmt.Sprintf("if !%s.IsZero() {\n%s = timestamppb.New(%s)\n}", p, m, p)
+			field.FromPb = fmt.Sprintf("if %s != nil {\n%s = %s.AsTime()\n}", m, p, m)
+		}
+		return true
+	}
+
+	scalar, ok := protoScalars[goType]
+	if !ok {
+		return false
+	}
+	pbType := protoGoTypes[scalar]
+	convert := func(typ, v string) string {
+		if typ == pbType && typ == goType {
+			return v
+		}
+		return typ + "(" + v + ")"
+	}
+	if !nullable {
+		field.Type = scalar
+		field.ToPb = m + " = " + convert(pbType, p)
+		field.FromPb = p + " = " + convert(goType, m)
+		return true
+	}
+
+	wrapper := protoWrappers[scalar]
+	field.Type = wrapper[0]
+	protoImports["google/protobuf/wrappers.proto"] = true
+	imports[wrapperspbPath] = true
+	switch kind {
+	case "sql":
+		value := sqlNullValues[col.Type][0]
+		field.ToPb = fmt.Sprintf("if %s.Valid {\n%s = wrapperspb.%s(%s)\n}", p, m, wrapper[1], convert(pbType, p+"."+value))
+		field.FromPb = fmt.Sprintf("if %s != nil {\n%s = %s{%s: %s, Valid: true}\n}", m, p, col.Type, value, convert(goType, m+".Value"))
+		imports["database/sql"] = true
+	case "pointer":
+		field.ToPb = fmt.Sprintf("if %s != nil {\n%s = wrapperspb.%s(%s)\n}", p, m, wrapper[1], convert(pbType, "*"+p))
+		field.FromPb = fmt.Sprintf("if %s != nil {\nv := %s\n%s = &v\n}", m, convert(goType, m+".Value"), p)
+	default:
+		field.ToPb = fmt.Sprintf("%s = wrapperspb.%s(%s)", m, wrapper[1], convert(pbType, p))
+		field.FromPb = fmt.Sprintf("if %s != nil {\n%s = %s\n}", m, p, convert(goType, m+".Value"))
+	}
+	return true
+}
+
+// protoGoName returns the name protoc-gen-go gives to the Go field of the proto field name
+func protoGoName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isASCIILower(name[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isASCIILower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+const (
+	ProtoTPL = `syntax = "proto3";
+
+package pb;
+
+option go_package = "{{.PkgPath}}/pb;pb";
+{{- if .ProtoImports}}
+{{range .ProtoImports}}
+import "{{.}}";
+{{- end}}
+{{- end}}
+
+// {{.Name}} is a row of the {{.Table.Name}} table
+message {{.Name}} {
+{{- range .Fields}}
+  {{.Type}} {{.Name}} = {{.Number}};
+{{- end}}
+}
+{{- with .Pk}}
+
+service {{$.Name}}Service {
+  rpc Create{{$.Name}}({{$.Name}}) returns ({{$.Name}});
+  rpc Get{{$.Name}}(Get{{$.Name}}Request) returns ({{$.Name}});
+  rpc List{{$.Plural}}(List{{$.Plural}}Request) returns (List{{$.Plural}}Response);
+  rpc Update{{$.Name}}({{$.Name}}) returns ({{$.Name}});
+  rpc Delete{{$.Name}}(Delete{{$.Name}}Request) returns (google.protobuf.Empty);
+}
+
+message Get{{$.Name}}Request {
+  {{.Type}} {{.Name}} = 1;
+}
+
+// List{{$.Plural}}Request is a page of {{$.Name}} matching the query, whose keys use the
+// dot notation, i.e. name.contains
+message List{{$.Plural}}Request {
+  int64 page = 1;
+  int64 page_size = 2;
+  map<string, string> query = 3;
+  repeated string sortby = 4;
+  repeated string order = 5;
+}
+
+message List{{$.Plural}}Response {
+  repeated {{$.Name}} list = 1;
+  int64 page = 2;
+  int64 page_size = 3;
+  int64 total = 4;
+}
+
+message Delete{{$.Name}}Request {
+  {{.Type}} {{.Name}} = 1;
+}
+{{- end}}
+`
+	ProtoConvertTPL = `package pb
+
+import (
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{- if .StdImports}}
+{{end}}
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+{{- if .Imports}}
+{{end}}
+	"{{.PkgPath}}/{{.Models.Dir}}/po"
+)
+
+// {{.Name}}FromPo returns the {{.Name}} message of the po p
+func {{.Name}}FromPo(p *po.{{.Name}}Po) *{{.Name}} {
+	m := &{{.Name}}{}
+{{- range .Fields}}
+	{{.ToPb}}
+{{- end}}
+	return m
+}
+
+// {{.Name}}ToPo returns the po of the {{.Name}} message m
+func {{.Name}}ToPo(m *{{.Name}}) *po.{{.Name}}Po {
+	p := &po.{{.Name}}Po{}
+{{- range .Fields}}
+	{{.FromPb}}
+{{- end}}
+	return p
+}
+`
+)
+
+func init() {
+	templates.Register("proto/message.proto.tpl", ProtoTPL)
+	templates.Register("proto/convert.go.tpl", ProtoConvertTPL)
+}
@@ -0,0 +1,231 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"fmt"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// Relation is a relation field of a model, built from the foreign keys of the tables
+type Relation struct {
+	Name     string  // name of the field, e.g. Author
+	Type     string  // type of the field, e.g. *Author or []*Post
+	RefTable string  // table of the related model
+	Model    string  // name of the related model, e.g. Author
+	Column   *Column // column holding the foreign key of rel(fk) and rel(one) relations, nil otherwise
+	Tag      *OrmTag // rel or reverse options of the field
+}
+
+// String returns the source code of the relation field,
+// e.g. Author *Author `orm:"column(author_id);rel(fk)" json:"author,omitempty"`
+func (rel *Relation) String() string {
+	return fmt.Sprintf("%s %s `orm:\"%s\" json:\"%s,omitempty\"`",
+		rel.Name, rel.Type, strings.Join(rel.Tag.options(), ";"), utils.SnakeString(rel.Name))
+}
+
+// FkRelations returns the relations of the table held by one of its columns
+func (tb *Table) FkRelations() (rels []*Relation) {
+	for _, rel := range tb.Relations {
+		if rel.Column != nil {
+			rels = append(rels, rel)
+		}
+	}
+	return
+}
+
+// resolveRelations adds the relation fields of the models of tables from their foreign keys.
+// Only tables with a primary key are registered as models, so relations link them only:
+//   - a foreign key column becomes a rel(fk) field, or rel(one) when the column is unique.
+//     The referenced model gets the reverse(many) or reverse(one) field, unless the table
+//     references it several times
+//   - a junction table, made of two foreign keys and possibly a primary key, becomes a rel(m2m)
+//     field of the first referenced model and a reverse(many) field of the second one
+//
+// The foreign key columns are kept for the po and vo structs, but are ignored by the orm.
+// modelsPath is the import path of the package of the models.
+func resolveRelations(tables []*Table, modelsPath string) {
+	models := make(map[string]*Table)
+	for _, tb := range tables {
+		tb.Relations = nil
+		if tb.Pk != "" {
+			models[tb.Name] = tb
+		}
+	}
+
+	for _, tb := range tables {
+		fks := relationFks(tb, models)
+		junction := isJunction(tb, fks)
+		if junction {
+			addM2M(tb, fks, models, modelsPath)
+		}
+		if tb.Pk == "" {
+			continue
+		}
+		refCount := make(map[string]int)
+		for _, col := range fks {
+			refCount[tb.Fk[col.Tag.Column].RefTable]++
+		}
+		for _, col := range fks {
+			ref := models[tb.Fk[col.Tag.Column].RefTable]
+			one := isUnique(tb, col.Tag.Column)
+			col.Tag.Ignore = true
+			addRelation(tb, &Relation{
+				Name:     relationName(col.Tag.Column, ref.GoName),
+				Type:     "*" + ref.GoName,
+				RefTable: ref.Name,
+				Model:    ref.GoName,
+				Column:   col,
+				Tag:      &OrmTag{Column: col.Tag.Column, Null: col.Tag.Null, RelFk: !one, RelOne: one, OnDelete: "do_nothing"},
+			})
+
+			// a reverse field would be ambiguous if the table references the model several times
+			if junction || refCount[ref.Name] > 1 {
+				continue
+			}
+			if one {
+				addRelation(ref, &Relation{
+					Name:     tb.GoName,
+					Type:     "*" + tb.GoName,
+					RefTable: tb.Name,
+					Model:    tb.GoName,
+					Tag:      &OrmTag{ReverseOne: true},
+				})
+			} else {
+				addRelation(ref, &Relation{
+					Name:     plural(tb.GoName),
+					Type:     "[]*" + tb.GoName,
+					RefTable: tb.Name,
+					Model:    tb.GoName,
+					Tag:      &OrmTag{ReverseMany: true},
+				})
+			}
+		}
+	}
+}
+
+// relationFks returns the integer foreign key columns of tb which reference the primary key of a model
+func relationFks(tb *Table, models map[string]*Table) (fks []*Column) {
+	for _, col := range tb.Columns {
+		fk, ok := tb.Fk[col.Tag.Column]
+		if !ok || col.Tag.Column == tb.Pk || !strings.Contains(col.Type, "int") {
+			continue
+		}
+		if ref, ok := models[fk.RefTable]; ok && fk.RefColumn == ref.Pk {
+			fks = append(fks, col)
+		}
+	}
+	return
+}
+
+// isUnique reports whether column has a unique key of its own
+func isUnique(tb *Table, column string) bool {
+	for _, uk := range tb.Uk {
+		if uk == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isJunction reports whether tb only links two different models, i.e. post_tag(post_id, tag_id)
+func isJunction(tb *Table, fks []*Column) bool {
+	if len(fks) != 2 || tb.Fk[fks[0].Tag.Column].RefTable == tb.Fk[fks[1].Tag.Column].RefTable {
+		return false
+	}
+	for _, col := range tb.Columns {
+		if col != fks[0] && col != fks[1] && col.Tag.Column != tb.Pk {
+			return false
+		}
+	}
+	return true
+}
+
+// addM2M adds the many-to-many relation of the junction table tb. A junction table with a
+// primary key is a model, the relation goes through it. Otherwise the orm builds the junction
+// model itself, which needs the columns to be named after the tables, i.e. post_id and tag_id.
+func addM2M(tb *Table, fks []*Column, models map[string]*Table, modelsPath string) {
+	from, to := models[tb.Fk[fks[0].Tag.Column].RefTable], models[tb.Fk[fks[1].Tag.Column].RefTable]
+	tag := &OrmTag{RelM2M: true}
+	if tb.Pk != "" {
+		tag.RelThrough = modelsPath + "." + tb.GoName
+	} else if fks[0].Tag.Column == from.Name+"_id" && fks[1].Tag.Column == to.Name+"_id" {
+		tag.RelTable = tb.Name
+	} else {
+		beeLogger.Log.Warnf("Junction table '%s' has no primary key and its columns are not named %s_id and %s_id, "+
+			"the many-to-many relation between '%s' and '%s' is left out", tb.Name, from.Name, to.Name, from.Name, to.Name)
+		return
+	}
+	addRelation(from, &Relation{
+		Name:     plural(to.GoName),
+		Type:     "[]*" + to.GoName,
+		RefTable: to.Name,
+		Model:    to.GoName,
+		Tag:      tag,
+	})
+	addRelation(to, &Relation{
+		Name:     plural(from.GoName),
+		Type:     "[]*" + from.GoName,
+		RefTable: from.Name,
+		Model:    from.GoName,
+		Tag:      &OrmTag{ReverseMany: true},
+	})
+}
+
+// addRelation adds rel to the relations of tb, renaming it when a field already has its name
+func addRelation(tb *Table, rel *Relation) {
+	taken := func(name string) bool {
+		for _, col := range tb.Columns {
+			if col.Name == name {
+				return true
+			}
+		}
+		for _, r := range tb.Relations {
+			if r.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	for taken(rel.Name) {
+		rel.Name += "Rel"
+	}
+	tb.Relations = append(tb.Relations, rel)
+}
+
+// relationName returns the field name of the relation held by column, i.e.
+// Author for author_id, and OwnerUser for owner referencing the User model
+func relationName(column, model string) string {
+	if name := strings.TrimSuffix(column, "_id"); name != column && name != "" {
+		return utils.CamelCase(name)
+	}
+	return utils.CamelCase(column) + model
+}
+
+// plural returns the plural of the English noun s
+func plural(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(s) > 1 && !strings.ContainsAny(lower[len(s)-2:len(s)-1], "aeiou"):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
@@ -9,28 +9,32 @@ import (
 )
 
 func GenerateScaffold(sname, fields, currpath, driver, conn string) {
-	beeLogger.Log.Infof("Do you want to create a '%s' model? [Yes|No] ", sname)
+	// With -force, -skip-existing or -dry-run, every part is generated without asking
+	confirm := func(format string, args ...interface{}) bool {
+		if utils.GenUnattended() {
+			return true
+		}
+		beeLogger.Log.Infof(format, args...)
+		return utils.AskForConfirmation()
+	}
 
 	// Generate the model
-	if utils.AskForConfirmation() {
+	if confirm("Do you want to create a '%s' model? [Yes|No] ", sname) {
 		GenerateModel(sname, fields, currpath)
 	}
 
 	// Generate the controller
-	beeLogger.Log.Infof("Do you want to create a '%s' controller? [Yes|No] ", sname)
-	if utils.AskForConfirmation() {
+	if confirm("Do you want to create a '%s' controller? [Yes|No] ", sname) {
 		GenerateController(sname, currpath)
 	}
 
 	// Generate the views
-	beeLogger.Log.Infof("Do you want to create views for this '%s' resource? [Yes|No] ", sname)
-	if utils.AskForConfirmation() {
+	if confirm("Do you want to create views for this '%s' resource? [Yes|No] ", sname) {
 		GenerateView(sname, currpath)
 	}
 
 	// Generate a migration
-	beeLogger.Log.Infof("Do you want to create a '%s' migration and schema for this resource? [Yes|No] ", sname)
-	if utils.AskForConfirmation() {
+	if confirm("Do you want to create a '%s' migration and schema for this resource? [Yes|No] ", sname) {
 		upsql := ""
 		downsql := ""
 		if fields != "" {
@@ -41,10 +45,14 @@ func GenerateScaffold(sname, fields, currpath, driver, conn string) {
 		GenerateMigration(sname, upsql, downsql, currpath)
 	}
 
-	// Run the migration
-	beeLogger.Log.Infof("Do you want to migrate the database? [Yes|No] ")
-	if utils.AskForConfirmation() {
-		migrate.MigrateUpdate(currpath, driver, conn, "")
+	// Run the migration, which is never done unattended
+	if utils.GenUnattended() {
+		beeLogger.Log.Info("Not migrating the database unattended. Run 'bee migrate' to apply the migration.")
+	} else {
+		beeLogger.Log.Infof("Do you want to migrate the database? [Yes|No] ")
+		if utils.AskForConfirmation() {
+			migrate.MigrateUpdate(currpath, driver, conn, "")
+		}
 	}
 	beeLogger.Log.Successf("All done! Don't forget to add  beego.Router(\"/%s\" ,&controllers.%sController{}) to routers/route.go\n", sname, strings.Title(sname))
 }
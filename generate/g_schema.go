@@ -0,0 +1,731 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// Snapshot is the schema of a database as written by 'bee db snapshot', tables
+// are already analysed so that it can be used without the database
+type Snapshot struct {
+	Driver string   `json:"driver"`
+	Tables []*Table `json:"tables"`
+}
+
+// WriteSnapshot writes the snapshot of the selected tables of a database to output,
+// - being the standard output
+func WriteSnapshot(driver, connStr, tables, output string) {
+	checkDriver(driver)
+	dbms := utils.SQLDriverName(driver)
+	snapshot := Snapshot{Driver: dbms, Tables: readTables(dbms, connStr, selectTables(tables))}
+	dt, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not encode the snapshot: %s", err)
+	}
+	dt = append(dt, '\n')
+	if output == "-" {
+		os.Stdout.Write(dt)
+		return
+	}
+	if dir := filepath.Dir(output); !utils.IsExist(dir) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			beeLogger.Log.Fatalf("Could not create directory '%s': %s", dir, err)
+		}
+	}
+	if err := ioutil.WriteFile(output, dt, 0644); err != nil {
+		beeLogger.Log.Fatalf("Could not write the snapshot to '%s': %s", output, err)
+	}
+	beeLogger.Log.Successf("Snapshot of %d tables written to '%s'", len(snapshot.Tables), output)
+}
+
+// LoadSchema reads the selected tables, or all the tables, of a snapshot (*.json) or of
+// the CREATE TABLE statements of a SQL file in the dialect of dbms
+func LoadSchema(dbms, schemaFile string, selectedTableNames map[string]bool) ([]*Table, error) {
+	dt, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*Table
+	if strings.EqualFold(filepath.Ext(schemaFile), ".json") {
+		var snapshot Snapshot
+		if err := json.Unmarshal(dt, &snapshot); err != nil {
+			return nil, err
+		}
+		for _, tb := range snapshot.Tables {
+			if len(selectedTableNames) == 0 || selectedTableNames[tb.Name] {
+				tables = append(tables, tb)
+			}
+		}
+	} else {
+		trans, ok := dbDriver[dbms]
+		if !ok {
+			return nil, fmt.Errorf("'%s' dialect is not supported", dbms)
+		}
+		var ddlTables []*ddlTable
+		for _, dtb := range parseDDL(string(dt)) {
+			// internal tables of SQLite are not application tables
+			if dbms == "sqlite3" && strings.HasPrefix(dtb.name, "sqlite_") {
+				continue
+			}
+			if len(selectedTableNames) == 0 || selectedTableNames[dtb.name] {
+				ddlTables = append(ddlTables, dtb)
+			}
+		}
+		if tables, err = ddlTableObjects(dbms, ddlTables, trans); err != nil {
+			return nil, err
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, tb := range tables {
+		found[tb.Name] = true
+	}
+	for name := range selectedTableNames {
+		if !found[name] {
+			beeLogger.Log.Warnf("Table '%s' is not in the schema", name)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no table found")
+	}
+	return tables, nil
+}
+
+// ddlTable is a table as declared by SQL statements
+type ddlTable struct {
+	name    string
+	columns []*ddlColumn
+	pk      []string
+	uk      []string
+	fk      map[string]*ForeignKey
+}
+
+// ddlColumn is a column as declared by SQL statements
+type ddlColumn struct {
+	name        string
+	dataType    string   // type without arguments, i.e. varchar
+	args        []string // type arguments, i.e. 10 and 2 of decimal(10,2)
+	elemType    string   // type of the elements of an array, i.e. integer of integer[]
+	unsigned    bool
+	nullable    bool
+	auto        bool
+	defaultNow  bool
+	onUpdateNow bool
+	comment     string
+}
+
+func (dtb *ddlTable) column(name string) *ddlColumn {
+	for _, col := range dtb.columns {
+		if strings.EqualFold(col.name, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// ddlTableObjects turns the declared tables into tables, in the same way as the
+// DbTransformer of dbms does for the tables of a live database
+func ddlTableObjects(dbms string, ddlTables []*ddlTable, trans DbTransformer) (tables []*Table, err error) {
+	for _, dtb := range ddlTables {
+		tb := new(Table)
+		tb.Name = dtb.name
+		tb.Uk = dtb.uk
+		tb.Fk = dtb.fk
+		if len(dtb.pk) == 1 {
+			tb.Pk = dtb.pk[0]
+		}
+		tables = append(tables, tb)
+	}
+	for i, dtb := range ddlTables {
+		for _, dcol := range dtb.columns {
+			col, err := ddlColumnObject(dbms, tables[i], dcol, trans)
+			if err != nil {
+				return nil, fmt.Errorf("column '%s' of table '%s': %s", dcol.name, dtb.name, err)
+			}
+			tables[i].Columns = append(tables[i].Columns, col)
+		}
+	}
+	return
+}
+
+func ddlColumnObject(dbms string, table *Table, dcol *ddlColumn, trans DbTransformer) (*Column, error) {
+	colName, dataType := dcol.name, dcol.dataType
+	if alias, ok := postgresTypeAliases[dataType]; ok && dbms == "postgres" {
+		dataType = alias
+	}
+	goType, err := trans.GetGoDataType(dataType)
+	if err != nil {
+		return nil, err
+	}
+
+	col := new(Column)
+	col.Name = utils.CamelCase(colName)
+	col.Type = goType
+	col.SQLType = dataType
+	if dcol.elemType != "" {
+		col.SQLType = postgresTypeName(dcol.elemType) + "[]"
+	}
+
+	// Tag info
+	tag := new(OrmTag)
+	tag.Column = colName
+	tag.Comment = dcol.comment
+	col.Tag = tag
+	if table.Pk == colName {
+		col.Name = "Id"
+		col.Type = "int"
+		// an INTEGER PRIMARY KEY column of SQLite is an alias of the rowid
+		if dcol.auto || (dbms == "sqlite3" && dataType == "integer") {
+			tag.Auto = true
+		} else {
+			tag.Pk = true
+		}
+		return col, nil
+	}
+
+	// if the name of column is Id, and it's not primary key
+	if colName == "id" {
+		col.Name = "Id_RENAME"
+	}
+	if dcol.nullable {
+		tag.Null = true
+	}
+	if dcol.unsigned && isSQLSignedIntType(dataType) && !dcol.auto {
+		if col.Type, err = trans.GetGoDataType(dataType + " unsigned"); err != nil {
+			return nil, err
+		}
+	}
+	if (isSQLStringType(dataType) || isSQLBinaryType(dataType) || (dbms == "mysql" && isSQLBitType(dataType))) && len(dcol.args) == 1 {
+		tag.Size = dcol.args[0]
+	}
+	if isSQLTemporalType(dataType) || strings.HasPrefix(dataType, "timestamp") {
+		tag.Type = dataType
+		// check auto_now, auto_now_add
+		if dcol.defaultNow && dcol.onUpdateNow {
+			tag.AutoNow = true
+		} else if dcol.defaultNow {
+			tag.AutoNowAdd = true
+		}
+		// need to import time package
+		table.ImportTimePkg = true
+	}
+	if isSQLDecimal(dataType) && len(dcol.args) == 2 {
+		tag.Digits, tag.Decimals = dcol.args[0], dcol.args[1]
+	}
+	if dbms == "postgres" && isSQLStrangeType(dataType) {
+		tag.Type = dataType
+	}
+	return col, nil
+}
+
+// postgresTypeAliases maps the short names of PostgreSQL types to the names
+// reported by information_schema
+var postgresTypeAliases = map[string]string{
+	"int":         "integer",
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"bool":        "boolean",
+	"float4":      "real",
+	"float8":      "double precision",
+	"timestamptz": "timestamp with time zone",
+	"timetz":      "time",
+	"varchar":     "character varying",
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"bpchar":      "character",
+}
+
+// postgresTypeName returns the name of a PostgreSQL type as reported by information_schema
+func postgresTypeName(name string) string {
+	if alias, ok := postgresTypeAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// ddlToken is a token of SQL statements
+type ddlToken struct {
+	text   string
+	quoted bool // quoted identifier or string literal
+	str    bool // string literal
+}
+
+// is reports whether the token is one of the keywords or punctuations
+func (t ddlToken) is(words ...string) bool {
+	if t.quoted {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeDDL splits SQL statements into tokens, leaving comments out
+func tokenizeDDL(src string) (tokens []ddlToken) {
+	isWord := func(c byte) bool {
+		return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == '-' && strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(src)
+			}
+		case c == '\'' || c == '"' || c == '`':
+			var text []byte
+			j := i + 1
+			for ; j < len(src); j++ {
+				if src[j] == '\\' && c == '\'' && j+1 < len(src) {
+					j++
+				} else if src[j] == c {
+					// doubled quotes are escaped quotes
+					if j+1 < len(src) && src[j+1] == c {
+						j++
+					} else {
+						break
+					}
+				}
+				text = append(text, src[j])
+			}
+			tokens = append(tokens, ddlToken{text: string(text), quoted: true, str: c == '\''})
+			i = j + 1
+		case isWord(c):
+			j := i
+			for j < len(src) && (isWord(src[j]) || src[j] == '.' && c >= '0' && c <= '9') {
+				j++
+			}
+			tokens = append(tokens, ddlToken{text: src[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, ddlToken{text: string(c)})
+			i++
+		}
+	}
+	return
+}
+
+// splitTokens splits tokens on a punctuation outside of parentheses
+func splitTokens(tokens []ddlToken, sep string) (parts [][]ddlToken) {
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+		case depth == 0 && t.is(sep):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// parenGroup returns the tokens inside the parentheses starting at tokens[i],
+// along with the index following them
+func parenGroup(tokens []ddlToken, i int) ([]ddlToken, int) {
+	if i >= len(tokens) || !tokens[i].is("(") {
+		return nil, i
+	}
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		if tokens[j].is("(") {
+			depth++
+		} else if tokens[j].is(")") {
+			if depth--; depth == 0 {
+				return tokens[i+1 : j], j + 1
+			}
+		}
+	}
+	return tokens[i+1:], len(tokens)
+}
+
+// tokenNames returns the names of a comma separated list of identifiers
+func tokenNames(tokens []ddlToken) (names []string) {
+	for _, part := range splitTokens(tokens, ",") {
+		if len(part) > 0 {
+			names = append(names, part[0].text)
+		}
+	}
+	return
+}
+
+// qualifiedName reads a possibly schema qualified name at tokens[i], along with
+// the index following it
+func qualifiedName(tokens []ddlToken, i int) (string, int) {
+	name := ""
+	for i < len(tokens) {
+		name = tokens[i].text
+		if i+2 < len(tokens) && tokens[i+1].is(".") {
+			i += 2
+			continue
+		}
+		return name, i + 1
+	}
+	return name, i
+}
+
+// parseDDL reads the tables declared by CREATE TABLE statements, along with the
+// constraints, indexes and comments added to them by other statements
+func parseDDL(src string) (tables []*ddlTable) {
+	byName := make(map[string]*ddlTable)
+	for _, stmt := range splitTokens(tokenizeDDL(src), ";") {
+		switch {
+		case len(stmt) == 0:
+		case stmt[0].is("CREATE"):
+			i := 1
+			for i < len(stmt) && stmt[i].is("OR", "REPLACE", "TEMPORARY", "TEMP", "UNLOGGED", "GLOBAL", "LOCAL") {
+				i++
+			}
+			if i < len(stmt) && stmt[i].is("TABLE") {
+				if tb := parseCreateTable(stmt[i+1:]); tb != nil {
+					tables = append(tables, tb)
+					byName[tb.name] = tb
+				}
+			} else if i+1 < len(stmt) && stmt[i].is("UNIQUE") && stmt[i+1].is("INDEX") {
+				parseCreateUniqueIndex(stmt[i+2:], byName)
+			}
+		case stmt[0].is("ALTER") && len(stmt) > 1 && stmt[1].is("TABLE"):
+			parseAlterTable(stmt[2:], byName)
+		case stmt[0].is("COMMENT") && len(stmt) > 3 && stmt[1].is("ON") && stmt[2].is("COLUMN"):
+			// COMMENT ON COLUMN [schema.]table.column IS 'comment'
+			var names []string
+			i := 3
+			for ; i < len(stmt) && !stmt[i].is("IS"); i++ {
+				if !stmt[i].is(".") {
+					names = append(names, stmt[i].text)
+				}
+			}
+			if len(names) >= 2 && i+1 < len(stmt) {
+				if tb, ok := byName[names[len(names)-2]]; ok {
+					if col := tb.column(names[len(names)-1]); col != nil {
+						col.comment = stmt[i+1].text
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+func parseCreateTable(tokens []ddlToken) *ddlTable {
+	i := 0
+	if i+2 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("NOT") && tokens[i+2].is("EXISTS") {
+		i += 3
+	}
+	tb := &ddlTable{fk: make(map[string]*ForeignKey)}
+	tb.name, i = qualifiedName(tokens, i)
+	items, _ := parenGroup(tokens, i)
+	// CREATE TABLE ... AS SELECT and CREATE TABLE ... LIKE declare no column
+	if items == nil {
+		return nil
+	}
+	for _, item := range splitTokens(items, ",") {
+		if len(item) == 0 {
+			continue
+		}
+		if !parseTableConstraint(tb, item) {
+			parseColumnDefinition(tb, item)
+		}
+	}
+	return tb
+}
+
+// parseTableConstraint reads a table constraint, it returns false when the
+// tokens are not a table constraint or index
+func parseTableConstraint(tb *ddlTable, item []ddlToken) bool {
+	i := 0
+	if item[0].is("CONSTRAINT") {
+		i = 2
+	}
+	if i >= len(item) {
+		return true
+	}
+	switch {
+	case item[i].is("PRIMARY"):
+		cols, _ := parenGroup(item, i+2)
+		tb.pk = tokenNames(cols)
+	case item[i].is("UNIQUE"):
+		// UNIQUE [KEY|INDEX] [name] (columns)
+		for i < len(item) && !item[i].is("(") {
+			i++
+		}
+		cols, _ := parenGroup(item, i)
+		if names := tokenNames(cols); len(names) == 1 {
+			tb.uk = append(tb.uk, names[0])
+		}
+	case item[i].is("FOREIGN"):
+		for i < len(item) && !item[i].is("(") {
+			i++
+		}
+		cols, next := parenGroup(item, i)
+		if names := tokenNames(cols); len(names) == 1 && next < len(item) && item[next].is("REFERENCES") {
+			addForeignKey(tb, names[0], item[next+1:])
+		}
+	case item[i].is("KEY", "INDEX", "FULLTEXT", "SPATIAL", "CHECK", "EXCLUDE", "LIKE", "PERIOD"):
+	default:
+		return i != 0
+	}
+	return true
+}
+
+// addForeignKey adds the foreign key of column referencing "table [(column)]"
+func addForeignKey(tb *ddlTable, column string, ref []ddlToken) {
+	fk := &ForeignKey{Name: column}
+	var i int
+	fk.RefTable, i = qualifiedName(ref, 0)
+	if cols, _ := parenGroup(ref, i); len(cols) > 0 {
+		fk.RefColumn = cols[0].text
+	}
+	tb.fk[column] = fk
+}
+
+func parseColumnDefinition(tb *ddlTable, item []ddlToken) {
+	col := &ddlColumn{name: item[0].text, nullable: true}
+	// the type is made of the words up to the first constraint keyword
+	var words []string
+	i := 1
+	for ; i < len(item); i++ {
+		t := item[i]
+		if t.is("NOT", "NULL", "DEFAULT", "AUTO_INCREMENT", "AUTOINCREMENT", "PRIMARY", "UNIQUE", "REFERENCES",
+			"COMMENT", "CHECK", "CONSTRAINT", "COLLATE", "ON", "GENERATED", "AS", "KEY", "IDENTITY", "CHARSET") ||
+			t.is("CHARACTER") && i+1 < len(item) && item[i+1].is("SET") {
+			break
+		}
+		switch {
+		case t.is("("):
+			var args []ddlToken
+			args, i = parenGroup(item, i)
+			i--
+			col.args = nil
+			for _, arg := range splitTokens(args, ",") {
+				if len(arg) > 0 {
+					col.args = append(col.args, arg[0].text)
+				}
+			}
+		case t.is("["):
+			if col.dataType != "ARRAY" {
+				col.dataType, col.elemType = "ARRAY", strings.Join(words, " ")
+			}
+			for i+1 < len(item) && !item[i].is("]") {
+				i++
+			}
+		case t.is("UNSIGNED"):
+			col.unsigned = true
+		case t.is("SIGNED", "ZEROFILL"):
+		default:
+			if col.dataType != "ARRAY" {
+				words = append(words, strings.ToLower(t.text))
+			}
+		}
+	}
+	if col.dataType != "ARRAY" {
+		col.dataType = strings.Join(words, " ")
+	}
+	if strings.Contains(col.dataType, "serial") {
+		col.auto = true
+	}
+
+	for i < len(item) {
+		t := item[i]
+		i++
+		switch {
+		case t.is("NOT") && i < len(item) && item[i].is("NULL"):
+			col.nullable = false
+			i++
+		case t.is("NULL"):
+			col.nullable = true
+		case t.is("AUTO_INCREMENT", "AUTOINCREMENT", "IDENTITY"):
+			col.auto = true
+		case t.is("GENERATED"):
+			// GENERATED {ALWAYS|BY DEFAULT} AS IDENTITY
+			for i < len(item) && !item[i].is("IDENTITY", "(") {
+				i++
+			}
+		case t.is("PRIMARY") && i < len(item) && item[i].is("KEY"):
+			tb.pk = []string{col.name}
+			col.nullable = false
+			i++
+		case t.is("UNIQUE"):
+			tb.uk = append(tb.uk, col.name)
+		case t.is("REFERENCES"):
+			addForeignKey(tb, col.name, item[i:])
+			_, i = qualifiedName(item, i)
+			_, i = parenGroup(item, i)
+		case t.is("DEFAULT"):
+			expr := ""
+			for i < len(item) && (item[i].is("(", ")", ":", ".", "-") || !isColumnKeyword(item[i])) {
+				if item[i].is("(") {
+					var group []ddlToken
+					group, i = parenGroup(item, i)
+					expr += "(" + joinTokens(group) + ")"
+					continue
+				}
+				expr += item[i].text
+				i++
+			}
+			col.setDefault(expr)
+		case t.is("ON") && i+1 < len(item) && item[i].is("UPDATE"):
+			col.onUpdateNow = isNowExpression(item[i+1].text)
+			i += 2
+		case t.is("COMMENT") && i < len(item):
+			col.comment = item[i].text
+			i++
+		}
+	}
+	tb.columns = append(tb.columns, col)
+}
+
+func isColumnKeyword(t ddlToken) bool {
+	return t.is("NOT", "NULL", "DEFAULT", "AUTO_INCREMENT", "AUTOINCREMENT", "PRIMARY", "UNIQUE", "REFERENCES",
+		"COMMENT", "CHECK", "CONSTRAINT", "COLLATE", "ON", "GENERATED", "CHARACTER", "CHARSET")
+}
+
+func joinTokens(tokens []ddlToken) string {
+	var parts []string
+	for _, t := range tokens {
+		parts = append(parts, t.text)
+	}
+	return strings.Join(parts, "")
+}
+
+func isNowExpression(expr string) bool {
+	expr = strings.ToUpper(expr)
+	return strings.HasPrefix(expr, "CURRENT_TIMESTAMP") || strings.HasPrefix(expr, "NOW(") || expr == "LOCALTIMESTAMP"
+}
+
+func (col *ddlColumn) setDefault(expr string) {
+	if strings.HasPrefix(strings.ToLower(expr), "nextval(") {
+		col.auto = true
+	}
+	col.defaultNow = isNowExpression(expr)
+}
+
+// parseAlterTable reads the constraints and columns added by ALTER TABLE, as in
+// the dumps of pg_dump and phpMyAdmin
+func parseAlterTable(tokens []ddlToken, byName map[string]*ddlTable) {
+	i := 0
+	for i < len(tokens) && tokens[i].is("ONLY", "IF", "EXISTS") {
+		i++
+	}
+	var name string
+	name, i = qualifiedName(tokens, i)
+	tb, ok := byName[name]
+	if !ok {
+		return
+	}
+	for _, action := range splitTokens(tokens[i:], ",") {
+		switch {
+		case len(action) < 2:
+		case action[0].is("ADD"):
+			item := action[1:]
+			if item[0].is("COLUMN") {
+				item = item[1:]
+			}
+			if len(item) > 0 && !parseTableConstraint(tb, item) {
+				parseColumnDefinition(tb, item)
+			}
+		case action[0].is("MODIFY", "CHANGE"):
+			item := action[1:]
+			if item[0].is("COLUMN") {
+				item = item[1:]
+			}
+			if action[0].is("CHANGE") && len(item) > 0 {
+				// CHANGE old_name new_name definition
+				item = item[1:]
+			}
+			if len(item) > 0 {
+				parseColumnDefinition(tb, item)
+				replaceColumn(tb, tb.columns[len(tb.columns)-1])
+			}
+		case action[0].is("ALTER"):
+			// ALTER [COLUMN] column {SET DEFAULT expression|ADD GENERATED ...}
+			item := action[1:]
+			if item[0].is("COLUMN") {
+				item = item[1:]
+			}
+			col := tb.column(item[0].text)
+			if col == nil || len(item) < 3 {
+				continue
+			}
+			if item[1].is("SET") && item[2].is("DEFAULT") {
+				col.setDefault(joinTokens(item[3:]))
+			} else if item[1].is("ADD") && item[2].is("GENERATED") {
+				// ALTER COLUMN column ADD GENERATED {ALWAYS|BY DEFAULT} AS IDENTITY
+				col.auto = true
+			}
+		}
+	}
+}
+
+// replaceColumn replaces the declaration of a column by its last declaration
+func replaceColumn(tb *ddlTable, col *ddlColumn) {
+	for i, c := range tb.columns[:len(tb.columns)-1] {
+		if strings.EqualFold(c.name, col.name) {
+			tb.columns[i] = col
+			tb.columns = tb.columns[:len(tb.columns)-1]
+			return
+		}
+	}
+}
+
+// parseCreateUniqueIndex reads CREATE UNIQUE INDEX [name] ON table (column)
+func parseCreateUniqueIndex(tokens []ddlToken, byName map[string]*ddlTable) {
+	i := 0
+	for i < len(tokens) && !tokens[i].is("ON") {
+		i++
+	}
+	for i+1 < len(tokens) && tokens[i+1].is("ONLY") {
+		i++
+	}
+	name, next := qualifiedName(tokens, i+1)
+	tb, ok := byName[name]
+	if !ok {
+		return
+	}
+	for next < len(tokens) && !tokens[next].is("(") {
+		next++
+	}
+	cols, _ := parenGroup(tokens, next)
+	if names := tokenNames(cols); len(names) == 1 {
+		tb.uk = append(tb.uk, names[0])
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/iwooyun/bee/generate/templates"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// GenerateTemplates copies the built-in templates of the code generators into the templates
+// directory of the application, where they override the built-in ones once customized.
+// Only the templates whose name starts with one of names are copied, all of them if none is given.
+func GenerateTemplates(names []string, currpath string) {
+	dir := templates.Dir()
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(currpath, dir)
+	}
+
+	found := false
+	for _, name := range templates.Names() {
+		if !hasAnyPrefix(name, names) {
+			continue
+		}
+		found = true
+		utils.WriteGeneratedFile(filepath.Join(dir, filepath.FromSlash(name)), templates.Builtin(name))
+	}
+	if !found {
+		beeLogger.Log.Fatalf("No template matches '%s'. Available templates: %s", strings.Join(names, ", "), strings.Join(templates.Names(), ", "))
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,435 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/astaxie/beego/swagger"
+	"github.com/iwooyun/bee/generate/swaggergen"
+	"github.com/iwooyun/bee/generate/templates"
+	"github.com/iwooyun/bee/generate/validation"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// testData is the data the test templates are executed with
+type testData struct {
+	PkgPath    string     // import path of the application
+	Controller string     // name of the controller, e.g. ObjectController
+	Cases      []testCase // requests of the endpoints served by the controller
+}
+
+// testCase is a request of a controller test and the status code it expects
+type testCase struct {
+	Name   string            // name of the case, e.g. "Get GET /:objectId"
+	Method string            // HTTP method
+	URL    string            // URL with the example path and query parameters
+	Header map[string]string // request headers
+	Body   string            // request body, JSON or form encoded
+	Code   int               // status code of the @Success annotation
+}
+
+// GenerateTests generates a table-driven test per controller in the tests directory,
+// running every endpoint served by the routers of routerFile with example parameters
+func GenerateTests(routerFile, currpath string) {
+	if !filepath.IsAbs(routerFile) {
+		routerFile = filepath.Join(currpath, routerFile)
+	}
+	if !utils.IsExist(routerFile) {
+		beeLogger.Log.Fatalf("Router file '%s' does not exist", routerFile)
+	}
+	beeLogger.Log.Infof("Using '%s' as router file", routerFile)
+
+	doc, operations := swaggergen.AnalyseRouter(currpath, routerFile)
+	if len(operations) == 0 {
+		beeLogger.Log.Fatal("No annotated endpoint is served by the routers")
+	}
+
+	var controllers []string
+	cases := make(map[string][]testCase)
+	for _, op := range operations {
+		if _, ok := cases[op.Controller]; !ok {
+			controllers = append(controllers, op.Controller)
+		}
+		cases[op.Controller] = append(cases[op.Controller], genTestCase(doc, op))
+	}
+
+	testsPath := path.Join(currpath, "tests")
+	if !hasTestSetup(testsPath) {
+		content := templates.MustExecute("test/setup_test.go.tpl", testData{PkgPath: getPackagePath(currpath)})
+		utils.WriteGeneratedFile(path.Join(testsPath, "setup_test.go"), content)
+	}
+	for _, controller := range controllers {
+		content := templates.MustExecute("test/controller_test.go.tpl", testData{Controller: controller, Cases: cases[controller]})
+		utils.WriteGeneratedFile(path.Join(testsPath, utils.SnakeString(controller)+"_test.go"), content)
+	}
+}
+
+// hasTestSetup reports whether a test of the tests directory already initializes beego
+func hasTestSetup(testsPath string) bool {
+	files, _ := filepath.Glob(filepath.Join(testsPath, "*_test.go"))
+	for _, file := range files {
+		if filepath.Base(file) == "setup_test.go" {
+			continue
+		}
+		if dt, err := ioutil.ReadFile(file); err == nil && strings.Contains(string(dt), "beego.TestBeegoInit") {
+			return true
+		}
+	}
+	return false
+}
+
+// genTestCase returns the test case of an operation, filled with example parameters
+func genTestCase(doc swagger.Swagger, op swaggergen.APIOperation) testCase {
+	urlPath := doc.BasePath + op.Path
+	query, form := url.Values{}, url.Values{}
+	header := make(map[string]string)
+	var body string
+	for _, param := range op.Operation.Parameters {
+		rules := op.Rules[param.Name]
+		switch param.In {
+		case "path":
+			urlPath = strings.Replace(urlPath, "{"+param.Name+"}", url.PathEscape(paramExample(param, rules)), -1)
+		case "query":
+			query.Set(param.Name, paramExample(param, rules))
+		case "header":
+			header[param.Name] = paramExample(param, rules)
+		case "formData":
+			if param.Type == "file" {
+				beeLogger.Log.Warnf("File parameter '%s' of %s.%s is not sent by the test", param.Name, op.Controller, op.Func)
+				continue
+			}
+			form.Set(param.Name, paramExample(param, rules))
+		case "body":
+			dt, _ := json.Marshal(schemaExample(doc, param.Schema, param.Type, nil))
+			body = string(dt)
+			header["Content-Type"] = "application/json"
+		}
+	}
+	if len(form) > 0 && body == "" {
+		body = form.Encode()
+		header["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+	if len(query) > 0 {
+		urlPath += "?" + query.Encode()
+	}
+
+	return testCase{
+		Name:   op.Func + " " + op.Method + " " + op.Path,
+		Method: op.Method,
+		URL:    urlPath,
+		Header: header,
+		Body:   body,
+		Code:   successCode(op.Operation),
+	}
+}
+
+// successCode returns the lowest 2xx status code of the @Success annotations, 200 by default
+func successCode(op *swagger.Operation) int {
+	code := 0
+	for c := range op.Responses {
+		if n, err := strconv.Atoi(c); err == nil && n >= 200 && n < 300 && (code == 0 || n < code) {
+			code = n
+		}
+	}
+	if code == 0 {
+		return 200
+	}
+	return code
+}
+
+// paramExample returns the example value of a non-body parameter, which follows the
+// valid:"..." rules of its @Param
+func paramExample(param swagger.Parameter, rules string) string {
+	if param.Default != nil {
+		return fmt.Sprint(param.Default)
+	}
+	typ := param.Type
+	if typ == "array" && param.Items != nil {
+		typ = param.Items.Type
+	}
+	return fmt.Sprint(ruleExample(typeExample(typ, param.Format), typ, rules))
+}
+
+// schemaExample returns an example value of a body schema, definitions are resolved
+// and left out once they are being expanded to stop on recursive models
+func schemaExample(doc swagger.Swagger, schema *swagger.Schema, typ string, seen map[string]bool) interface{} {
+	if schema == nil {
+		return typeExample(typ, "")
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/definitions/")
+		def, ok := doc.Definitions[name]
+		if !ok || seen[name] {
+			return nil
+		}
+		expanding := map[string]bool{name: true}
+		for k := range seen {
+			expanding[k] = true
+		}
+		example := schemaExample(doc, &def, "", expanding)
+		// the properties without example follow the valid tag of their struct field
+		if obj, ok := example.(map[string]interface{}); ok {
+			for prop, valid := range swaggergen.ModelRules(name) {
+				p, ok := def.Properties[prop]
+				if ok && p.Example == nil && p.Default == nil && p.Ref == "" {
+					obj[prop] = ruleExample(obj[prop], p.Type, valid)
+				}
+			}
+		}
+		return example
+	}
+	switch schema.Type {
+	case "array":
+		return []interface{}{schemaExample(doc, schema.Items, "", seen)}
+	case "object", "":
+		if len(schema.Properties) == 0 && schema.Type == "" {
+			return typeExample(typ, schema.Format)
+		}
+		obj := make(map[string]interface{})
+		for name, prop := range schema.Properties {
+			obj[name] = propertyExample(doc, prop, seen)
+		}
+		return obj
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	return typeExample(schema.Type, schema.Format)
+}
+
+func propertyExample(doc swagger.Swagger, prop swagger.Propertie, seen map[string]bool) interface{} {
+	switch {
+	case prop.Example != nil:
+		return prop.Example
+	case prop.Default != nil:
+		return prop.Default
+	case prop.Ref != "":
+		return schemaExample(doc, &swagger.Schema{Ref: prop.Ref}, "", seen)
+	case prop.Type == "array" && prop.Items != nil:
+		return []interface{}{propertyExample(doc, *prop.Items, seen)}
+	case prop.Type == "object":
+		obj := make(map[string]interface{})
+		for name, p := range prop.Properties {
+			obj[name] = propertyExample(doc, p, seen)
+		}
+		return obj
+	}
+	return typeExample(prop.Type, prop.Format)
+}
+
+// typeExample returns an example value of a swagger type
+func typeExample(typ, format string) interface{} {
+	switch typ {
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		switch format {
+		case "date-time":
+			return "2006-01-02T15:04:05Z"
+		case "date":
+			return "2006-01-02"
+		}
+		return "example"
+	}
+	return "example"
+}
+
+// ruleExample returns an example of a value of the swagger type typ following rules, the
+// valid:"..." rules of a @Param or the valid tag of a struct field, starting from example:
+// the first value of an enum, the lower bound of a number, a string of the minimum size
+// or of an email or a mobile number
+func ruleExample(example interface{}, typ, rules string) interface{} {
+	if rules == "" {
+		return example
+	}
+	parsed, _ := validation.ParseRules(rules)
+	numeric := typ == "integer" || typ == "number"
+	for _, rule := range parsed {
+		arg := func(i int) string {
+			if i < len(rule.Args) {
+				return rule.Args[i]
+			}
+			return ""
+		}
+		switch rule.Name {
+		case "enum":
+			if len(rule.Args) > 0 {
+				example = typedExample(example, rule.Args[0], typ)
+			}
+		case "email":
+			example = "user@example.com"
+		case "mobile", "phone":
+			example = "13800138000"
+		case "tel":
+			example = "010-12345678"
+		case "zipcode":
+			example = "100000"
+		case "ip":
+			example = "127.0.0.1"
+		case "numeric":
+			example = "123"
+		case "min", "range":
+			if numeric {
+				example = typedExample(example, arg(0), typ)
+			} else if rule.Name == "range" {
+				example = sizedExample(example, arg(0), arg(1))
+			} else {
+				example = sizedExample(example, arg(0), "")
+			}
+		case "max":
+			if !numeric {
+				example = sizedExample(example, "", arg(0))
+			} else if max, err := strconv.ParseFloat(arg(0), 64); err == nil {
+				if n, err := strconv.ParseFloat(fmt.Sprint(example), 64); err == nil && n > max {
+					example = typedExample(example, arg(0), typ)
+				}
+			}
+		case "minsize":
+			example = sizedExample(example, arg(0), "")
+		case "maxsize":
+			example = sizedExample(example, "", arg(0))
+		case "length":
+			if len(rule.Args) == 1 {
+				example = sizedExample(example, arg(0), arg(0))
+			} else {
+				example = sizedExample(example, arg(0), arg(1))
+			}
+		}
+	}
+	return example
+}
+
+// typedExample returns the value s of a rule as a value of the swagger type typ, or example
+// if s is not a number of a numeric type
+func typedExample(example interface{}, s, typ string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+		return example
+	case "number":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+		return example
+	}
+	return s
+}
+
+// sizedExample pads or truncates the string example to a size between min and max,
+// either of which may be empty
+func sizedExample(example interface{}, min, max string) interface{} {
+	s, ok := example.(string)
+	if !ok {
+		return example
+	}
+	if n, err := strconv.Atoi(min); err == nil && len(s) < n {
+		s += strings.Repeat("x", n-len(s))
+	}
+	if n, err := strconv.Atoi(max); err == nil && n >= 0 && len(s) > n {
+		s = s[:n]
+	}
+	return s
+}
+
+var testSetupTpl = `package test
+
+import (
+	"path/filepath"
+	"runtime"
+
+	_ "{{.PkgPath}}/routers"
+
+	"github.com/astaxie/beego"
+)
+
+func init() {
+	_, file, _, _ := runtime.Caller(0)
+	apppath, _ := filepath.Abs(filepath.Dir(filepath.Join(file, ".." + string(filepath.Separator))))
+	beego.TestBeegoInit(apppath)
+}
+`
+
+var controllerTestTpl = `package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+// Test{{.Controller}} runs the endpoints of {{.Controller}} with example parameters
+// and checks the status code of their @Success annotation
+func Test{{.Controller}}(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		header map[string]string
+		body   string
+		code   int
+	}{
+		{{range .Cases}}{
+			name:   {{printf "%q" .Name}},
+			method: {{printf "%q" .Method}},
+			url:    {{printf "%q" .URL}},
+			header: map[string]string{ {{range $k, $v := .Header}}{{printf "%q" $k}}: {{printf "%q" $v}}, {{end}}},
+			body:   {{quote .Body}},
+			code:   {{.Code}},
+		},
+		{{end}}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			beego.BeeApp.Handlers.ServeHTTP(w, r)
+
+			if w.Code != tt.code {
+				t.Errorf("%s %s: status code %d, want %d\n%s", tt.method, tt.url, w.Code, tt.code, w.Body.String())
+			}
+		})
+	}
+}
+`
+
+func init() {
+	templates.Register("test/setup_test.go.tpl", testSetupTpl)
+	templates.Register("test/controller_test.go.tpl", controllerTestTpl)
+}
@@ -0,0 +1,164 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package generate
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/iwooyun/bee/config"
+	beeLogger "github.com/iwooyun/bee/logger"
+)
+
+// sqlNullTypes maps Go types to the database/sql type of their nullable columns. The
+// types of Go 1.13, sql.NullInt32 and sql.NullTime, are left out.
+var sqlNullTypes = map[string]string{
+	"bool":      "sql.NullBool",
+	"int":       "sql.NullInt64",
+	"int8":      "sql.NullInt64",
+	"int16":     "sql.NullInt64",
+	"int32":     "sql.NullInt64",
+	"int64":     "sql.NullInt64",
+	"uint":      "sql.NullInt64",
+	"uint8":     "sql.NullInt64",
+	"uint16":    "sql.NullInt64",
+	"uint32":    "sql.NullInt64",
+	"float32":   "sql.NullFloat64",
+	"float64":   "sql.NullFloat64",
+	"string":    "sql.NullString",
+	"time.Time": "*time.Time",
+}
+
+// mapColumnTypes applies the type mapping of the Beefile to the columns of tables:
+// the Go type of a column is taken from, by order of precedence
+//   - the columns overrides, by table.column
+//   - the types overrides, by SQL type
+//   - the decimal, raw_json and arrays options
+//   - the built-in mapping of the database
+//
+// Nullable columns are then made pointers or database/sql Null types, unless their
+// type comes from a columns override. Primary keys and foreign keys mapped by a
+// relation field keep their type.
+func mapColumnTypes(tables []*Table) {
+	conf := config.Conf.Appcode
+	switch conf.Nullable {
+	case "", "pointer", "sql":
+	default:
+		beeLogger.Log.Fatalf("Unknown nullable type '%s'. Use either pointer or sql.", conf.Nullable)
+	}
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if col.Tag.Pk || col.Tag.Auto {
+				continue
+			}
+			if spec, ok := conf.Columns[tb.Name+"."+col.Tag.Column]; ok {
+				col.Type, col.Import = parseGoType(spec)
+				continue
+			}
+			if spec := sqlTypeOverride(col.SQLType); spec != "" {
+				col.Type, col.Import = parseGoType(spec)
+			}
+			if col.Tag.Null && !col.Tag.Ignore {
+				col.Type = nullableType(col.Type, conf.Nullable)
+			}
+		}
+	}
+}
+
+// sqlTypeOverride returns the Go type the Beefile gives to the columns of sqlType, if any
+func sqlTypeOverride(sqlType string) string {
+	conf := config.Conf.Appcode
+	if spec, ok := conf.Types[sqlType]; ok {
+		return spec
+	}
+	switch {
+	case conf.Decimal != "" && (isSQLDecimal(sqlType) || sqlType == "numeric" || sqlType == "money"):
+		return conf.Decimal
+	case conf.RawJSON && (sqlType == "json" || sqlType == "jsonb"):
+		return "encoding/json.RawMessage"
+	case conf.Arrays && strings.HasSuffix(sqlType, "[]"):
+		elem := strings.TrimSuffix(sqlType, "[]")
+		if spec, ok := conf.Types[elem]; ok {
+			return "[]" + spec
+		}
+		if goType, ok := typeMappingPostgres[elem]; ok {
+			return "[]" + goType
+		}
+	}
+	return ""
+}
+
+// nullableType returns the Go type of a nullable column of type goType. Slices,
+// i.e. json.RawMessage and arrays, and pointers are already nullable
+func nullableType(goType, nullable string) string {
+	if strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "*") || goType == "json.RawMessage" {
+		return goType
+	}
+	switch nullable {
+	case "pointer":
+		return "*" + goType
+	case "sql":
+		if t, ok := sqlNullTypes[goType]; ok {
+			return t
+		}
+	}
+	return goType
+}
+
+// parseGoType splits a Go type of the Beefile into the type used in the source code
+// and its import path, i.e. decimal.Decimal and github.com/shopspring/decimal
+// for github.com/shopspring/decimal.Decimal. Built-in types have no import path.
+func parseGoType(spec string) (goType, importPath string) {
+	prefix := ""
+	for {
+		if strings.HasPrefix(spec, "*") {
+			prefix, spec = prefix+"*", spec[1:]
+		} else if strings.HasPrefix(spec, "[]") {
+			prefix, spec = prefix+"[]", spec[2:]
+		} else {
+			break
+		}
+	}
+	i := strings.LastIndex(spec, ".")
+	if i < 0 {
+		return prefix + spec, ""
+	}
+	importPath = spec[:i]
+	return prefix + path.Base(importPath) + spec[i:], importPath
+}
+
+// Imports returns the import paths of the types of the columns, sorted
+func (tb *Table) Imports() (imports []string) {
+	seen := make(map[string]bool)
+	add := func(importPath string) {
+		if importPath != "" && !seen[importPath] {
+			seen[importPath] = true
+			imports = append(imports, importPath)
+		}
+	}
+	for _, col := range tb.Columns {
+		switch {
+		case strings.HasPrefix(col.Type, "sql.Null"):
+			add("database/sql")
+		case col.Import != "":
+			add(col.Import)
+		case strings.Contains(col.Type, "time.Time"):
+			add("time")
+		}
+	}
+	sort.Strings(imports)
+	return
+}
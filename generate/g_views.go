@@ -15,61 +15,20 @@
 package generate
 
 import (
-	"fmt"
-	"os"
 	"path"
 
 	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
 	"github.com/iwooyun/bee/utils"
 )
 
 // recipe
 // admin/recipe
 func GenerateView(viewpath, currpath string) {
-	w := colors.NewColorWriter(os.Stdout)
-
 	beeLogger.Log.Info("Generating view...")
 
 	absViewPath := path.Join(currpath, "views", viewpath)
-	err := os.MkdirAll(absViewPath, os.ModePerm)
-	if err != nil {
-		beeLogger.Log.Fatalf("Could not create '%s' view: %s", viewpath, err)
-	}
-
-	cfile := path.Join(absViewPath, "index.tpl")
-	if f, err := os.OpenFile(cfile, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		f.WriteString(cfile)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", cfile, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create view file: %s", err)
-	}
-
-	cfile = path.Join(absViewPath, "show.tpl")
-	if f, err := os.OpenFile(cfile, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		f.WriteString(cfile)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", cfile, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create view file: %s", err)
-	}
-
-	cfile = path.Join(absViewPath, "create.tpl")
-	if f, err := os.OpenFile(cfile, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		f.WriteString(cfile)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", cfile, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create view file: %s", err)
-	}
-
-	cfile = path.Join(absViewPath, "edit.tpl")
-	if f, err := os.OpenFile(cfile, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
-		defer utils.CloseFile(f)
-		f.WriteString(cfile)
-		fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", cfile, "\x1b[0m")
-	} else {
-		beeLogger.Log.Fatalf("Could not create view file: %s", err)
+	for _, name := range []string{"index.tpl", "show.tpl", "create.tpl", "edit.tpl"} {
+		cfile := path.Join(absViewPath, name)
+		utils.WriteGeneratedFile(cfile, cfile)
 	}
 }
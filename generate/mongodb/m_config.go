@@ -1,13 +0,0 @@
-// @Time : 2019/9/3 10:37
-// @Author : duanqiangwen
-// @File : config
-// @Software: GoLand
-package mongodb
-
-const (
-	MongoHost     = ""
-	MongoPort     = ""
-	MongoDatabase = ""
-	MongoUsername = ""
-	MongoPassword = ""
-)
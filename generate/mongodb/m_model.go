@@ -5,10 +5,11 @@
 package mongodb
 
 import (
+	"errors"
 	"fmt"
-	beeLogger "github.com/iwooyun/bee/logger"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"time"
 )
 
 var dbClient *mgo.Database
@@ -20,31 +21,52 @@ func FindProjectByHost(host string) *Project {
 	return result
 }
 
-func FindLocationMaxVersion(projectId bson.ObjectId) *SwaggerLocation {
+// FindLocations returns every swagger location of a project, rolled back ones included.
+// Versions are sorted by the caller since they cannot be compared as strings.
+func FindLocations(projectId bson.ObjectId) ([]SwaggerLocation, error) {
 	c := dbClient.C("swagger_location")
-	result := &SwaggerLocation{}
-	_ = c.Find(bson.M{"project_id": projectId, "is_deleted": IsDeletedFalse}).Sort("-version").One(result)
-	return result
+	var result []SwaggerLocation
+	err := c.Find(bson.M{"project_id": projectId}).Sort("created_at").All(&result)
+	return result, err
 }
 
-func Insert(db string, docs ...interface{}) {
+// DeleteLocations flags the given versions of a project as deleted
+func DeleteLocations(projectId bson.ObjectId, versions []string) error {
+	c := dbClient.C("swagger_location")
+	_, err := c.UpdateAll(
+		bson.M{"project_id": projectId, "version": bson.M{"$in": versions}},
+		bson.M{"$set": bson.M{"is_deleted": IsDeletedTrue, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func Insert(db string, docs ...interface{}) error {
 	c := dbClient.C(db)
-	err := c.Insert(docs...)
-	if err != nil {
-		panic(err)
-	}
+	return c.Insert(docs...)
 }
 
-func init() {
-	url := fmt.Sprintf("mongodb://%s:%s@%s:%s/%s",
-		MongoUsername, MongoPassword, MongoHost, MongoPort, MongoDatabase)
+// Connect dials the MongoDB doc center once, later calls reuse the session
+func Connect(host, port, database, username, password string) error {
+	if dbClient != nil {
+		return nil
+	}
+	if host == "" || database == "" {
+		return errors.New("mongodb host and database must be configured")
+	}
+	addr := host
+	if port != "" {
+		addr += ":" + port
+	}
+	if username != "" {
+		addr = fmt.Sprintf("%s:%s@%s", username, password, addr)
+	}
+	url := fmt.Sprintf("mongodb://%s/%s", addr, database)
 	session, err := mgo.Dial(url)
 	if err != nil {
-		beeLogger.Log.Fatalf("mongodb connect failed! err => %s", err)
-		return
+		return fmt.Errorf("mongodb connect failed! err => %s", err)
 	}
 
-	// defer session.Close()
 	session.SetMode(mgo.Monotonic, true)
-	dbClient = session.DB(MongoDatabase)
+	dbClient = session.DB(database)
+	return nil
 }
@@ -12,7 +12,7 @@ import (
 const (
 	IsDeletedFalse = 0
 	IsDeletedTrue  = 1
-	DefaultVersion = "1.0"
+	DefaultVersion = "1.0.0"
 )
 
 type SwaggerLocation struct {
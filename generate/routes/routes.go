@@ -0,0 +1,651 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package routes analyses the routers package of a Beego application
+// and builds the table of the controllers it registers.
+package routes
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	bu "github.com/iwooyun/bee/utils"
+)
+
+// Kinds of controller registrations
+const (
+	// KindInclude serves the @router annotations of the controller:
+	// beego.Include, NSInclude or Namespace.Include
+	KindInclude = "include"
+	// KindRouter serves the controller on a URL pattern:
+	// beego.Router, NSRouter or Namespace.Router
+	KindRouter = "router"
+	// KindAutoRouter serves every method of the controller on /<controller>/<method>:
+	// beego.AutoRouter, NSAutoRouter or Namespace.AutoRouter
+	KindAutoRouter = "autorouter"
+)
+
+// Route is a controller registration found in the routers package
+type Route struct {
+	Kind       string
+	Prefix     string            // URL prefix of the enclosing namespaces
+	Pattern    string            // URL pattern of a KindRouter route
+	Mappings   map[string]string // HTTP method in lower case, or *, => controller method of a KindRouter route
+	PkgPath    string            // Import path of the controller package
+	Controller string            // Name of the controller type
+	Pos        token.Position    // Position of the registration
+}
+
+// Table lists the controllers registered by the routers package
+type Table struct {
+	BasePath string // Prefix of the first namespace
+	Routes   []*Route
+}
+
+// Packages returns the import paths of the controller packages, in order of appearance
+func (t *Table) Packages() []string {
+	seen := make(map[string]bool)
+	var pkgs []string
+	for _, r := range t.Routes {
+		if !seen[r.PkgPath] {
+			seen[r.PkgPath] = true
+			pkgs = append(pkgs, r.PkgPath)
+		}
+	}
+	return pkgs
+}
+
+// Annotation is the @router annotation of a controller method
+type Annotation struct {
+	Path    string   // Empty if the method is not annotated
+	Methods []string // HTTP methods in upper case
+}
+
+// Endpoint is a controller method served by a route
+type Endpoint struct {
+	Path   string // Full URL pattern, i.e. /v1/user/:uid
+	Method string // HTTP method in upper case
+	Func   string // Controller method
+}
+
+// RESTful methods served by a KindRouter route without mappings
+var restMethods = []string{"Get", "Post", "Put", "Patch", "Delete", "Head", "Options"}
+
+// Controller methods which are never served by a KindAutoRouter route
+var reservedFuncs = map[string]bool{
+	"Init": true, "Prepare": true, "Finish": true, "URLMapping": true, "HandlerFunc": true,
+}
+
+// Endpoints returns the endpoints served by the route,
+// given the methods of its controller along with their @router annotations
+func (r *Route) Endpoints(funcs map[string]Annotation) []Endpoint {
+	var endpoints []Endpoint
+	add := func(p string, methods []string, fn string) {
+		if len(methods) == 0 {
+			methods = []string{"GET"}
+		}
+		for _, m := range methods {
+			endpoints = append(endpoints, Endpoint{Path: joinPath(r.Prefix, p), Method: m, Func: fn})
+		}
+	}
+
+	switch r.Kind {
+	case KindInclude:
+		for _, fn := range sortedFuncs(funcs) {
+			if a := funcs[fn]; a.Path != "" {
+				add(a.Path, a.Methods, fn)
+			}
+		}
+	case KindRouter:
+		if len(r.Mappings) == 0 {
+			for _, fn := range restMethods {
+				if _, ok := funcs[fn]; ok {
+					add(r.Pattern, []string{strings.ToUpper(fn)}, fn)
+				}
+			}
+			break
+		}
+		var methods []string
+		for m := range r.Mappings {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		for _, m := range methods {
+			fn := r.Mappings[m]
+			if m == "*" {
+				// Any method is served, the annotated ones are documented
+				add(r.Pattern, funcs[fn].Methods, fn)
+			} else {
+				add(r.Pattern, []string{strings.ToUpper(m)}, fn)
+			}
+		}
+	case KindAutoRouter:
+		ctrl := strings.ToLower(strings.TrimSuffix(r.Controller, "Controller"))
+		for _, fn := range sortedFuncs(funcs) {
+			if reservedFuncs[fn] || !ast.IsExported(fn) {
+				continue
+			}
+			add(path.Join("/", ctrl, strings.ToLower(fn)), funcs[fn].Methods, fn)
+		}
+	}
+	return endpoints
+}
+
+func sortedFuncs(funcs map[string]Annotation) []string {
+	names := make([]string, 0, len(funcs))
+	for fn := range funcs {
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func joinPath(prefix, p string) string {
+	if prefix == "" {
+		return p
+	}
+	if p == "" {
+		return prefix
+	}
+	// beego concatenates the prefix and the pattern, a "/" pattern keeps its trailing slash
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// ParseRouterAnnotation parses the value of a @router annotation, i.e. "/:uid [get,post]"
+func ParseRouterAnnotation(s string) Annotation {
+	elements := strings.SplitN(strings.TrimSpace(s), " ", 2)
+	a := Annotation{Path: elements[0]}
+	if len(elements) == 2 && strings.TrimSpace(elements[1]) != "" {
+		methods := strings.SplitN(strings.TrimSpace(elements[1]), " ", 2)[0]
+		for _, m := range strings.Split(strings.Trim(methods, "[]"), ",") {
+			if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+				a.Methods = append(a.Methods, m)
+			}
+		}
+	}
+	if len(a.Methods) == 0 {
+		a.Methods = []string{"GET"}
+	}
+	return a
+}
+
+// Analyse parses every file of the routers package found in dir and returns its route table.
+// Routes registered with beego.Router, beego.Include, beego.AutoRouter and namespaces, nested
+// at any level and possibly built in helper functions or variables, are resolved.
+func Analyse(dir string) (*Table, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		name := info.Name()
+		return !info.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analyser{
+		fset:      fset,
+		table:     &Table{},
+		files:     make(map[ast.Node]*ast.File),
+		imports:   make(map[*ast.File]map[string]string),
+		funcs:     make(map[string]*ast.FuncDecl),
+		values:    make(map[string]ast.Expr),
+		seen:      make(map[*ast.CallExpr]bool),
+		expanding: make(map[*ast.FuncDecl]bool),
+	}
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		var names []string
+		for name := range pkg.Files {
+			names = append(names, name)
+		}
+		// router.go holds the main namespace, other files come in alphabetical order
+		sort.Slice(names, func(i, j int) bool {
+			if (path.Base(names[i]) == "router.go") != (path.Base(names[j]) == "router.go") {
+				return path.Base(names[i]) == "router.go"
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			files = append(files, pkg.Files[name])
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go file found in '%s'", dir)
+	}
+	for _, f := range files {
+		a.index(f)
+	}
+
+	// Registrations are statements of any function
+	var assigned []*ast.CallExpr
+	for _, f := range files {
+		a.file = f
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch s := n.(type) {
+				case *ast.ExprStmt:
+					if call, ok := s.X.(*ast.CallExpr); ok {
+						a.statement(call)
+					}
+					return false
+				case *ast.AssignStmt:
+					for _, rhs := range s.Rhs {
+						if call, ok := rhs.(*ast.CallExpr); ok && a.isBeegoFunc(call, "NewNamespace") {
+							assigned = append(assigned, call)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	// Namespaces which were assigned but never added explicitly are registered
+	// as well, as beego.NewNamespace is commonly followed by beego.AddNamespace.
+	for _, call := range assigned {
+		if !a.seen[call] {
+			a.file = a.files[call]
+			a.namespace(call, "")
+		}
+	}
+	return a.table, nil
+}
+
+type analyser struct {
+	fset      *token.FileSet
+	table     *Table
+	file      *ast.File                       // File being analysed
+	files     map[ast.Node]*ast.File          // Top level declarations and namespaces => file
+	imports   map[*ast.File]map[string]string // File => import name => import path
+	funcs     map[string]*ast.FuncDecl        // Package functions
+	values    map[string]ast.Expr             // Package variables and constants
+	seen      map[*ast.CallExpr]bool          // Analysed beego.NewNamespace calls
+	expanding map[*ast.FuncDecl]bool          // Helper functions being expanded
+}
+
+// index records the imports and the package level declarations of a file
+func (a *analyser) index(f *ast.File) {
+	imports := make(map[string]string)
+	for _, im := range f.Imports {
+		p, _ := strconv.Unquote(im.Path.Value)
+		name := path.Base(p)
+		if im.Name != nil {
+			name = im.Name.Name
+		}
+		imports[name] = p
+	}
+	a.imports[f] = imports
+
+	for _, d := range f.Decls {
+		switch decl := d.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				a.funcs[decl.Name.Name] = decl
+				a.files[decl] = f
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						a.values[name.Name] = vs.Values[i]
+						a.files[vs.Values[i]] = f
+					}
+				}
+			}
+		}
+	}
+
+	// Remember the file of every beego.NewNamespace call
+	ast.Inspect(f, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			a.files[call] = f
+		}
+		return true
+	})
+}
+
+func (a *analyser) warnf(node ast.Node, format string, args ...interface{}) {
+	beeLogger.Log.Warnf("%s: %s", a.fset.Position(node.Pos()), fmt.Sprintf(format, args...))
+}
+
+// beegoFunc returns the name of the Beego function called, or an empty string
+func (a *analyser) beegoFunc(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if x, ok := fun.X.(*ast.Ident); ok && a.imports[a.file][x.Name] == bu.BeegoImportPath && x.Obj == nil {
+			return fun.Sel.Name
+		}
+	case *ast.Ident:
+		if a.imports[a.file]["."] == bu.BeegoImportPath && fun.Obj == nil {
+			return fun.Name
+		}
+	}
+	return ""
+}
+
+func (a *analyser) isBeegoFunc(call *ast.CallExpr, name string) bool {
+	return a.beegoFunc(call) == name
+}
+
+// statement analyses a call made as a statement
+func (a *analyser) statement(call *ast.CallExpr) {
+	switch a.beegoFunc(call) {
+	case "Router":
+		a.router(call, "")
+		return
+	case "Include":
+		a.include(call.Args, "", call)
+		return
+	case "AutoRouter":
+		a.autoRouter(call, "")
+		return
+	case "AddNamespace":
+		for _, arg := range call.Args {
+			a.addNamespace(arg, "")
+		}
+		return
+	case "NewNamespace":
+		a.namespace(call, "")
+		return
+	}
+
+	// Methods of a namespace, i.e. ns.Include(...)
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	ns := a.resolveNamespace(sel.X)
+	if ns == nil {
+		return
+	}
+	prefix := a.stringValue(ns.Args[0])
+	switch sel.Sel.Name {
+	case "Router":
+		a.router(call, prefix)
+	case "Include":
+		a.include(call.Args, prefix, call)
+	case "AutoRouter":
+		a.autoRouter(call, prefix)
+	case "Namespace":
+		for _, arg := range call.Args {
+			a.addNamespace(arg, prefix)
+		}
+	}
+}
+
+// addNamespace analyses a namespace expression, i.e. a variable, a helper function call or beego.NewNamespace
+func (a *analyser) addNamespace(expr ast.Expr, prefix string) {
+	if ns := a.resolveNamespace(expr); ns != nil {
+		a.namespace(ns, prefix)
+	} else {
+		a.warnf(expr, "Couldn't resolve the namespace")
+	}
+}
+
+// resolveNamespace returns the beego.NewNamespace call an expression evaluates to
+func (a *analyser) resolveNamespace(expr ast.Expr) (ns *ast.CallExpr) {
+	a.resolve(expr, func(e ast.Expr) bool {
+		if call, ok := e.(*ast.CallExpr); ok && a.isBeegoFunc(call, "NewNamespace") && len(call.Args) > 0 {
+			ns = call
+			return true
+		}
+		return false
+	})
+	return
+}
+
+// resolve follows variables and helper function calls until found returns true
+func (a *analyser) resolve(expr ast.Expr, found func(ast.Expr) bool) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return a.resolve(e.X, found)
+	case *ast.Ident:
+		if value := a.identValue(e); value != nil {
+			return a.withFile(value, func() bool { return a.resolve(value, found) })
+		}
+		return false
+	case *ast.CallExpr:
+		if found(e) {
+			return true
+		}
+		ident, ok := e.Fun.(*ast.Ident)
+		if !ok || a.beegoFunc(e) != "" {
+			return false
+		}
+		fn, ok := a.funcs[ident.Name]
+		if !ok || a.expanding[fn] {
+			return false
+		}
+		a.expanding[fn] = true
+		defer delete(a.expanding, fn)
+		ok = false
+		a.withFile(fn, func() bool {
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if _, isLit := n.(*ast.FuncLit); isLit || ok {
+					return false
+				}
+				if ret, isRet := n.(*ast.ReturnStmt); isRet && len(ret.Results) > 0 {
+					ok = a.resolve(ret.Results[0], found)
+				}
+				return true
+			})
+			return ok
+		})
+		return ok
+	}
+	return found(expr)
+}
+
+// identValue returns the value assigned to a variable or constant
+func (a *analyser) identValue(ident *ast.Ident) ast.Expr {
+	if ident.Obj != nil {
+		switch decl := ident.Obj.Decl.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range decl.Lhs {
+				if l, ok := lhs.(*ast.Ident); ok && l.Name == ident.Name && i < len(decl.Rhs) {
+					return decl.Rhs[i]
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range decl.Names {
+				if name.Name == ident.Name && i < len(decl.Values) {
+					return decl.Values[i]
+				}
+			}
+		}
+		return nil
+	}
+	// Declared in another file of the package
+	return a.values[ident.Name]
+}
+
+// withFile runs fn with the file of node as the current one, when node is a package level declaration
+func (a *analyser) withFile(node ast.Node, fn func() bool) bool {
+	f, ok := a.files[node]
+	if !ok {
+		return fn()
+	}
+	current := a.file
+	a.file = f
+	defer func() { a.file = current }()
+	return fn()
+}
+
+// namespace analyses the parameters of beego.NewNamespace
+func (a *analyser) namespace(call *ast.CallExpr, parent string) {
+	if a.seen[call] || len(call.Args) == 0 {
+		return
+	}
+	a.seen[call] = true
+	a.withFile(call, func() bool {
+		prefix := joinPath(parent, a.stringValue(call.Args[0]))
+		if a.table.BasePath == "" && parent == "" {
+			a.table.BasePath = prefix
+		}
+		for _, arg := range call.Args[1:] {
+			a.link(arg, prefix)
+		}
+		return true
+	})
+}
+
+// link analyses a namespace parameter: NSInclude, NSRouter, NSAutoRouter or a nested NSNamespace
+func (a *analyser) link(expr ast.Expr, prefix string) {
+	resolved := a.resolve(expr, func(e ast.Expr) bool {
+		switch e := e.(type) {
+		case *ast.CompositeLit:
+			// A slice of links returned by a helper function, passed with ...
+			for _, elt := range e.Elts {
+				a.link(elt, prefix)
+			}
+			return true
+		case *ast.CallExpr:
+			switch a.beegoFunc(e) {
+			case "NSInclude":
+				a.include(e.Args, prefix, e)
+			case "NSRouter":
+				a.router(e, prefix)
+			case "NSAutoRouter":
+				a.autoRouter(e, prefix)
+			case "NSNamespace":
+				if len(e.Args) > 0 {
+					sub := joinPath(prefix, a.stringValue(e.Args[0]))
+					for _, arg := range e.Args[1:] {
+						a.link(arg, sub)
+					}
+				}
+			case "":
+				return false
+			}
+			// NSCond, NSBefore, NSGet... do not register controllers
+			return true
+		}
+		return false
+	})
+	if !resolved {
+		a.warnf(expr, "Couldn't resolve the namespace parameter")
+	}
+}
+
+func (a *analyser) include(ctrls []ast.Expr, prefix string, at ast.Node) {
+	for _, ctrl := range ctrls {
+		a.addRoute(&Route{Kind: KindInclude, Prefix: prefix}, ctrl, at)
+	}
+}
+
+// router analyses Router(pattern, controller, mappings...)
+func (a *analyser) router(call *ast.CallExpr, prefix string) {
+	if len(call.Args) < 2 {
+		return
+	}
+	route := &Route{
+		Kind:     KindRouter,
+		Prefix:   prefix,
+		Pattern:  a.stringValue(call.Args[0]),
+		Mappings: make(map[string]string),
+	}
+	for _, arg := range call.Args[2:] {
+		// i.e. "get:GetUser;post,put:SaveUser"
+		for _, mapping := range strings.Split(a.stringValue(arg), ";") {
+			parts := strings.SplitN(mapping, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			for _, m := range strings.Split(parts[0], ",") {
+				route.Mappings[strings.ToLower(strings.TrimSpace(m))] = strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	a.addRoute(route, call.Args[1], call)
+}
+
+func (a *analyser) autoRouter(call *ast.CallExpr, prefix string) {
+	if len(call.Args) > 0 {
+		a.addRoute(&Route{Kind: KindAutoRouter, Prefix: prefix}, call.Args[0], call)
+	}
+}
+
+func (a *analyser) addRoute(route *Route, ctrl ast.Expr, at ast.Node) {
+	resolved := a.resolve(ctrl, func(e ast.Expr) bool {
+		var typ ast.Expr
+		switch e := e.(type) {
+		case *ast.UnaryExpr:
+			if lit, ok := e.X.(*ast.CompositeLit); ok {
+				typ = lit.Type
+			}
+		case *ast.CallExpr:
+			// new(controllers.UserController)
+			if ident, ok := e.Fun.(*ast.Ident); ok && ident.Name == "new" && len(e.Args) == 1 {
+				typ = e.Args[0]
+			}
+		}
+		sel, ok := typ.(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok || a.imports[a.file][x.Name] == "" {
+			return false
+		}
+		route.PkgPath = a.imports[a.file][x.Name]
+		route.Controller = sel.Sel.Name
+		return true
+	})
+	if !resolved {
+		a.warnf(ctrl, "Couldn't determine the controller type")
+		return
+	}
+	route.Pos = a.fset.Position(at.Pos())
+	a.table.Routes = append(a.table.Routes, route)
+}
+
+// stringValue evaluates a constant string expression
+func (a *analyser) stringValue(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if s, err := strconv.Unquote(e.Value); err == nil {
+			return s
+		}
+	case *ast.ParenExpr:
+		return a.stringValue(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			return a.stringValue(e.X) + a.stringValue(e.Y)
+		}
+	case *ast.Ident:
+		if value := a.identValue(e); value != nil {
+			var s string
+			a.withFile(value, func() bool { s = a.stringValue(value); return true })
+			return s
+		}
+	}
+	a.warnf(expr, "Couldn't evaluate the string")
+	return ""
+}
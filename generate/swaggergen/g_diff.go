@@ -0,0 +1,555 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package swaggergen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/astaxie/beego/swagger"
+	bu "github.com/iwooyun/bee/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Kinds of changes between two swagger documents
+const (
+	PathAdded           = "path-added"
+	PathRemoved         = "path-removed"
+	MethodAdded         = "method-added"
+	MethodRemoved       = "method-removed"
+	ParamAdded          = "param-added"
+	ParamRemoved        = "param-removed"
+	ParamRequired       = "param-required"
+	ParamOptional       = "param-optional"
+	TypeChanged         = "type-changed"
+	FieldAdded          = "field-added"
+	FieldRemoved        = "field-removed"
+	EnumValuesAdded     = "enum-values-added"
+	EnumValuesRemoved   = "enum-values-removed"
+	ResponseAdded       = "response-added"
+	ResponseRemoved     = "response-removed"
+	OperationDeprecated = "operation-deprecated"
+)
+
+// DocChange is a single difference between two swagger documents
+type DocChange struct {
+	Breaking  bool   `json:"breaking"`
+	Kind      string `json:"kind"`
+	Operation string `json:"operation"`          // i.e. "GET /v1/user/{uid}"
+	Location  string `json:"location,omitempty"` // i.e. "query parameter 'name'"
+	Message   string `json:"message"`
+}
+
+// DocDiff lists the differences between two swagger documents
+type DocDiff struct {
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	Changes []DocChange `json:"changes"`
+}
+
+// Breaking returns the number of breaking changes
+func (d *DocDiff) Breaking() (n int) {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			n++
+		}
+	}
+	return
+}
+
+// WriteReport writes a human readable report of the changes
+func (d *DocDiff) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "Comparing %s with %s\n", d.Old, d.New)
+	for _, breaking := range []bool{true, false} {
+		for _, c := range d.Changes {
+			if c.Breaking != breaking {
+				continue
+			}
+			level := "non-breaking"
+			if c.Breaking {
+				level = "BREAKING"
+			}
+			where := c.Operation
+			if c.Location != "" {
+				where += ", " + c.Location
+			}
+			fmt.Fprintf(w, "  %-12s  %s: %s\n", level, where, c.Message)
+		}
+	}
+	fmt.Fprintf(w, "%d breaking, %d non-breaking change(s)\n", d.Breaking(), len(d.Changes)-d.Breaking())
+}
+
+// WriteJSON writes the changes as JSON
+func (d *DocDiff) WriteJSON(w io.Writer) error {
+	if d.Changes == nil {
+		d.Changes = []DocChange{}
+	}
+	dt, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", dt)
+	return err
+}
+
+// ParseDoc parses a swagger document, either in JSON or in YAML
+func ParseDoc(name string, dt []byte) (*swagger.Swagger, error) {
+	unmarshal := json.Unmarshal
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		unmarshal = yaml.Unmarshal
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi" yaml:"openapi"`
+	}
+	if err := unmarshal(dt, &spec); err == nil && spec.OpenAPI != "" {
+		return nil, fmt.Errorf("'%s' is an OpenAPI %s document, only Swagger 2.0 documents can be compared", name, spec.OpenAPI)
+	}
+	doc := &swagger.Swagger{}
+	if err := unmarshal(dt, doc); err != nil {
+		return nil, fmt.Errorf("could not parse the swagger doc '%s': %s", name, err)
+	}
+	return doc, nil
+}
+
+// ReadDoc reads a swagger document file
+func ReadDoc(file string) (*swagger.Swagger, error) {
+	dt, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDoc(file, dt)
+}
+
+// LatestDoc designates the current version of the doc store
+const LatestDoc = "latest"
+
+// LoadDoc loads a swagger document from a file or, if there is no such file,
+// from the configured doc store, ref being a published version or "latest".
+// It returns the document along with a name describing where it comes from.
+func LoadDoc(curpath, ref string) (*swagger.Swagger, string, error) {
+	file := ref
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(curpath, file)
+	}
+	if ref != LatestDoc && bu.IsExist(file) {
+		doc, err := ReadDoc(file)
+		return doc, ref, err
+	}
+
+	store, err := NewDocHistory(curpath)
+	if err != nil {
+		return nil, "", fmt.Errorf("'%s' is not a file and cannot be loaded from the doc store: %s", ref, err)
+	}
+	version := ref
+	if ref == LatestDoc {
+		versions, err := store.History()
+		if err != nil {
+			return nil, "", err
+		}
+		latest := latestVersion(versions)
+		if latest == nil {
+			return nil, "", errors.New("no swagger doc has been published yet")
+		}
+		version = latest.Version
+	}
+	dt, err := store.Show(version)
+	if err != nil {
+		return nil, "", err
+	}
+	name := "version " + version
+	doc, err := ParseDoc(name, dt)
+	return doc, name, err
+}
+
+// DiffDocs compares two swagger documents and classifies their differences
+// as breaking or non-breaking for the clients of the API.
+func DiffDocs(oldDoc, newDoc *swagger.Swagger) *DocDiff {
+	d := &docDiffer{
+		diff:    &DocDiff{},
+		oldDefs: oldDoc.Definitions,
+		newDefs: newDoc.Definitions,
+	}
+	oldPaths := docPaths(oldDoc)
+	newPaths := docPaths(newDoc)
+
+	for _, p := range sortedKeys(oldPaths, newPaths) {
+		oldItem, inOld := oldPaths[p]
+		newItem, inNew := newPaths[p]
+		switch {
+		case !inNew:
+			d.add(true, PathRemoved, p, "", "path removed")
+			continue
+		case !inOld:
+			d.add(false, PathAdded, p, "", "path added")
+			continue
+		}
+
+		oldOps := itemOperations(oldItem)
+		newOps := itemOperations(newItem)
+		for _, method := range httpMethods {
+			oldOp, newOp := oldOps[method], newOps[method]
+			operation := strings.ToUpper(method) + " " + p
+			switch {
+			case oldOp == nil && newOp == nil:
+			case newOp == nil:
+				d.add(true, MethodRemoved, operation, "", "method removed")
+			case oldOp == nil:
+				d.add(false, MethodAdded, operation, "", "method added")
+			default:
+				d.diffOperation(operation, oldOp, newOp)
+			}
+		}
+	}
+	return d.diff
+}
+
+var httpMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+func docPaths(doc *swagger.Swagger) map[string]*swagger.Item {
+	paths := make(map[string]*swagger.Item, len(doc.Paths))
+	for p, item := range doc.Paths {
+		if item != nil {
+			paths[path.Join("/", doc.BasePath, p)] = item
+		}
+	}
+	return paths
+}
+
+func itemOperations(item *swagger.Item) map[string]*swagger.Operation {
+	return map[string]*swagger.Operation{
+		"get":     item.Get,
+		"post":    item.Post,
+		"put":     item.Put,
+		"patch":   item.Patch,
+		"delete":  item.Delete,
+		"head":    item.Head,
+		"options": item.Options,
+	}
+}
+
+func sortedKeys(maps ...map[string]*swagger.Item) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type docDiffer struct {
+	diff             *DocDiff
+	oldDefs, newDefs map[string]swagger.Schema
+}
+
+func (d *docDiffer) add(breaking bool, kind, operation, location, message string) {
+	d.diff.Changes = append(d.diff.Changes, DocChange{
+		Breaking:  breaking,
+		Kind:      kind,
+		Operation: operation,
+		Location:  location,
+		Message:   message,
+	})
+}
+
+func (d *docDiffer) diffOperation(operation string, oldOp, newOp *swagger.Operation) {
+	if !oldOp.Deprecated && newOp.Deprecated {
+		d.add(false, OperationDeprecated, operation, "", "operation deprecated")
+	}
+
+	// Parameters
+	oldParams := make(map[string]swagger.Parameter)
+	for _, p := range oldOp.Parameters {
+		oldParams[p.In+":"+p.Name] = p
+	}
+	newParams := make(map[string]swagger.Parameter)
+	for _, p := range newOp.Parameters {
+		newParams[p.In+":"+p.Name] = p
+	}
+	for _, p := range oldOp.Parameters {
+		if _, ok := newParams[p.In+":"+p.Name]; !ok {
+			d.add(false, ParamRemoved, operation, paramLocation(p), "parameter removed")
+		}
+	}
+	for _, np := range newOp.Parameters {
+		location := paramLocation(np)
+		op, ok := oldParams[np.In+":"+np.Name]
+		if !ok {
+			if np.Required {
+				d.add(true, ParamRequired, operation, location, "new required parameter")
+			} else {
+				d.add(false, ParamAdded, operation, location, "new optional parameter")
+			}
+			continue
+		}
+		if !op.Required && np.Required {
+			d.add(true, ParamRequired, operation, location, "parameter is now required")
+		} else if op.Required && !np.Required {
+			d.add(false, ParamOptional, operation, location, "parameter is now optional")
+		}
+		if np.Schema != nil || op.Schema != nil {
+			d.diffSchema(operation, location, d.fromSchema(op.Schema, d.oldDefs, nil), d.fromSchema(np.Schema, d.newDefs, nil), true)
+		} else if o, n := paramType(op), paramType(np); o != n {
+			d.add(true, TypeChanged, operation, location, fmt.Sprintf("type changed from %s to %s", o, n))
+		}
+	}
+
+	// Responses
+	for _, code := range sortedResponseCodes(oldOp.Responses, newOp.Responses) {
+		or, inOld := oldOp.Responses[code]
+		nr, inNew := newOp.Responses[code]
+		location := "response " + code
+		switch {
+		case !inNew:
+			d.add(true, ResponseRemoved, operation, location, "response removed")
+		case !inOld:
+			d.add(false, ResponseAdded, operation, location, "response added")
+		default:
+			d.diffSchema(operation, location, d.fromSchema(or.Schema, d.oldDefs, nil), d.fromSchema(nr.Schema, d.newDefs, nil), false)
+		}
+	}
+}
+
+func paramLocation(p swagger.Parameter) string {
+	return fmt.Sprintf("%s parameter '%s'", p.In, p.Name)
+}
+
+func paramType(p swagger.Parameter) string {
+	t := typeName(p.Type, p.Format)
+	if p.Items != nil {
+		t += " of " + typeName(p.Items.Type, p.Items.Format)
+	}
+	return t
+}
+
+func typeName(typ, format string) string {
+	if typ == "" {
+		typ = "object"
+	}
+	if format != "" {
+		return typ + "(" + format + ")"
+	}
+	return typ
+}
+
+func sortedResponseCodes(maps ...map[string]swagger.Response) []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				codes = append(codes, k)
+			}
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// docSchema is a schema or a property with its references resolved
+type docSchema struct {
+	Type       string
+	Format     string
+	Items      *docSchema
+	Properties map[string]*docSchema
+	Required   map[string]bool
+	Enum       []interface{}
+}
+
+func (s *docSchema) typeName() string {
+	if s.Type == "" && len(s.Properties) > 0 {
+		return "object"
+	}
+	t := typeName(s.Type, s.Format)
+	if s.Items != nil {
+		t += " of " + s.Items.typeName()
+	}
+	return t
+}
+
+// fromSchema resolves a schema. Definitions which are being resolved already are not
+// expanded again so that recursive models do not loop forever.
+func (d *docDiffer) fromSchema(s *swagger.Schema, defs map[string]swagger.Schema, resolving map[string]bool) *docSchema {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		return d.fromRef(s.Ref, defs, resolving)
+	}
+	ds := &docSchema{
+		Type:     s.Type,
+		Format:   s.Format,
+		Enum:     s.Enum,
+		Required: make(map[string]bool),
+	}
+	if s.Items != nil {
+		ds.Items = d.fromSchema(s.Items, defs, resolving)
+	}
+	for _, r := range s.Required {
+		ds.Required[r] = true
+	}
+	if len(s.Properties) > 0 {
+		ds.Properties = make(map[string]*docSchema)
+		for name, p := range s.Properties {
+			ds.Properties[name] = d.fromProperty(p, defs, resolving)
+		}
+	}
+	return ds
+}
+
+func (d *docDiffer) fromProperty(p swagger.Propertie, defs map[string]swagger.Schema, resolving map[string]bool) *docSchema {
+	if p.Ref != "" {
+		return d.fromRef(p.Ref, defs, resolving)
+	}
+	ds := &docSchema{
+		Type:     p.Type,
+		Format:   p.Format,
+		Required: make(map[string]bool),
+	}
+	if p.Items != nil {
+		ds.Items = d.fromProperty(*p.Items, defs, resolving)
+	}
+	for _, r := range p.Required {
+		ds.Required[r] = true
+	}
+	if len(p.Properties) > 0 {
+		ds.Properties = make(map[string]*docSchema)
+		for name, sub := range p.Properties {
+			ds.Properties[name] = d.fromProperty(sub, defs, resolving)
+		}
+	}
+	return ds
+}
+
+func (d *docDiffer) fromRef(ref string, defs map[string]swagger.Schema, resolving map[string]bool) *docSchema {
+	name := strings.TrimPrefix(ref, "#/definitions/")
+	def, ok := defs[name]
+	if !ok || resolving[name] {
+		return &docSchema{Type: "object", Required: make(map[string]bool)}
+	}
+	nested := map[string]bool{name: true}
+	for k := range resolving {
+		nested[k] = true
+	}
+	return d.fromSchema(&def, defs, nested)
+}
+
+// diffSchema compares the schemas of a request body (request is true) or of a response.
+// Removing fields only breaks the clients reading responses while newly required
+// fields only break the clients sending requests.
+func (d *docDiffer) diffSchema(operation, location string, oldSchema, newSchema *docSchema, request bool) {
+	switch {
+	case oldSchema == nil && newSchema == nil:
+		return
+	case oldSchema == nil:
+		d.add(request, TypeChanged, operation, location, "schema added")
+		return
+	case newSchema == nil:
+		d.add(!request, TypeChanged, operation, location, "schema removed")
+		return
+	}
+
+	if o, n := oldSchema.typeName(), newSchema.typeName(); o != n {
+		d.add(true, TypeChanged, operation, location, fmt.Sprintf("type changed from %s to %s", o, n))
+		return
+	}
+	d.diffEnum(operation, location, oldSchema.Enum, newSchema.Enum, request)
+	if oldSchema.Items != nil && newSchema.Items != nil {
+		d.diffSchema(operation, location+" items", oldSchema.Items, newSchema.Items, request)
+	}
+
+	var names []string
+	for name := range oldSchema.Properties {
+		names = append(names, name)
+	}
+	for name := range newSchema.Properties {
+		if _, ok := oldSchema.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fieldLocation := fmt.Sprintf("%s field '%s'", location, name)
+		op, inOld := oldSchema.Properties[name]
+		np, inNew := newSchema.Properties[name]
+		switch {
+		case !inNew:
+			d.add(!request, FieldRemoved, operation, fieldLocation, "field removed")
+		case !inOld:
+			if request && newSchema.Required[name] {
+				d.add(true, ParamRequired, operation, fieldLocation, "new required field")
+			} else {
+				d.add(false, FieldAdded, operation, fieldLocation, "field added")
+			}
+		default:
+			if request && !oldSchema.Required[name] && newSchema.Required[name] {
+				d.add(true, ParamRequired, operation, fieldLocation, "field is now required")
+			}
+			d.diffSchema(operation, fieldLocation, op, np, request)
+		}
+	}
+}
+
+// diffEnum compares enum values. Values which are not accepted anymore break the
+// requests using them, and new values may not be understood by the response readers.
+// An empty enum does not restrict the values at all.
+func (d *docDiffer) diffEnum(operation, location string, oldEnum, newEnum []interface{}, request bool) {
+	if len(oldEnum) == 0 && len(newEnum) == 0 {
+		return
+	}
+	if removed := enumDifference(oldEnum, newEnum); len(removed) > 0 {
+		breaking := len(newEnum) == 0
+		if request {
+			breaking = !breaking
+		}
+		d.add(breaking, EnumValuesRemoved, operation, location, "enum values removed: "+strings.Join(removed, ", "))
+	}
+	if added := enumDifference(newEnum, oldEnum); len(added) > 0 {
+		breaking := len(oldEnum) > 0
+		if request {
+			breaking = !breaking
+		}
+		d.add(breaking, EnumValuesAdded, operation, location, "enum values added: "+strings.Join(added, ", "))
+	}
+}
+
+// enumDifference returns the values of a which are not in b
+func enumDifference(a, b []interface{}) (values []string) {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[fmt.Sprint(v)] = true
+	}
+	for _, v := range a {
+		if s := fmt.Sprint(v); !in[s] {
+			values = append(values, s)
+		}
+	}
+	return
+}
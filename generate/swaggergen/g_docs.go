@@ -20,11 +20,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/iwooyun/bee/generate/mongodb"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/yaml.v2"
 	"os"
 	"path"
@@ -35,9 +33,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 	"unicode"
 
+	"github.com/iwooyun/bee/generate/routes"
 	beeLogger "github.com/iwooyun/bee/logger"
 	bu "github.com/iwooyun/bee/utils"
 	"github.com/astaxie/beego/swagger"
@@ -60,12 +58,39 @@ const (
 
 var pkgCache map[string]struct{} // pkg:controller:function:comments comments: key:value
 var controllerComments map[string]map[string]string
-var importlist map[string]string
-var controllerList map[string]map[string]*swagger.Item // controllername Paths items
+var controllerFuncs map[string]map[string]*swagger.Operation  // controller => method => operation
+var controllerRouters map[string]map[string]routes.Annotation // controller => method => @router annotation
+var controllerRules map[string]map[string]map[string]string   // controller => method => parameter => valid:"..." rules
+var modelRules map[string]map[string]string                   // model => property => valid tag
 var modelsList map[string]map[string]swagger.Schema
 var rootapi swagger.Swagger
+var apiOperations []APIOperation
 var astPkgs []*ast.Package
 
+// APIOperation is an operation of the swagger doc along with the controller method serving it
+type APIOperation struct {
+	Controller string // name of the controller type, i.e. UserController
+	Func       string // name of the controller method
+	Method     string // HTTP method
+	Path       string // path relative to the basePath of the doc, i.e. /user/{uid}
+	Operation  *swagger.Operation
+	Rules      map[string]string // valid:"..." rules of the parameters, by name
+}
+
+// ModelRules returns the valid tags of the properties of the model of a definition,
+// i.e. models.User, by property name
+func ModelRules(definition string) map[string]string {
+	return modelRules[definition]
+}
+
+// OpenAPIVersion is the major version of the specification the documents are written in:
+// either 2 (Swagger 2.0, the default) or 3 (OpenAPI 3.0).
+var OpenAPIVersion string
+
+// VersionBump is the part of the latest published version to increment
+// when publishing the document: either major, minor or patch.
+var VersionBump string
+
 // refer to builtin.go
 var basicTypes = map[string]string{
 	"bool":       "boolean:",
@@ -100,8 +125,10 @@ var stdlibObject = map[string]string{
 func init() {
 	pkgCache = make(map[string]struct{})
 	controllerComments = make(map[string]map[string]string)
-	importlist = make(map[string]string)
-	controllerList = make(map[string]map[string]*swagger.Item)
+	controllerFuncs = make(map[string]map[string]*swagger.Operation)
+	controllerRouters = make(map[string]map[string]routes.Annotation)
+	controllerRules = make(map[string]map[string]map[string]string)
+	modelRules = make(map[string]map[string]string)
 	modelsList = make(map[string]map[string]swagger.Schema)
 	astPkgs = make([]*ast.Package, 0)
 }
@@ -161,11 +188,66 @@ func parsePackageFromDir(path string) error {
 
 // GenerateDocs generates documentations for a given path.
 func GenerateDocs(curpath string) {
+	switch OpenAPIVersion {
+	case "", "2", "3":
+	default:
+		beeLogger.Log.Fatalf("Unknown OpenAPI version '%s'. Must be either 2 or 3", OpenAPIVersion)
+	}
+
+	AnalyseRouter(curpath, filepath.Join(curpath, "routers", "router.go"))
+
+	os.Mkdir(path.Join(curpath, "swagger"), 0755)
+	writeDoc(path.Join(curpath, "swagger", "swagger"), rootapi)
+	// swagger.json stays a Swagger 2.0 document, which bee docs diff and the doc stores read
+	if OpenAPIVersion == "3" {
+		writeDoc(path.Join(curpath, "swagger", "openapi"), ToOpenAPI(rootapi))
+	}
+
+	publisher, err := NewDocPublisher(curpath)
+	if err != nil {
+		beeLogger.Log.Fatalf("%s", err)
+	}
+	if publisher == nil {
+		return
+	}
+	if err := publishDocs(publisher); err != nil {
+		beeLogger.Log.Errorf("Could not publish the swagger doc: %s", err)
+	}
+}
+
+// writeDoc writes doc to the files name.json and name.yml
+func writeDoc(name string, doc interface{}) {
+	fd, err := os.Create(name + ".json")
+	if err != nil {
+		panic(err)
+	}
+	fdyml, err := os.Create(name + ".yml")
+	if err != nil {
+		panic(err)
+	}
+	defer fdyml.Close()
+	defer fd.Close()
+	dt, err := json.MarshalIndent(doc, "", "    ")
+	dtyml, erryml := yaml.Marshal(doc)
+	if err != nil || erryml != nil {
+		panic(err)
+	}
+	_, err = fd.Write(dt)
+	_, erryml = fdyml.Write(dtyml)
+	if err != nil || erryml != nil {
+		panic(err)
+	}
+}
+
+// AnalyseRouter builds the swagger doc of the API served by the routers of the
+// directory of routerFile, whose comments hold the general API information.
+// The operations are returned along with the controller methods serving them.
+func AnalyseRouter(curpath, routerFile string) (swagger.Swagger, []APIOperation) {
 	fset := token.NewFileSet()
 
-	f, err := parser.ParseFile(fset, filepath.Join(curpath, "routers", "router.go"), nil, parser.ParseComments)
+	f, err := parser.ParseFile(fset, routerFile, nil, parser.ParseComments)
 	if err != nil {
-		beeLogger.Log.Fatalf("Error while parsing router.go: %s", err)
+		beeLogger.Log.Fatalf("Error while parsing %s: %s", filepath.Base(routerFile), err)
 	}
 
 	rootapi.Infos = swagger.Information{}
@@ -264,144 +346,16 @@ func GenerateDocs(curpath string) {
 			beeLogger.Log.Fatal("Title and host can not be empty")
 		}
 	}
-	// Analyse controller package
-	for _, im := range f.Imports {
-		localName := ""
-		if im.Name != nil {
-			localName = im.Name.Name
-		}
-		analyseControllerPkg(path.Join(curpath, "vendor"), localName, im.Path.Value)
-	}
-	for _, d := range f.Decls {
-		switch specDecl := d.(type) {
-		case *ast.FuncDecl:
-			for _, l := range specDecl.Body.List {
-				switch stmt := l.(type) {
-				case *ast.AssignStmt:
-					for _, l := range stmt.Rhs {
-						if v, ok := l.(*ast.CallExpr); ok {
-							// Analyze NewNamespace, it will return version and the subfunction
-							selExpr, selOK := v.Fun.(*ast.SelectorExpr)
-							if !selOK || selExpr.Sel.Name != "NewNamespace" {
-								continue
-							}
-							version, params := analyseNewNamespace(v)
-							if rootapi.BasePath == "" && version != "" {
-								rootapi.BasePath = version
-							}
-							for _, p := range params {
-								switch pp := p.(type) {
-								case *ast.CallExpr:
-									var controllerName string
-									if selname := pp.Fun.(*ast.SelectorExpr).Sel.String(); selname == "NSNamespace" {
-										s, params := analyseNewNamespace(pp)
-										for _, sp := range params {
-											switch pp := sp.(type) {
-											case *ast.CallExpr:
-												if pp.Fun.(*ast.SelectorExpr).Sel.String() == "NSInclude" {
-													controllerName = analyseNSInclude(s, pp)
-													if v, ok := controllerComments[controllerName]; ok {
-														rootapi.Tags = append(rootapi.Tags, swagger.Tag{
-															Name:        v["tagName"],
-															Description: v["description"],
-														})
-													}
-												}
-											}
-										}
-									} else if selname == "NSInclude" {
-										controllerName = analyseNSInclude("", pp)
-										if v, ok := controllerComments[controllerName]; ok {
-											rootapi.Tags = append(rootapi.Tags, swagger.Tag{
-												Name:        v["tagName"],
-												Description: v["description"],
-											})
-										}
-									}
-								}
-							}
-						}
-
-					}
-				}
-			}
-		}
-	}
-
-	os.Mkdir(path.Join(curpath, "swagger"), 0755)
-	fd, err := os.Create(path.Join(curpath, "swagger", "swagger.json"))
-	if err != nil {
-		panic(err)
-	}
-	fdyml, err := os.Create(path.Join(curpath, "swagger", "swagger.yml"))
-	if err != nil {
-		panic(err)
-	}
-	defer fdyml.Close()
-	defer fd.Close()
-	dt, err := json.MarshalIndent(rootapi, "", "    ")
-	dtyml, erryml := yaml.Marshal(rootapi)
-	if err != nil || erryml != nil {
-		panic(err)
-	}
-	_, err = fd.Write(dt)
-	_, erryml = fdyml.Write(dtyml)
-	if err != nil || erryml != nil {
-		panic(err)
-	}
-
-	beeLogger.Log.Warn("Do you want to push update to remote doc? [Yes|No] ")
-	if bu.AskForConfirmation() {
-		saveMongoDB(rootapi)
-	}
-}
-
-func saveMongoDB(rootapi swagger.Swagger) {
-	var (
-		err       error
-		hashValue string
-		version   string
-	)
-	dt, err := json.Marshal(rootapi)
+	table, err := routes.Analyse(filepath.Dir(routerFile))
 	if err != nil {
-		panic(err)
-	}
-
-	timestamp := time.Now()
-	project := mongodb.FindProjectByHost(rootapi.Host)
-	if !project.Id_.Valid() {
-		project.Id_ = bson.NewObjectId()
-		project.Name = rootapi.Infos.Title
-		project.Host = rootapi.Host
-		project.CreatedAt = timestamp
-		project.UpdateAt = timestamp
-		mongodb.Insert("center_project", project)
-	}
-
-	hashValue = MD5(string(dt))
-	location := mongodb.FindLocationMaxVersion(project.Id_)
-	if location.Id_ != "" {
-		version = location.Version
-		if hashValue == location.Hash {
-			return
-		}
+		beeLogger.Log.Fatalf("Error while analysing the routers: %s", err)
 	}
-
-	locationJson, _ := json.Marshal(rootapi)
-	var locationMap map[string]interface{}
-	err = json.Unmarshal(locationJson, &locationMap)
-	if err != nil {
-		panic(err)
+	// Analyse controller package
+	for _, pkgpath := range table.Packages() {
+		analyseControllerPkg(path.Join(curpath, "vendor"), pkgpath)
 	}
-
-	mongodb.Insert("swagger_location", mongodb.SwaggerLocation{
-		ProjectId: project.Id_,
-		Version:   IncrVersion(version),
-		Hash:      MD5(string(dt)),
-		Location:  locationMap,
-		CreatedAt: timestamp,
-		UpdateAt:  timestamp,
-	})
+	analyseRoutes(table)
+	return rootapi, apiOperations
 }
 
 func MD5(str string) string {
@@ -410,122 +364,100 @@ func MD5(str string) string {
 	return hex.EncodeToString(h.Sum(nil))
 }
 
-func IncrVersion(version string) string {
-	if version == "" {
-		return mongodb.DefaultVersion
+// analyseRoutes adds the operations of the controllers served by the routers to rootapi
+func analyseRoutes(table *routes.Table) {
+	type routeEndpoint struct {
+		routes.Endpoint
+		controller string
+		cname      string
+	}
+	var endpoints []routeEndpoint
+	tagged := make(map[string]bool)
+	for _, route := range table.Routes {
+		cname := route.PkgPath + route.Controller
+		for _, e := range route.Endpoints(controllerRouters[cname]) {
+			endpoints = append(endpoints, routeEndpoint{e, route.Controller, cname})
+		}
+		if v, ok := controllerComments[cname]; ok && !tagged[cname] {
+			tagged[cname] = true
+			rootapi.Tags = append(rootapi.Tags, swagger.Tag{
+				Name:        v["tagName"],
+				Description: v["description"],
+			})
+		}
 	}
 
-	ver, _ := strconv.ParseFloat(version, 64)
-	return strconv.FormatFloat(ver+0.1, 'f', 1, 64)
-}
+	// Paths are relative to the prefix of the first namespace,
+	// unless some routes are served outside of it
+	basePath := strings.TrimSuffix(table.BasePath, "/")
+	for _, e := range endpoints {
+		if e.Path != basePath && !strings.HasPrefix(e.Path, basePath+"/") {
+			basePath = ""
+			break
+		}
+	}
+	rootapi.BasePath = basePath
 
-// analyseNewNamespace returns version and the others params
-func analyseNewNamespace(ce *ast.CallExpr) (first string, others []ast.Expr) {
-	for i, p := range ce.Args {
-		if i == 0 {
-			switch pp := p.(type) {
-			case *ast.BasicLit:
-				first = strings.Trim(pp.Value, `"`)
-			}
+	for _, e := range endpoints {
+		opts, ok := controllerFuncs[e.cname][e.Func]
+		if !ok {
 			continue
 		}
-		others = append(others, p)
-	}
-	return
-}
+		opts.Tags = []string{controllerComments[e.cname]["tagName"]}
 
-func analyseNSInclude(baseurl string, ce *ast.CallExpr) string {
-	cname := ""
-	for _, p := range ce.Args {
-		var x *ast.SelectorExpr
-		var p1 interface{} = p
-		if ident, ok := p1.(*ast.Ident); ok {
-			if assign, ok := ident.Obj.Decl.(*ast.AssignStmt); ok {
-				if len(assign.Rhs) > 0 {
-					p1 = assign.Rhs[0].(*ast.UnaryExpr)
-				}
-			}
+		if len(rootapi.Paths) == 0 {
+			rootapi.Paths = make(map[string]*swagger.Item)
 		}
-		if _, ok := p1.(*ast.UnaryExpr); ok {
-			x = p1.(*ast.UnaryExpr).X.(*ast.CompositeLit).Type.(*ast.SelectorExpr)
-		} else {
-			beeLogger.Log.Warnf("Couldn't determine type\n")
-			continue
+		rt := urlReplace(strings.TrimPrefix(e.Path, basePath))
+		if rt == "" {
+			rt = "/"
 		}
-		if v, ok := importlist[fmt.Sprint(x.X)]; ok {
-			cname = v + x.Sel.Name
+		item, ok := rootapi.Paths[rt]
+		if !ok {
+			item = &swagger.Item{}
+			rootapi.Paths[rt] = item
 		}
-		if apis, ok := controllerList[cname]; ok {
-			for rt, item := range apis {
-				tag := controllerComments[cname]["tagName"]
-				if baseurl != "" {
-					rt = baseurl + rt
-					// tag = strings.Trim(baseurl, "/")
-				}
-				if item.Get != nil {
-					item.Get.Tags = []string{tag}
-				}
-				if item.Post != nil {
-					item.Post.Tags = []string{tag}
-				}
-				if item.Put != nil {
-					item.Put.Tags = []string{tag}
-				}
-				if item.Patch != nil {
-					item.Patch.Tags = []string{tag}
-				}
-				if item.Head != nil {
-					item.Head.Tags = []string{tag}
-				}
-				if item.Delete != nil {
-					item.Delete.Tags = []string{tag}
-				}
-				if item.Options != nil {
-					item.Options.Tags = []string{tag}
-				}
-				if len(rootapi.Paths) == 0 {
-					rootapi.Paths = make(map[string]*swagger.Item)
-				}
-				rt = urlReplace(rt)
-				rootapi.Paths[rt] = item
-			}
+		switch e.Method {
+		case "GET":
+			item.Get = opts
+		case "POST":
+			item.Post = opts
+		case "PUT":
+			item.Put = opts
+		case "PATCH":
+			item.Patch = opts
+		case "DELETE":
+			item.Delete = opts
+		case "HEAD":
+			item.Head = opts
+		case "OPTIONS":
+			item.Options = opts
 		}
+		apiOperations = append(apiOperations, APIOperation{
+			Controller: e.controller,
+			Func:       e.Func,
+			Method:     e.Method,
+			Path:       rt,
+			Operation:  opts,
+			Rules:      controllerRules[e.cname][e.Func],
+		})
 	}
-	return cname
 }
 
-func analyseControllerPkg(vendorPath, localName, pkgpath string) {
-	pkgpath = strings.Trim(pkgpath, "\"")
+func analyseControllerPkg(vendorPath, pkgpath string) {
 	if isSystemPackage(pkgpath) {
 		return
 	}
-	if pkgpath == "github.com/astaxie/beego" {
+	if pkgpath == bu.BeegoImportPath {
 		return
 	}
-	if localName != "" {
-		importlist[localName] = pkgpath
-	} else {
-		pps := strings.Split(pkgpath, "/")
-		importlist[pps[len(pps)-1]] = pkgpath
-	}
-	gopaths := bu.GetGOPATHs()
-	if len(gopaths) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
 	pkgRealpath := ""
 
 	wg, _ := filepath.EvalSymlinks(filepath.Join(vendorPath, pkgpath))
 	if utils.FileExists(wg) {
 		pkgRealpath = wg
 	} else {
-		wgopath := gopaths
-		for _, wg := range wgopath {
-			wg, _ = filepath.EvalSymlinks(filepath.Join(wg, "src", pkgpath))
-			if utils.FileExists(wg) {
-				pkgRealpath = wg
-				break
-			}
-		}
+		pkgRealpath = bu.ResolvePackageDir(filepath.Dir(vendorPath), pkgpath)
 	}
 	if pkgRealpath != "" {
 		if _, ok := pkgCache[pkgpath]; ok {
@@ -533,7 +465,7 @@ func analyseControllerPkg(vendorPath, localName, pkgpath string) {
 		}
 		pkgCache[pkgpath] = struct{}{}
 	} else {
-		beeLogger.Log.Fatalf("Package '%s' does not exist in the GOPATH or vendor path", pkgpath)
+		beeLogger.Log.Fatalf("Package '%s' does not exist in the module, GOPATH or vendor path", pkgpath)
 	}
 
 	fileSet := token.NewFileSet()
@@ -623,6 +555,7 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 	funcName := f.Name.String()
 	comments := f.Doc
 	funcParamMap := buildParamMap(f.Type.Params)
+	rules := make(map[string]string)
 	// TODO: resultMap := buildParamMap(f.Type.Results)
 	if comments != nil && comments.List != nil {
 		opts.Consumes = append(opts.Consumes, "application/x-www-form-urlencoded")
@@ -695,11 +628,24 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 			} else if strings.HasPrefix(t, "@Param") {
 				para := swagger.Parameter{}
 				p := getparams(strings.TrimSpace(t[len("@Param "):]))
+				// The valid:"..." rules are for the validators of 'bee generate validation'
+				// and the examples of 'bee generate test'
+				paramRules := ""
+				for i := range p {
+					if strings.HasPrefix(p[i], "valid:") {
+						paramRules = strings.TrimPrefix(p[i], "valid:")
+						p = append(p[:i:i], p[i+1:]...)
+						break
+					}
+				}
 				if len(p) < 4 {
 					beeLogger.Log.Fatal(controllerName + "_" + funcName + "'s comments @Param should have at least 4 params")
 				}
 				paramNames := strings.SplitN(p[0], "=>", 2)
 				para.Name = paramNames[0]
+				if paramRules != "" {
+					rules[para.Name] = paramRules
+				}
 				funcParamName := para.Name
 				if len(paramNames) > 1 {
 					funcParamName = paramNames[1]
@@ -818,51 +764,35 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 		}
 	}
 
-	if routerPath != "" {
-		// Go over function parameters which were not mapped and create swagger params for them
-		for name, typ := range funcParamMap {
-			para := swagger.Parameter{}
-			para.Name = name
-			setParamType(&para, typ, pkgpath, controllerName)
-			if paramInPath(name, routerPath) {
-				para.In = "path"
-			} else {
-				para.In = "query"
-			}
-			opts.Parameters = append(opts.Parameters, para)
-		}
+	if comments == nil {
+		return nil
+	}
 
-		var item *swagger.Item
-		if itemList, ok := controllerList[pkgpath+controllerName]; ok {
-			if it, ok := itemList[routerPath]; !ok {
-				item = &swagger.Item{}
-			} else {
-				item = it
-			}
+	// Go over function parameters which were not mapped and create swagger params for them
+	for name, typ := range funcParamMap {
+		para := swagger.Parameter{}
+		para.Name = name
+		setParamType(&para, typ, pkgpath, controllerName)
+		if paramInPath(name, routerPath) {
+			para.In = "path"
 		} else {
-			controllerList[pkgpath+controllerName] = make(map[string]*swagger.Item)
-			item = &swagger.Item{}
+			para.In = "query"
 		}
-		for _, hm := range strings.Split(HTTPMethod, ",") {
-			switch hm {
-			case "GET":
-				item.Get = &opts
-			case "POST":
-				item.Post = &opts
-			case "PUT":
-				item.Put = &opts
-			case "PATCH":
-				item.Patch = &opts
-			case "DELETE":
-				item.Delete = &opts
-			case "HEAD":
-				item.Head = &opts
-			case "OPTIONS":
-				item.Options = &opts
-			}
-		}
-		controllerList[pkgpath+controllerName][routerPath] = item
+		opts.Parameters = append(opts.Parameters, para)
+	}
+
+	if _, ok := controllerFuncs[pkgpath+controllerName]; !ok {
+		controllerFuncs[pkgpath+controllerName] = make(map[string]*swagger.Operation)
+		controllerRouters[pkgpath+controllerName] = make(map[string]routes.Annotation)
+		controllerRules[pkgpath+controllerName] = make(map[string]map[string]string)
 	}
+	controllerFuncs[pkgpath+controllerName][funcName] = &opts
+	controllerRules[pkgpath+controllerName][funcName] = rules
+	annotation := routes.Annotation{}
+	if routerPath != "" {
+		annotation = routes.Annotation{Path: routerPath, Methods: strings.Split(HTTPMethod, ",")}
+	}
+	controllerRouters[pkgpath+controllerName][funcName] = annotation
 	return nil
 }
 
@@ -1266,6 +1196,9 @@ func parseStruct(st *ast.StructType, k string, m *swagger.Schema, realTypes *[]s
 					if example := stag.Get("example"); example != "" && !isObject && !isSlice {
 						mp.Example = str2RealType(example, realType)
 					}
+					if valid := stag.Get("valid"); valid != "" {
+						addModelRules(packageName+"."+k, map[string]string{name: valid})
+					}
 
 					m.Properties[name] = mp
 				}
@@ -1299,6 +1232,7 @@ func parseStruct(st *ast.StructType, k string, m *swagger.Schema, realTypes *[]s
 							for nameOfObj, obj := range fl.Scope.Objects {
 								if obj.Name == fmt.Sprint(field.Type) {
 									parseObject(obj, nameOfObj, nm, realTypes, astPkgs, pkg.Name)
+									addModelRules(packageName+"."+k, modelRules[pkg.Name+"."+nameOfObj])
 								}
 							}
 						}
@@ -1313,6 +1247,19 @@ func parseStruct(st *ast.StructType, k string, m *swagger.Schema, realTypes *[]s
 	}
 }
 
+// addModelRules adds the valid tags of properties to those of the model
+func addModelRules(model string, rules map[string]string) {
+	if len(rules) == 0 {
+		return
+	}
+	if _, ok := modelRules[model]; !ok {
+		modelRules[model] = make(map[string]string)
+	}
+	for name, valid := range rules {
+		modelRules[model][name] = valid
+	}
+}
+
 func typeAnalyser(f *ast.Field) (isSlice bool, realType, swaggerType string) {
 	if arr, ok := f.Type.(*ast.ArrayType); ok {
 		if isBasicType(fmt.Sprint(arr.Elt)) {
@@ -0,0 +1,423 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package swaggergen
+
+import (
+	"path"
+	"strings"
+
+	"github.com/astaxie/beego/swagger"
+)
+
+const aurlencoded = "application/x-www-form-urlencoded"
+
+// OpenAPI is the root document of an OpenAPI 3.0 specification,
+// see https://spec.openapis.org/oas/v3.0.3
+type OpenAPI struct {
+	OpenAPI      string                `json:"openapi" yaml:"openapi"`
+	Info         swagger.Information   `json:"info" yaml:"info"`
+	Servers      []OAServer            `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths        map[string]*OAPath    `json:"paths" yaml:"paths"`
+	Components   OAComponents          `json:"components,omitempty" yaml:"components,omitempty"`
+	Security     []map[string][]string `json:"security,omitempty" yaml:"security,omitempty"`
+	Tags         []swagger.Tag         `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ExternalDocs *swagger.ExternalDocs `json:"externalDocs,omitempty" yaml:"externalDocs,omitempty"`
+}
+
+// OAServer is a server hosting the API
+type OAServer struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// OAPath describes the operations available on a single path
+type OAPath struct {
+	Get     *OAOperation `json:"get,omitempty" yaml:"get,omitempty"`
+	Put     *OAOperation `json:"put,omitempty" yaml:"put,omitempty"`
+	Post    *OAOperation `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete  *OAOperation `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options *OAOperation `json:"options,omitempty" yaml:"options,omitempty"`
+	Head    *OAOperation `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch   *OAOperation `json:"patch,omitempty" yaml:"patch,omitempty"`
+}
+
+// OAOperation describes a single API operation on a path
+type OAOperation struct {
+	Tags        []string              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Parameters  []OAParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *OARequestBody        `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]OAResponse `json:"responses" yaml:"responses"`
+	Security    []map[string][]string `json:"security,omitempty" yaml:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// OAParameter describes a path, query, header or cookie parameter
+type OAParameter struct {
+	Name        string    `json:"name" yaml:"name"`
+	In          string    `json:"in" yaml:"in"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool      `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *OASchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// OARequestBody describes the body of a request
+type OARequestBody struct {
+	Description string                 `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool                   `json:"required,omitempty" yaml:"required,omitempty"`
+	Content     map[string]OAMediaType `json:"content" yaml:"content"`
+}
+
+// OAResponse describes a single response of an operation
+type OAResponse struct {
+	Description string                 `json:"description" yaml:"description"`
+	Content     map[string]OAMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// OAMediaType holds the schema of a content type
+type OAMediaType struct {
+	Schema *OASchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// OASchema defines an input or output data type
+type OASchema struct {
+	Ref                  string               `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Title                string               `json:"title,omitempty" yaml:"title,omitempty"`
+	Description          string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Type                 string               `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string               `json:"format,omitempty" yaml:"format,omitempty"`
+	Required             []string             `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *OASchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*OASchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *OASchema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Enum                 []interface{}        `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Default              interface{}          `json:"default,omitempty" yaml:"default,omitempty"`
+	Example              interface{}          `json:"example,omitempty" yaml:"example,omitempty"`
+	ReadOnly             bool                 `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+}
+
+// OAComponents holds the reusable objects of the specification
+type OAComponents struct {
+	Schemas         map[string]*OASchema        `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	SecuritySchemes map[string]OASecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+}
+
+// OASecurityScheme defines a security scheme usable by the operations
+type OASecurityScheme struct {
+	Type        string   `json:"type" yaml:"type"` // Either apiKey, http or oauth2
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Name        string   `json:"name,omitempty" yaml:"name,omitempty"`
+	In          string   `json:"in,omitempty" yaml:"in,omitempty"`
+	Scheme      string   `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	Flows       *OAFlows `json:"flows,omitempty" yaml:"flows,omitempty"`
+}
+
+// OAFlows lists the supported OAuth2 flows
+type OAFlows struct {
+	Implicit          *OAFlow `json:"implicit,omitempty" yaml:"implicit,omitempty"`
+	Password          *OAFlow `json:"password,omitempty" yaml:"password,omitempty"`
+	ClientCredentials *OAFlow `json:"clientCredentials,omitempty" yaml:"clientCredentials,omitempty"`
+	AuthorizationCode *OAFlow `json:"authorizationCode,omitempty" yaml:"authorizationCode,omitempty"`
+}
+
+// OAFlow describes an OAuth2 flow
+type OAFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty" yaml:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty" yaml:"tokenUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes" yaml:"scopes"`
+}
+
+// ToOpenAPI converts a Swagger 2.0 document into an OpenAPI 3.0 one
+func ToOpenAPI(doc swagger.Swagger) *OpenAPI {
+	oa := &OpenAPI{
+		OpenAPI:      "3.0.3",
+		Info:         doc.Infos,
+		Servers:      openAPIServers(doc),
+		Paths:        make(map[string]*OAPath, len(doc.Paths)),
+		Security:     doc.Security,
+		Tags:         doc.Tags,
+		ExternalDocs: doc.ExternalDocs,
+	}
+
+	if len(doc.Definitions) > 0 {
+		oa.Components.Schemas = make(map[string]*OASchema, len(doc.Definitions))
+		for name, def := range doc.Definitions {
+			def := def
+			oa.Components.Schemas[name] = openAPISchema(&def)
+		}
+	}
+	if len(doc.SecurityDefinitions) > 0 {
+		oa.Components.SecuritySchemes = make(map[string]OASecurityScheme, len(doc.SecurityDefinitions))
+		for name, sec := range doc.SecurityDefinitions {
+			oa.Components.SecuritySchemes[name] = openAPISecurityScheme(sec)
+		}
+	}
+
+	for p, item := range doc.Paths {
+		if item == nil {
+			continue
+		}
+		oa.Paths[p] = &OAPath{
+			Get:     openAPIOperation(item.Get, doc),
+			Put:     openAPIOperation(item.Put, doc),
+			Post:    openAPIOperation(item.Post, doc),
+			Delete:  openAPIOperation(item.Delete, doc),
+			Options: openAPIOperation(item.Options, doc),
+			Head:    openAPIOperation(item.Head, doc),
+			Patch:   openAPIOperation(item.Patch, doc),
+		}
+	}
+	return oa
+}
+
+// openAPIServers builds the server URLs out of @Schemes, @Host and the base path
+func openAPIServers(doc swagger.Swagger) []OAServer {
+	basePath := doc.BasePath
+	if basePath != "" {
+		basePath = path.Join("/", basePath)
+	}
+	if doc.Host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []OAServer{{URL: basePath}}
+	}
+	schemes := doc.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http"}
+	}
+	servers := make([]OAServer, 0, len(schemes))
+	for _, scheme := range schemes {
+		servers = append(servers, OAServer{URL: strings.TrimSpace(scheme) + "://" + doc.Host + basePath})
+	}
+	return servers
+}
+
+func openAPIOperation(op *swagger.Operation, doc swagger.Swagger) *OAOperation {
+	if op == nil {
+		return nil
+	}
+	oop := &OAOperation{
+		Tags:        op.Tags,
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.OperationID,
+		Responses:   make(map[string]OAResponse, len(op.Responses)),
+		Security:    op.Security,
+		Deprecated:  op.Deprecated,
+	}
+	consumes := op.Consumes
+	if len(consumes) == 0 {
+		consumes = doc.Consumes
+	}
+	produces := op.Produces
+	if len(produces) == 0 {
+		produces = doc.Produces
+	}
+
+	var form *OASchema
+	isMultipart := false
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "body":
+			oop.RequestBody = &OARequestBody{
+				Description: p.Description,
+				Required:    p.Required,
+				Content:     openAPIContent(bodyTypes(consumes), openAPISchema(p.Schema)),
+			}
+		case "formData":
+			if form == nil {
+				form = &OASchema{Type: astTypeObject, Properties: make(map[string]*OASchema)}
+			}
+			schema := openAPIParamSchema(p)
+			schema.Description = p.Description
+			form.Properties[p.Name] = schema
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+			isMultipart = isMultipart || p.Type == "file"
+		default:
+			oop.Parameters = append(oop.Parameters, OAParameter{
+				Name:        p.Name,
+				In:          p.In,
+				Description: p.Description,
+				Required:    p.Required || p.In == "path",
+				Schema:      openAPIParamSchema(p),
+			})
+		}
+	}
+	if form != nil && oop.RequestBody == nil {
+		oop.RequestBody = &OARequestBody{
+			Required: len(form.Required) > 0,
+			Content:  openAPIContent(formTypes(consumes, isMultipart), form),
+		}
+	}
+
+	for code, rs := range op.Responses {
+		ors := OAResponse{Description: rs.Description}
+		if rs.Schema != nil {
+			ors.Content = openAPIContent(produces, openAPISchema(rs.Schema))
+		}
+		oop.Responses[code] = ors
+	}
+	return oop
+}
+
+func openAPIContent(contentTypes []string, schema *OASchema) map[string]OAMediaType {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{ajson}
+	}
+	content := make(map[string]OAMediaType, len(contentTypes))
+	for _, ct := range contentTypes {
+		content[ct] = OAMediaType{Schema: schema}
+	}
+	return content
+}
+
+// bodyTypes returns the content types of a body parameter, which cannot be a form
+func bodyTypes(consumes []string) (types []string) {
+	for _, ct := range consumes {
+		if ct != aurlencoded && ct != aform {
+			types = append(types, ct)
+		}
+	}
+	return
+}
+
+// formTypes returns the content types of formData parameters, which upload files as multipart
+func formTypes(consumes []string, isMultipart bool) (types []string) {
+	for _, ct := range consumes {
+		if (ct == aurlencoded && !isMultipart) || ct == aform {
+			types = append(types, ct)
+		}
+	}
+	if len(types) == 0 {
+		if isMultipart {
+			return []string{aform}
+		}
+		return []string{aurlencoded}
+	}
+	return
+}
+
+func openAPIRef(ref string) string {
+	return strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+}
+
+func openAPIParamSchema(p swagger.Parameter) *OASchema {
+	if p.Schema != nil {
+		return openAPISchema(p.Schema)
+	}
+	schema := &OASchema{
+		Type:    p.Type,
+		Format:  p.Format,
+		Default: p.Default,
+	}
+	if p.Type == "file" {
+		schema.Type, schema.Format = "string", "binary"
+	}
+	if p.Items != nil {
+		schema.Items = &OASchema{Type: p.Items.Type, Format: p.Items.Format}
+	}
+	return schema
+}
+
+func openAPISchema(s *swagger.Schema) *OASchema {
+	if s == nil {
+		return nil
+	}
+	oas := &OASchema{
+		Ref:         openAPIRef(s.Ref),
+		Title:       s.Title,
+		Description: s.Description,
+		Type:        s.Type,
+		Format:      s.Format,
+		Required:    s.Required,
+		Items:       openAPISchema(s.Items),
+		Enum:        s.Enum,
+		Example:     s.Example,
+	}
+	if len(s.Properties) > 0 {
+		oas.Properties = make(map[string]*OASchema, len(s.Properties))
+		for name, p := range s.Properties {
+			oas.Properties[name] = openAPIProperty(&p)
+		}
+	}
+	return oas
+}
+
+func openAPIProperty(p *swagger.Propertie) *OASchema {
+	if p == nil {
+		return nil
+	}
+	oas := &OASchema{
+		Ref:                  openAPIRef(p.Ref),
+		Title:                p.Title,
+		Description:          p.Description,
+		Type:                 p.Type,
+		Format:               p.Format,
+		Required:             p.Required,
+		Items:                openAPIProperty(p.Items),
+		AdditionalProperties: openAPIProperty(p.AdditionalProperties),
+		Default:              p.Default,
+		Example:              p.Example,
+		ReadOnly:             p.ReadOnly,
+	}
+	if len(p.Properties) > 0 {
+		oas.Properties = make(map[string]*OASchema, len(p.Properties))
+		for name, sub := range p.Properties {
+			sub := sub
+			oas.Properties[name] = openAPIProperty(&sub)
+		}
+	}
+	return oas
+}
+
+func openAPISecurityScheme(sec swagger.Security) OASecurityScheme {
+	scheme := OASecurityScheme{
+		Type:        sec.Type,
+		Description: sec.Description,
+	}
+	switch sec.Type {
+	case "basic":
+		scheme.Type = "http"
+		scheme.Scheme = "basic"
+	case "apiKey":
+		scheme.Name = sec.Name
+		scheme.In = sec.In
+	case "oauth2":
+		flow := &OAFlow{
+			AuthorizationURL: sec.AuthorizationURL,
+			TokenURL:         sec.TokenURL,
+			Scopes:           sec.Scopes,
+		}
+		if flow.Scopes == nil {
+			flow.Scopes = make(map[string]string)
+		}
+		scheme.Flows = &OAFlows{}
+		switch sec.Flow {
+		case "implicit":
+			scheme.Flows.Implicit = flow
+		case "password":
+			scheme.Flows.Password = flow
+		case "application":
+			scheme.Flows.ClientCredentials = flow
+		case "accessCode":
+			scheme.Flows.AuthorizationCode = flow
+		}
+	}
+	return scheme
+}
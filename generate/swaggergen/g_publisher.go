@@ -0,0 +1,547 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package swaggergen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/astaxie/beego/swagger"
+	"github.com/iwooyun/bee/config"
+	"github.com/iwooyun/bee/generate/mongodb"
+	beeLogger "github.com/iwooyun/bee/logger"
+	bu "github.com/iwooyun/bee/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DocVersion describes a published version of the swagger document
+type DocVersion struct {
+	Version    string    `json:"version"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+	RolledBack bool      `json:"rolled_back,omitempty"`
+}
+
+// DocPublisher publishes generated swagger documents to a doc store
+type DocPublisher interface {
+	// Name returns the publisher name, as used in the configuration
+	Name() string
+	// Latest returns the current version of the document in the store,
+	// or nil if the store does not know of any.
+	Latest() (*DocVersion, error)
+	// Publish stores the document under the given version
+	Publish(doc swagger.Swagger, version *DocVersion) error
+}
+
+// DocHistory is implemented by the doc stores which keep every published version
+// of the document, so that they can be listed, shown and rolled back to.
+type DocHistory interface {
+	// History returns the published versions, from the oldest to the newest
+	History() ([]*DocVersion, error)
+	// Show returns the JSON document published under the given version
+	Show(version string) ([]byte, error)
+	// Rollback makes the given version the current one again
+	// and returns the versions which got rolled back.
+	Rollback(version string) ([]string, error)
+}
+
+// NewDocPublisher returns the publisher configured in the 'docs' section
+// of Beefile or bee.json, or nil if no publisher is configured.
+func NewDocPublisher(curpath string) (DocPublisher, error) {
+	conf := config.Conf.Docs
+	switch conf.Publisher {
+	case "":
+		return nil, nil
+	case "local":
+		dir := conf.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(curpath, dir)
+		}
+		return &LocalPublisher{Dir: dir}, nil
+	case "http":
+		if conf.URL == "" {
+			return nil, errors.New("docs url must be configured for the http publisher")
+		}
+		return &HTTPPublisher{URL: conf.URL, Headers: conf.Headers}, nil
+	case "mongodb":
+		return &MongoPublisher{
+			Host:     conf.Mongo.Host,
+			Port:     conf.Mongo.Port,
+			Database: conf.Mongo.Database,
+			Username: conf.Mongo.Username,
+			Password: conf.Mongo.Password,
+			APIHost:  apiHost(curpath),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown doc publisher '%s'. Must be either \"local\", \"http\" or \"mongodb\"", conf.Publisher)
+	}
+}
+
+// NewDocHistory returns the configured doc store, provided it keeps a history of the documents
+func NewDocHistory(curpath string) (DocHistory, error) {
+	publisher, err := NewDocPublisher(curpath)
+	if err != nil {
+		return nil, err
+	}
+	if publisher == nil {
+		return nil, errors.New("no doc publisher is configured in the 'docs' section of Beefile or bee.json")
+	}
+	history, ok := publisher.(DocHistory)
+	if !ok {
+		return nil, fmt.Errorf("the '%s' doc store does not keep a history of the documents", publisher.Name())
+	}
+	return history, nil
+}
+
+// publishDocs publishes rootapi under the next version, unless it did not change
+// since the latest version in the store
+func publishDocs(publisher DocPublisher) error {
+	dt, err := json.Marshal(rootapi)
+	if err != nil {
+		return err
+	}
+	hashValue := MD5(string(dt))
+
+	latest, err := publisher.Latest()
+	if err != nil {
+		return err
+	}
+	latestVersion := ""
+	if latest != nil {
+		if latest.Hash == hashValue {
+			beeLogger.Log.Infof("Swagger doc has not changed since version %s", latest.Version)
+			return nil
+		}
+		latestVersion = latest.Version
+	}
+	version, err := NextVersion(latestVersion, rootapi.Infos.Version, VersionBump)
+	if err != nil {
+		return err
+	}
+
+	if history, ok := publisher.(DocHistory); ok && latest != nil {
+		reportChanges(history, latest.Version, version)
+	}
+
+	beeLogger.Log.Warnf("Do you want to push version %s to the '%s' doc store? [Yes|No] ", version, publisher.Name())
+	if !bu.AskForConfirmation() {
+		return nil
+	}
+	doc := rootapi
+	doc.Infos.Version = version
+	return publisher.Publish(doc, &DocVersion{
+		Version:   version,
+		Hash:      hashValue,
+		CreatedAt: time.Now(),
+	})
+}
+
+// reportChanges prints the differences between the latest published document and rootapi,
+// warning when breaking changes are about to be published without a major version bump
+func reportChanges(history DocHistory, latest, version string) {
+	dt, err := history.Show(latest)
+	if err != nil {
+		beeLogger.Log.Warnf("Could not compare with version %s: %s", latest, err)
+		return
+	}
+	oldDoc, err := ParseDoc(latest, dt)
+	if err != nil {
+		beeLogger.Log.Warnf("Could not compare with version %s: %s", latest, err)
+		return
+	}
+
+	diff := DiffDocs(oldDoc, &rootapi)
+	if len(diff.Changes) == 0 {
+		return
+	}
+	diff.Old = "version " + latest
+	diff.New = "swagger/swagger.json"
+	diff.WriteReport(os.Stdout)
+
+	oldVer, _ := parseSemver(latest)
+	newVer, _ := parseSemver(version)
+	if diff.Breaking() > 0 && newVer.Major <= oldVer.Major {
+		beeLogger.Log.Warnf("%d breaking change(s) since version %s, consider publishing with -bump=major", diff.Breaking(), latest)
+	}
+}
+
+// latestVersion returns the highest version which was not rolled back
+func latestVersion(versions []*DocVersion) *DocVersion {
+	var latest *DocVersion
+	for _, v := range versions {
+		if !v.RolledBack && (latest == nil || CompareVersions(v.Version, latest.Version) > 0) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// rollbackVersions returns the versions to roll back in order to make version the current one
+func rollbackVersions(versions []*DocVersion, version string) ([]string, error) {
+	var target *DocVersion
+	for _, v := range versions {
+		if CompareVersions(v.Version, version) == 0 {
+			target = v
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("version %s was never published", version)
+	}
+	if target.RolledBack {
+		return nil, fmt.Errorf("version %s was rolled back already", version)
+	}
+
+	var newer []string
+	for _, v := range versions {
+		if !v.RolledBack && CompareVersions(v.Version, target.Version) > 0 {
+			newer = append(newer, v.Version)
+		}
+	}
+	if len(newer) == 0 {
+		return nil, fmt.Errorf("version %s is the current version already", version)
+	}
+	return newer, nil
+}
+
+// apiHost returns the @Host of the API, that of the doc generated by this run if any, else
+// that of the last generated swagger/swagger.json
+func apiHost(curpath string) string {
+	if rootapi.Host != "" {
+		return rootapi.Host
+	}
+	doc, err := ReadDoc(filepath.Join(curpath, "swagger", "swagger.json"))
+	if err != nil {
+		return ""
+	}
+	return doc.Host
+}
+
+// LocalPublisher keeps every published version of the document
+// as <version>.json inside a directory, along with an index.json file.
+type LocalPublisher struct {
+	Dir string
+}
+
+// Name returns the publisher name
+func (*LocalPublisher) Name() string {
+	return "local"
+}
+
+// Latest returns the current version from the index
+func (p *LocalPublisher) Latest() (*DocVersion, error) {
+	versions, err := p.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	return latestVersion(versions), nil
+}
+
+// Publish writes the document as a new version in the local store
+func (p *LocalPublisher) Publish(doc swagger.Swagger, version *DocVersion) error {
+	versions, err := p.readIndex()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p.Dir, 0755); err != nil {
+		return err
+	}
+	dt, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(p.docFile(version.Version), dt, 0644); err != nil {
+		return err
+	}
+
+	// A version which was rolled back before is replaced
+	kept := versions[:0]
+	for _, v := range versions {
+		if CompareVersions(v.Version, version.Version) != 0 {
+			kept = append(kept, v)
+		}
+	}
+	if err := p.writeIndex(append(kept, version)); err != nil {
+		return err
+	}
+	beeLogger.Log.Successf("Swagger doc version %s saved to '%s'", version.Version, p.Dir)
+	return nil
+}
+
+// History returns the versions listed in the index
+func (p *LocalPublisher) History() ([]*DocVersion, error) {
+	versions, err := p.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// Show reads the document of the given version
+func (p *LocalPublisher) Show(version string) ([]byte, error) {
+	versions, err := p.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if CompareVersions(v.Version, version) == 0 {
+			return ioutil.ReadFile(p.docFile(v.Version))
+		}
+	}
+	return nil, fmt.Errorf("version %s was never published", version)
+}
+
+// Rollback flags the versions newer than the given one as rolled back in the index
+func (p *LocalPublisher) Rollback(version string) ([]string, error) {
+	versions, err := p.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	newer, err := rollbackVersions(versions, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		for _, n := range newer {
+			if v.Version == n {
+				v.RolledBack = true
+			}
+		}
+	}
+	return newer, p.writeIndex(versions)
+}
+
+func (p *LocalPublisher) docFile(version string) string {
+	return filepath.Join(p.Dir, version+".json")
+}
+
+func (p *LocalPublisher) readIndex() (versions []*DocVersion, err error) {
+	dt, err := ioutil.ReadFile(filepath.Join(p.Dir, "index.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(dt, &versions)
+	return
+}
+
+func (p *LocalPublisher) writeIndex(versions []*DocVersion) error {
+	dt, err := json.MarshalIndent(versions, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(p.Dir, "index.json"), dt, 0644)
+}
+
+// HTTPPublisher POSTs the document as JSON to an endpoint
+// which is in charge of versioning it.
+type HTTPPublisher struct {
+	URL     string
+	Headers map[string]string
+}
+
+// Name returns the publisher name
+func (*HTTPPublisher) Name() string {
+	return "http"
+}
+
+// Latest always returns nil as the endpoint does not expose the published versions
+func (*HTTPPublisher) Latest() (*DocVersion, error) {
+	return nil, nil
+}
+
+// Publish sends the document to the configured endpoint
+func (p *HTTPPublisher) Publish(doc swagger.Swagger, version *DocVersion) error {
+	dt, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(dt))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Swagger-Hash", version.Hash)
+	req.Header.Set("X-Swagger-Version", version.Version)
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("'%s' responded with %s: %s", p.URL, resp.Status, bytes.TrimSpace(body))
+	}
+	beeLogger.Log.Successf("Swagger doc version %s posted to '%s'", version.Version, p.URL)
+	return nil
+}
+
+// MongoPublisher stores the document in the MongoDB doc center,
+// in the center_project and swagger_location collections.
+// Projects are identified by the @Host of the API.
+// The connection is only established when the store is used.
+type MongoPublisher struct {
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
+	APIHost  string
+}
+
+// Name returns the publisher name
+func (*MongoPublisher) Name() string {
+	return "mongodb"
+}
+
+// project returns the doc center project of the API, or nil if it was never published
+func (p *MongoPublisher) project() (*mongodb.Project, error) {
+	if p.APIHost == "" {
+		return nil, errors.New("the API has no @Host, or its doc was never generated. Run: bee generate docs")
+	}
+	if err := mongodb.Connect(p.Host, p.Port, p.Database, p.Username, p.Password); err != nil {
+		return nil, err
+	}
+	project := mongodb.FindProjectByHost(p.APIHost)
+	if !project.Id_.Valid() {
+		return nil, nil
+	}
+	return project, nil
+}
+
+func (p *MongoPublisher) locations() ([]mongodb.SwaggerLocation, error) {
+	project, err := p.project()
+	if err != nil || project == nil {
+		return nil, err
+	}
+	return mongodb.FindLocations(project.Id_)
+}
+
+// Latest returns the current swagger location of the project
+func (p *MongoPublisher) Latest() (*DocVersion, error) {
+	versions, err := p.History()
+	if err != nil {
+		return nil, err
+	}
+	return latestVersion(versions), nil
+}
+
+// Publish inserts a new swagger location for the project of the document
+func (p *MongoPublisher) Publish(doc swagger.Swagger, version *DocVersion) error {
+	project, err := p.project()
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		project = &mongodb.Project{
+			Id_:       bson.NewObjectId(),
+			Name:      doc.Infos.Title,
+			Host:      p.APIHost,
+			CreatedAt: version.CreatedAt,
+			UpdateAt:  version.CreatedAt,
+		}
+		if err := mongodb.Insert("center_project", project); err != nil {
+			return err
+		}
+	}
+
+	dt, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var locationMap map[string]interface{}
+	if err := json.Unmarshal(dt, &locationMap); err != nil {
+		return err
+	}
+
+	err = mongodb.Insert("swagger_location", mongodb.SwaggerLocation{
+		ProjectId: project.Id_,
+		Version:   version.Version,
+		Hash:      version.Hash,
+		Location:  locationMap,
+		CreatedAt: version.CreatedAt,
+		UpdateAt:  version.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	beeLogger.Log.Successf("Swagger doc version %s pushed to the doc center", version.Version)
+	return nil
+}
+
+// History returns the swagger locations of the project
+func (p *MongoPublisher) History() ([]*DocVersion, error) {
+	locations, err := p.locations()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*DocVersion, 0, len(locations))
+	for _, l := range locations {
+		versions = append(versions, &DocVersion{
+			Version:    l.Version,
+			Hash:       l.Hash,
+			CreatedAt:  l.CreatedAt,
+			RolledBack: l.IsDeleted == mongodb.IsDeletedTrue,
+		})
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// Show returns the swagger location of the given version.
+// The last inserted location wins if the version was published several times.
+func (p *MongoPublisher) Show(version string) ([]byte, error) {
+	locations, err := p.locations()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(locations) - 1; i >= 0; i-- {
+		if CompareVersions(locations[i].Version, version) == 0 {
+			return json.MarshalIndent(locations[i].Location, "", "    ")
+		}
+	}
+	return nil, fmt.Errorf("version %s was never published", version)
+}
+
+// Rollback flags the swagger locations newer than the given version as deleted
+func (p *MongoPublisher) Rollback(version string) ([]string, error) {
+	versions, err := p.History()
+	if err != nil {
+		return nil, err
+	}
+	newer, err := rollbackVersions(versions, version)
+	if err != nil {
+		return nil, err
+	}
+	project, err := p.project()
+	if err != nil {
+		return nil, err
+	}
+	return newer, mongodb.DeleteLocations(project.Id_, newer)
+}
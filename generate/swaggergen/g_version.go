@@ -0,0 +1,157 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package swaggergen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iwooyun/bee/generate/mongodb"
+)
+
+// Parts of a version which can be bumped
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+var semverRegex = regexp.MustCompile(`^v?([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// semVersion is a semantic version, see https://semver.org
+type semVersion struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// parseSemver parses a semantic version. Missing minor and patch numbers
+// default to 0 so that versions published as "1.0" or "2" remain usable.
+func parseSemver(version string) (v semVersion, err error) {
+	m := semverRegex.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return v, fmt.Errorf("'%s' is not a semantic version", version)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, n := range nums {
+		if m[i+1] != "" {
+			*n, _ = strconv.Atoi(m[i+1])
+		}
+	}
+	v.Pre = m[4]
+	return
+}
+
+func (v semVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1 whether v is lower, equal or greater than o
+func (v semVersion) compare(o semVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	case v.Pre < o.Pre:
+		return -1
+	}
+	return 1
+}
+
+func (v semVersion) bump(part string) semVersion {
+	switch part {
+	case BumpMajor:
+		return semVersion{Major: v.Major + 1}
+	case BumpMinor:
+		return semVersion{Major: v.Major, Minor: v.Minor + 1}
+	}
+	if v.Pre != "" {
+		// 1.2.0-rc.1 is released as 1.2.0
+		return semVersion{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	}
+	return semVersion{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// CompareVersions compares two semantic versions. Versions which cannot be
+// parsed are lower than any valid one and are compared as strings between them.
+func CompareVersions(a, b string) int {
+	va, errA := parseSemver(a)
+	vb, errB := parseSemver(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// SortVersions sorts the documents from the oldest to the newest version
+func SortVersions(versions []*DocVersion) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersions(versions[i].Version, versions[j].Version) < 0
+	})
+}
+
+// NextVersion returns the version a changed document is published under.
+// With an explicit bump (major, minor or patch) the latest published version is
+// incremented. Otherwise @APIVersion is used if it is newer than the latest published
+// version, and the latest published version gets a patch bump if it is not.
+func NextVersion(latest, apiVersion, bump string) (string, error) {
+	switch bump {
+	case "", BumpMajor, BumpMinor, BumpPatch:
+	default:
+		return "", fmt.Errorf("unknown version bump '%s'. Must be either \"major\", \"minor\" or \"patch\"", bump)
+	}
+
+	api, apiErr := parseSemver(apiVersion)
+	if latest == "" {
+		if apiErr == nil {
+			return api.String(), nil
+		}
+		return mongodb.DefaultVersion, nil
+	}
+
+	last, err := parseSemver(latest)
+	if err != nil {
+		return "", fmt.Errorf("latest published version: %s", err)
+	}
+	if bump == "" && apiErr == nil && api.compare(last) > 0 {
+		return api.String(), nil
+	}
+	if bump == "" {
+		bump = BumpPatch
+	}
+	return last.bump(bump).String(), nil
+}
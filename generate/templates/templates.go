@@ -0,0 +1,140 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package templates holds the text/template templates of the code generators.
+// Each generator registers its built-in templates, which an application overrides
+// by placing a file of the same name in its templates directory.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/iwooyun/bee/config"
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/utils"
+)
+
+// DefaultDir is the templates directory of an application when the Beefile sets none
+const DefaultDir = "templates"
+
+var (
+	builtins = make(map[string]string)             // name => built-in text
+	parsed   = make(map[string]*template.Template) // name => template in use
+)
+
+// FuncMap returns the functions available to the templates besides the text/template ones
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"camel": utils.CamelCase,
+		"snake": utils.SnakeString,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"title": strings.Title,
+		"join":  strings.Join,
+		"quote": quote,
+	}
+}
+
+// quote returns s as a Go string literal, a raw one when it can be
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, "`\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// Register registers the built-in text of the template name,
+// which is the path of its override in the templates directory
+func Register(name, text string) {
+	builtins[name] = text
+}
+
+// Names returns the names of the registered templates, sorted
+func Names() []string {
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Builtin returns the built-in text of the template name
+func Builtin(name string) string {
+	return builtins[name]
+}
+
+// Dir returns the directory the templates are overridden from,
+// the templates path of the Beefile or DefaultDir
+func Dir() string {
+	if config.Conf.Templates != "" {
+		return config.Conf.Templates
+	}
+	return DefaultDir
+}
+
+// Execute executes the template name with data. The template of the
+// templates directory is used if there is one, the built-in one otherwise.
+func Execute(name string, data interface{}) (string, error) {
+	t, err := lookup(name)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// MustExecute is like Execute but exits when the template fails
+func MustExecute(name string, data interface{}) string {
+	s, err := Execute(name, data)
+	if err != nil {
+		beeLogger.Log.Fatalf("Could not execute template '%s': %s", name, err)
+	}
+	return s
+}
+
+func lookup(name string) (*template.Template, error) {
+	if t, ok := parsed[name]; ok {
+		return t, nil
+	}
+	text, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template")
+	}
+	fpath := filepath.Join(Dir(), filepath.FromSlash(name))
+	if utils.IsExist(fpath) {
+		dt, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		beeLogger.Log.Infof("Using template '%s'", fpath)
+		text = string(dt)
+	}
+	t, err := template.New(name).Funcs(FuncMap()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	parsed[name] = t
+	return t, nil
+}
@@ -0,0 +1,559 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package validation
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/astaxie/beego/swagger"
+	beeLogger "github.com/iwooyun/bee/logger"
+	bu "github.com/iwooyun/bee/utils"
+)
+
+// validRulesPrefix starts the token of a @Param annotation holding its validation rules, i.e.
+// @Param	age	query	int	true	"The age"	valid:"range(1,140);message(age must be between 1 and 140)"
+const validRulesPrefix = "valid:"
+
+// Rules of the valid:"..." token of @Param
+const (
+	ruleMin     = "min"
+	ruleMax     = "max"
+	ruleRange   = "range"
+	ruleLength  = "length"
+	ruleRegex   = "regex"
+	ruleEnum    = "enum"
+	ruleEmail   = "email"
+	ruleMobile  = "mobile"
+	ruleMessage = "message"
+)
+
+// Rule is a rule of a parameter, i.e. range(1,140), or of the valid tag of a struct field,
+// i.e. MaxSize(64), named in lower case
+type Rule struct {
+	Name string
+	Args []string
+}
+
+// validParam is a parameter of a controller method along with its validation rules
+type validParam struct {
+	swagger.Parameter
+	ItemType  string // type of the items of an array parameter
+	ModelPkg  string // import path of the type of a body parameter
+	ModelType string // name of the type of a body parameter
+	Rules     []Rule
+	Message   string // custom error message of the rules
+}
+
+// stdImports are the imports a validator file may need besides the model packages
+var stdImports = map[string]string{
+	"encoding/json": "json",
+	"regexp":        "regexp",
+	"strconv":       "strconv",
+	"strings":       "strings",
+}
+
+// splitRulesToken removes the valid:"..." token from the @Param elements and returns its rules
+func splitRulesToken(p []string) ([]string, string) {
+	for i, e := range p {
+		if strings.HasPrefix(e, validRulesPrefix) {
+			return append(p[:i:i], p[i+1:]...), strings.TrimPrefix(e, validRulesPrefix)
+		}
+	}
+	return p, ""
+}
+
+// ParseRules parses rules such as "min(1);regex(^[a-z;]+$);message(invalid name)", semicolons
+// inside parentheses do not separate rules
+func ParseRules(s string) (rules []Rule, message string) {
+	var elements []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ';':
+			if depth == 0 {
+				elements = append(elements, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	elements = append(elements, s[start:])
+
+	for _, e := range elements {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		rule := Rule{Name: strings.ToLower(e)}
+		if i := strings.Index(e, "("); i > 0 && strings.HasSuffix(e, ")") {
+			rule.Name = strings.ToLower(strings.TrimSpace(e[:i]))
+			arg := e[i+1 : len(e)-1]
+			switch rule.Name {
+			case ruleRegex, ruleMessage:
+				rule.Args = []string{arg}
+			default:
+				for _, a := range strings.Split(arg, ",") {
+					rule.Args = append(rule.Args, strings.TrimSpace(a))
+				}
+			}
+		}
+		if rule.Name == ruleMessage {
+			if len(rule.Args) == 1 {
+				message = rule.Args[0]
+			}
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return
+}
+
+// paramVarName returns the name of the variable holding the value of a parameter
+func paramVarName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+	name = bu.CamelCase(name)
+	if name == "" {
+		return "param"
+	}
+	name = strings.ToLower(name[0:1]) + name[1:]
+	if unicode.IsDigit(rune(name[0])) || token.Lookup(name).IsKeyword() {
+		return "param" + bu.CamelCase(name)
+	}
+	switch name {
+	case "v", "input", "valid", "err":
+		return name + "Param"
+	}
+	return name
+}
+
+// genParamCode returns the code reading a parameter and validating it
+func genParamCode(param validParam, imports map[string]string) string {
+	if param.In == "body" {
+		return genBodyCode(param, imports)
+	}
+
+	name, typ := paramVarName(param.Name), param.Type
+	var code, raw string
+	switch param.In {
+	case "path", "header":
+		raw = fmt.Sprintf("v.Input.Header(%q)", param.Name)
+		if param.In == "path" {
+			raw = fmt.Sprintf("v.Input.Param(%q)", ":"+param.Name)
+		}
+		code = genConvertCode(name, param.Type, raw, imports)
+		if isFloat(typ) {
+			typ = "float64"
+		} else if isNumeric(typ) {
+			typ = "int"
+		}
+	default:
+		raw = fmt.Sprintf("v.Input.Query(%q)", param.Name)
+		code = genGetterCode(name, param)
+		if isFloat(typ) {
+			typ = "float64"
+		}
+	}
+
+	var checks string
+	required := name
+	if param.Type == astTypeArray {
+		if param.In == "query" || param.In == "formData" {
+			raw = fmt.Sprintf("len(%s)", name)
+		} else {
+			// Split values always hold an item, the raw value is checked instead
+			required = raw
+		}
+		if rules := genItemRulesCode(param, imports); rules != "" {
+			checks = fmt.Sprintf("for _, item := range %s {\n%s}\n", name, rules)
+		}
+	} else {
+		checks = genRulesCode(name, typ, param, imports)
+	}
+
+	switch {
+	case checks == "" && !param.Required:
+		return ""
+	case checks == "" && required != name:
+		return fmt.Sprintf("valid.Required(%s, %q)%s\n", required, param.Name, messageCode(param.Message))
+	case param.Required:
+		code += fmt.Sprintf("valid.Required(%s, %q)%s\n", required, param.Name, messageCode(param.Message))
+		return code + checks
+	}
+	// Rules of optional parameters only apply to the values which are sent
+	if strings.HasPrefix(raw, "len(") {
+		return code + fmt.Sprintf("if %s > 0 {\n%s}\n", raw, checks)
+	}
+	return code + fmt.Sprintf("if %s != \"\" {\n%s}\n", raw, checks)
+}
+
+// genGetterCode reads a query or form parameter with the getters of the Validator. The values
+// of an array are read from the form of the request, which beego parses for a POST only.
+func genGetterCode(name string, param validParam) string {
+	if param.Type == astTypeArray {
+		return fmt.Sprintf("v.Input.Context.Request.ParseForm()\n%s := v.Input.Context.Request.Form[%q]\n", name, param.Name)
+	}
+	getters := map[string]string{
+		"int":     "GetInt",
+		"int64":   "GetInt64",
+		"int32":   "GetInt32",
+		"int16":   "GetInt16",
+		"int8":    "GetInt8",
+		"uint":    "GetInt",
+		"uint64":  "GetUint64",
+		"uint32":  "GetUint32",
+		"uint16":  "GetUint16",
+		"uint8":   "GetUint8",
+		"bool":    "GetBool",
+		"float64": "GetFloat",
+		"float32": "GetFloat",
+	}
+	if getter, ok := getters[param.Type]; ok {
+		return fmt.Sprintf("%s, _ := v.%s(%q)\n", name, getter, param.Name)
+	}
+	if param.Type != "string" {
+		beeLogger.Log.Warnf("Unsupported type '%s' of parameter '%s', it is validated as a string", param.Type, param.Name)
+	}
+	return fmt.Sprintf("%s := v.GetString(%q)\n", name, param.Name)
+}
+
+// genConvertCode converts the raw string value of a path or header parameter to its type
+func genConvertCode(name, typ, raw string, imports map[string]string) string {
+	switch typ {
+	case "int", "int64", "int32", "int16", "int8", "uint", "uint64", "uint32", "uint16", "uint8":
+		addImport(imports, "strconv")
+		return fmt.Sprintf("%s, _ := strconv.Atoi(%s)\n", name, raw)
+	case "bool":
+		addImport(imports, "strconv")
+		return fmt.Sprintf("%s, _ := strconv.ParseBool(%s)\n", name, raw)
+	case "float64", "float32":
+		addImport(imports, "strconv")
+		return fmt.Sprintf("%s, _ := strconv.ParseFloat(%s, 64)\n", name, raw)
+	case astTypeArray:
+		addImport(imports, "strings")
+		return fmt.Sprintf("%s := strings.Split(%s, \",\")\n", name, raw)
+	}
+	return fmt.Sprintf("%s := %s\n", name, raw)
+}
+
+// genItemRulesCode validates every item of an array parameter, items are strings converted
+// to numbers when the rules need it
+func genItemRulesCode(param validParam, imports map[string]string) string {
+	if len(param.Rules) == 0 {
+		return ""
+	}
+	if isFloat(param.ItemType) {
+		addImport(imports, "strconv")
+		return fmt.Sprintf("n, err := strconv.ParseFloat(item, 64)\nif err != nil {\nvalid.SetError(%q, %q)\ncontinue\n}\n%s",
+			param.Name, errorMessage(param.Message, "Must be a valid number"), genRulesCode("n", "float64", param, imports))
+	}
+	if isNumeric(param.ItemType) {
+		addImport(imports, "strconv")
+		return fmt.Sprintf("valid.Numeric(item, %q)%s\nn, _ := strconv.Atoi(item)\n%s",
+			param.Name, messageCode(param.Message), genRulesCode("n", "int", param, imports))
+	}
+	return genRulesCode("item", "string", param, imports)
+}
+
+// genRulesCode returns the validation calls of the rules of a parameter
+func genRulesCode(name, typ string, param validParam, imports map[string]string) string {
+	var code string
+	key, message := strconv.Quote(param.Name), messageCode(param.Message)
+	numeric, float := isNumeric(typ), isFloat(typ)
+	// The numeric validators of beego work on int values
+	value := name
+	if numeric && !float && typ != "int" {
+		value = "int(" + name + ")"
+	}
+	for _, rule := range param.Rules {
+		if !checkRuleArgs(rule, param.Name, float) {
+			continue
+		}
+		switch {
+		case rule.Name == ruleLength && numeric:
+			beeLogger.Log.Warnf("Rule '%s' of number parameter '%s' is skipped, it applies to strings", rule.Name, param.Name)
+			continue
+		case float:
+			// MinSize and MaxSize of beego fail on anything but strings and slices, and
+			// Min, Max and Range take ints
+			if check, ok := genFloatRuleCode(name, key, rule, param.Message); ok {
+				code += check
+				continue
+			}
+		}
+		switch rule.Name {
+		case ruleMin, ruleMax:
+			fn := map[string]string{ruleMin: "MinSize", ruleMax: "MaxSize"}[rule.Name]
+			if numeric {
+				fn = strings.Title(rule.Name)
+			}
+			code += fmt.Sprintf("valid.%s(%s, %s, %s)%s\n", fn, value, rule.Args[0], key, message)
+		case ruleRange:
+			if numeric {
+				code += fmt.Sprintf("valid.Range(%s, %s, %s, %s)%s\n", value, rule.Args[0], rule.Args[1], key, message)
+			} else {
+				code += fmt.Sprintf("valid.MinSize(%s, %s, %s)%s\n", name, rule.Args[0], key, message)
+				code += fmt.Sprintf("valid.MaxSize(%s, %s, %s)%s\n", name, rule.Args[1], key, message)
+			}
+		case ruleLength:
+			if len(rule.Args) == 1 {
+				code += fmt.Sprintf("valid.Length(%s, %s, %s)%s\n", name, rule.Args[0], key, message)
+			} else {
+				code += fmt.Sprintf("valid.MinSize(%s, %s, %s)%s\n", name, rule.Args[0], key, message)
+				code += fmt.Sprintf("valid.MaxSize(%s, %s, %s)%s\n", name, rule.Args[1], key, message)
+			}
+		case ruleRegex:
+			addImport(imports, "regexp")
+			code += fmt.Sprintf("valid.Match(%s, regexp.MustCompile(%q), %s)%s\n", name, rule.Args[0], key, message)
+		case ruleEmail:
+			code += fmt.Sprintf("valid.Email(%s, %s)%s\n", name, key, message)
+		case ruleMobile:
+			code += fmt.Sprintf("valid.Mobile(%s, %s)%s\n", name, key, message)
+		case ruleEnum:
+			values := make([]string, len(rule.Args))
+			for i, a := range rule.Args {
+				values[i] = strconv.Quote(a)
+				if numeric && isNumber(a, float) {
+					values[i] = a
+				} else if numeric {
+					beeLogger.Log.Warnf("Value '%s' of rule '%s' of parameter '%s' is not a number", a, rule.Name, param.Name)
+					values = nil
+					break
+				}
+			}
+			if values == nil {
+				continue
+			}
+			msg := param.Message
+			if msg == "" {
+				msg = "must be one of " + strings.Join(rule.Args, ", ")
+			}
+			code += fmt.Sprintf("switch %s {\ncase %s:\ndefault:\nvalid.SetError(%s, %q)\n}\n",
+				name, strings.Join(values, ", "), key, msg)
+		}
+	}
+	return code
+}
+
+// checkRuleArgs reports whether the rule is known and has valid arguments, which are decimal
+// numbers for the min, max and range of a float parameter
+func checkRuleArgs(rule Rule, param string, float bool) bool {
+	var counts []int
+	numeric := true
+	switch rule.Name {
+	case ruleMin, ruleMax:
+		counts = []int{1}
+	case ruleRange:
+		counts = []int{2}
+	case ruleLength:
+		counts = []int{1, 2}
+	case ruleRegex:
+		counts, numeric = []int{1}, false
+	case ruleEmail, ruleMobile:
+		counts, numeric = []int{0}, false
+	case ruleEnum:
+		if len(rule.Args) == 0 {
+			beeLogger.Log.Warnf("Rule '%s' of parameter '%s' has no values, it is skipped", rule.Name, param)
+			return false
+		}
+		return true
+	default:
+		beeLogger.Log.Warnf("Unknown rule '%s' of parameter '%s', it is skipped", rule.Name, param)
+		return false
+	}
+
+	valid := false
+	for _, c := range counts {
+		valid = valid || c == len(rule.Args)
+	}
+	float = float && rule.Name != ruleLength
+	for _, a := range rule.Args {
+		if numeric && !isNumber(a, float) {
+			valid = false
+		}
+	}
+	if !valid {
+		beeLogger.Log.Warnf("Invalid arguments of rule '%s' of parameter '%s', it is skipped", rule.Name, param)
+		return false
+	}
+	// the generated validator compiles the pattern with regexp.MustCompile on each request
+	if rule.Name == ruleRegex {
+		if _, err := regexp.Compile(rule.Args[0]); err != nil {
+			beeLogger.Log.Warnf("Invalid pattern of rule '%s' of parameter '%s', it is skipped: %s", rule.Name, param, err)
+			return false
+		}
+	}
+	return true
+}
+
+// genBodyCode decodes the JSON body and validates it with the valid tags of its struct
+func genBodyCode(param validParam, imports map[string]string) string {
+	key, message := strconv.Quote(param.Name), param.Message
+	if message == "" {
+		message = "Can not be empty"
+	}
+	code := "if len(v.Input.RequestBody) > 0 "
+	if param.Required {
+		if param.ModelType == "" {
+			return fmt.Sprintf("if len(v.Input.RequestBody) == 0 {\nvalid.SetError(%s, %q)\n}\n", key, message)
+		}
+		code = fmt.Sprintf("if len(v.Input.RequestBody) == 0 {\nvalid.SetError(%s, %q)\n} else ", key, message)
+	} else if param.ModelType == "" {
+		return ""
+	}
+
+	addImport(imports, "encoding/json")
+	typ := addImport(imports, param.ModelPkg) + "." + param.ModelType
+	name := paramVarName(param.Name)
+	if param.Type == astTypeArray {
+		return code + fmt.Sprintf(`{
+			var %[1]s []%[2]s
+			if err := json.Unmarshal(v.Input.RequestBody, &%[1]s); err != nil {
+				valid.SetError(%[3]s, err.Error())
+			}
+			for i := range %[1]s {
+				if _, err := valid.Valid(&%[1]s[i]); err != nil {
+					valid.SetError(%[3]s, err.Error())
+				}
+			}
+		}
+		`, name, typ, key)
+	}
+	return code + fmt.Sprintf(`{
+		var %[1]s %[2]s
+		if err := json.Unmarshal(v.Input.RequestBody, &%[1]s); err != nil {
+			valid.SetError(%[3]s, err.Error())
+		} else if _, err := valid.Valid(&%[1]s); err != nil {
+			valid.SetError(%[3]s, err.Error())
+		}
+	}
+	`, name, typ, key)
+}
+
+// messageCode sets the custom error message of a validation result
+func messageCode(message string) string {
+	if message == "" {
+		return ""
+	}
+	return fmt.Sprintf(".Message(%q)", message)
+}
+
+// genFloatRuleCode compares a float parameter to the bounds of a min, max or range rule
+func genFloatRuleCode(name, key string, rule Rule, message string) (string, bool) {
+	var cond, msg string
+	switch rule.Name {
+	case ruleMin:
+		cond, msg = fmt.Sprintf("%s < %s", name, rule.Args[0]), "Minimum is "+rule.Args[0]
+	case ruleMax:
+		cond, msg = fmt.Sprintf("%s > %s", name, rule.Args[0]), "Maximum is "+rule.Args[0]
+	case ruleRange:
+		cond = fmt.Sprintf("%s < %s || %s > %s", name, rule.Args[0], name, rule.Args[1])
+		msg = fmt.Sprintf("Range is %s to %s", rule.Args[0], rule.Args[1])
+	default:
+		return "", false
+	}
+	return fmt.Sprintf("if %s {\nvalid.SetError(%s, %q)\n}\n", cond, key, errorMessage(message, msg)), true
+}
+
+// errorMessage returns the custom error message of the rules, or the default one
+func errorMessage(message, defaultMessage string) string {
+	if message == "" {
+		return defaultMessage
+	}
+	return message
+}
+
+func isNumeric(typ string) bool {
+	switch typ {
+	case "int", "int64", "int32", "int16", "int8", "uint", "uint64", "uint32", "uint16", "uint8":
+		return true
+	}
+	return isFloat(typ)
+}
+
+func isFloat(typ string) bool {
+	return typ == "float64" || typ == "float32"
+}
+
+// isNumber reports whether s is an integer, or a decimal number if float is set
+func isNumber(s string, float bool) bool {
+	if float {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// addImport adds a package to the imports of a validator file and returns its name in the file
+func addImport(imports map[string]string, pkgpath string) string {
+	if name, ok := imports[pkgpath]; ok {
+		return name
+	}
+	name, ok := stdImports[pkgpath]
+	if !ok {
+		base := paramVarName(path.Base(pkgpath))
+		name = base
+		for i := 2; importNameUsed(imports, name); i++ {
+			name = base + strconv.Itoa(i)
+		}
+	}
+	imports[pkgpath] = name
+	return name
+}
+
+func importNameUsed(imports map[string]string, name string) bool {
+	for _, n := range stdImports {
+		if n == name {
+			return true
+		}
+	}
+	for _, n := range imports {
+		if n == name {
+			return true
+		}
+	}
+	return name == "context" || name == "validation"
+}
+
+// importLines returns the import specs of a validator file
+func importLines(imports map[string]string) []string {
+	var lines []string
+	for pkgpath, name := range imports {
+		if name == path.Base(pkgpath) {
+			lines = append(lines, strconv.Quote(pkgpath))
+		} else {
+			lines = append(lines, name+" "+strconv.Quote(pkgpath))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -7,11 +7,12 @@ package validation
 import (
 	"errors"
 	"fmt"
-	beeLogger "github.com/iwooyun/bee/logger"
-	"github.com/iwooyun/bee/logger/colors"
-	bu "github.com/iwooyun/bee/utils"
 	"github.com/astaxie/beego/swagger"
 	"github.com/astaxie/beego/utils"
+	"github.com/iwooyun/bee/generate/routes"
+	"github.com/iwooyun/bee/generate/templates"
+	beeLogger "github.com/iwooyun/bee/logger"
+	bu "github.com/iwooyun/bee/utils"
 	"go/ast"
 	"go/parser"
 	"go/token"
@@ -31,11 +32,8 @@ const (
 )
 
 var (
-	controllerList map[string]map[string]*swagger.Item
-	importList     map[string]string
-	moduleList     map[string]map[string]string
-	funcList       map[string]map[string]string
-	rootApi        swagger.Swagger
+	controllerParams  map[string]map[string][]validParam      // controller => method => parameters
+	controllerRouters map[string]map[string]routes.Annotation // controller => method => @router annotation
 )
 
 // refer to builtin.go
@@ -71,324 +69,153 @@ var basicTypes = map[string]string{
 }
 
 func init() {
-	importList = make(map[string]string)
-	controllerList = make(map[string]map[string]*swagger.Item)
-	moduleList = make(map[string]map[string]string)
-	funcList = make(map[string]map[string]string)
+	controllerParams = make(map[string]map[string][]validParam)
+	controllerRouters = make(map[string]map[string]routes.Annotation)
+	templates.Register("validation/valid.go.tpl", VALIDTPL)
+	templates.Register("validation/mapper.go.tpl", MAPPERTPL)
 }
 
-func GenerateValidation(currentPath string) {
-	fset := token.NewFileSet()
+// validData is the data the validator template is executed with
+type validData struct {
+	Name    string   // name of the validator, e.g. Object for ObjectController
+	Imports []string // import specs of the packages the rules need
+	Methods []string // validation methods, one per controller method
+}
+
+// mapperData is the data the mapper template is executed with
+type mapperData struct {
+	Validators []string     // names of the validators, e.g. ObjectValidator
+	Routes     []validRoute // endpoints and the validation methods they are checked with
+}
+
+// validRoute maps an endpoint to its validation method
+type validRoute struct {
+	Method    string // HTTP method of the endpoint
+	Path      string // path of the endpoint, e.g. /v1/object/{objectId}
+	Validator string // name of the validator
+	Func      string // name of the controller and validation method
+	ByPath    bool   // also registered by path alone, as former versions of bee did
+}
 
-	f, err := parser.ParseFile(fset, filepath.Join(currentPath, "routers", "router.go"), nil, parser.ParseComments)
+func GenerateValidation(currentPath string) {
+	table, err := routes.Analyse(filepath.Join(currentPath, "routers"))
 	if err != nil {
-		beeLogger.Log.Fatalf("Error while parsing router.go: %s", err)
+		beeLogger.Log.Fatalf("Error while analysing the routers: %s", err)
 	}
 
 	// Analyse controller package
-	for _, im := range f.Imports {
-		localName := ""
-		if im.Name != nil {
-			localName = im.Name.Name
-		}
-		analyseControllerPkg(path.Join(currentPath, "vendor"), localName, im.Path.Value)
-	}
-
-	for _, d := range f.Decls {
-		switch specDecl := d.(type) {
-		case *ast.FuncDecl:
-			for _, l := range specDecl.Body.List {
-				switch stmt := l.(type) {
-				case *ast.AssignStmt:
-					for _, l := range stmt.Rhs {
-						if v, ok := l.(*ast.CallExpr); ok {
-							// Analyze NewNamespace, it will return version and the subfunction
-							selExpr, selOK := v.Fun.(*ast.SelectorExpr)
-							if !selOK || selExpr.Sel.Name != "NewNamespace" {
-								continue
-							}
-							version, params := analyseNewNamespace(v)
-							if rootApi.BasePath == "" && version != "" {
-								rootApi.BasePath = version
-							}
-							for _, p := range params {
-								switch pp := p.(type) {
-								case *ast.CallExpr:
-									if selname := pp.Fun.(*ast.SelectorExpr).Sel.String(); selname == "NSNamespace" {
-										s, params := analyseNewNamespace(pp)
-										for _, sp := range params {
-											switch pp := sp.(type) {
-											case *ast.CallExpr:
-												if pp.Fun.(*ast.SelectorExpr).Sel.String() == "NSInclude" {
-													analyseNSInclude(s, pp)
-												}
-											}
-										}
-									} else if selname == "NSInclude" {
-										analyseNSInclude("", pp)
-									}
-								}
-							}
-						}
-
-					}
-				}
-			}
-		}
+	for _, pkgpath := range table.Packages() {
+		analyseControllerPkg(path.Join(currentPath, "vendor"), pkgpath)
 	}
 
 	validPath := path.Join(currentPath, "controllers", "validator")
-	_ = os.Mkdir(validPath, 0777)
+	_ = bu.MkdirGenerated(validPath)
 	var (
-		mapLines   []string
-		constLines []string
-		validList  map[string][]map[string][]swagger.Parameter
+		mapper     mapperData
+		modules    []string
+		validList  map[string][]string
+		importList map[string]map[string]string
+		seenFuncs  map[string]bool
 	)
-	validList = make(map[string][]map[string][]swagger.Parameter)
-	for rt, item := range rootApi.Paths {
-		moduleObj := moduleList[rt]
-		module := moduleObj["module"]
-		if _, ok := validList[module]; !ok {
-			constLines = append(constLines, fmt.Sprintf("%s = \"%s\"", module+validatorSuffix, module+validatorSuffix))
-		}
-		mapLines = append(mapLines, fmt.Sprintf(`GlobalControllerValidator["%s"] = ValidComments{
-				Validator:%s,
-				Method:"%s",
-			}`, rootApi.BasePath+rt, module+validatorSuffix, moduleObj["funcName"]))
-		var parameters []swagger.Parameter
-		if item.Get != nil {
-			parameters = item.Get.Parameters
-		} else if item.Post != nil {
-			parameters = item.Post.Parameters
-		}
-		validList[module] = append(validList[module], map[string][]swagger.Parameter{
-			rt: parameters,
-		})
-	}
-	writeMapFile(validPath, mapLines, constLines)
+	validList = make(map[string][]string)
+	importList = make(map[string]map[string]string)
+	seenFuncs = make(map[string]bool)
+	byPath := make(map[string]int)
+	for _, route := range table.Routes {
+		cname := route.PkgPath + route.Controller
+		module := bu.CamelCase(strings.TrimSuffix(route.Controller, "Controller"))
+		for _, endpoint := range route.Endpoints(controllerRouters[cname]) {
+			parameters, ok := controllerParams[cname][endpoint.Func]
+			if !ok {
+				continue
+			}
+			if _, ok := validList[module]; !ok {
+				mapper.Validators = append(mapper.Validators, module+validatorSuffix)
+				modules = append(modules, module)
+				validList[module] = nil
+				importList[module] = make(map[string]string)
+			}
+			// Several methods may serve the same path, the validators are looked up by "METHOD path".
+			// The path alone still maps to its GET, or else its first, method for the filters of
+			// the apps which look validators up by path.
+			rt := validRoute{
+				Method:    endpoint.Method,
+				Path:      urlReplace(endpoint.Path),
+				Validator: module + validatorSuffix,
+				Func:      endpoint.Func,
+			}
+			if i, ok := byPath[rt.Path]; !ok {
+				rt.ByPath = true
+				byPath[rt.Path] = len(mapper.Routes)
+			} else if rt.Method == "GET" && mapper.Routes[i].Method != "GET" {
+				mapper.Routes[i].ByPath, rt.ByPath = false, true
+				byPath[rt.Path] = len(mapper.Routes)
+			}
+			mapper.Routes = append(mapper.Routes, rt)
 
-	for validator, rtSlice := range validList {
-		var funcSlice []string
-		for _, rtMap := range rtSlice {
-			for rt, parameters := range rtMap {
-				funcSlice = append(funcSlice, genMethodCode(validator, moduleList[rt]["funcName"], parameters))
+			// A method served on several endpoints is only generated once
+			if !seenFuncs[module+"."+endpoint.Func] {
+				seenFuncs[module+"."+endpoint.Func] = true
+				validList[module] = append(validList[module], genMethodCode(module, endpoint.Func, parameters, importList[module]))
 			}
 		}
-		writeFile(validPath, validator, funcSlice)
+	}
+	writeMapFile(validPath, mapper)
+
+	for _, validator := range modules {
+		writeFile(validPath, validator, validList[validator], importList[validator])
 	}
 }
 
-func writeFile(validPath string, module string, funcSlice []string) {
-	w := colors.NewColorWriter(os.Stdout)
+func writeFile(validPath string, module string, funcSlice []string, imports map[string]string) {
 	filename := bu.SnakeString(module) + "_valid"
 	fPath := path.Join(validPath, filename+".go")
-	f, err := openFile(fPath)
-	if err != nil {
-		return
-	}
-
-	fileStr := strings.Replace(VALIDTPL, "{{moduleName}}", module, -1)
-	fileStr = strings.Replace(fileStr, "{{methodList}}", strings.Join(funcSlice, "\n"), -1)
-
-	if _, err := f.WriteString(fileStr); err != nil {
-		beeLogger.Log.Errorf("Could not write model file to '%s': %s", fPath, err)
-		return
-	}
-
-	bu.CloseFile(f)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fPath, "\x1b[0m")
-	bu.FormatSourceCode(fPath)
+	fileStr := templates.MustExecute("validation/valid.go.tpl", validData{
+		Name:    module,
+		Imports: importLines(imports),
+		Methods: funcSlice,
+	})
+	bu.WriteGeneratedFile(fPath, fileStr)
 }
 
-func writeMapFile(validPath string, mapLines []string, constLines []string) {
-	w := colors.NewColorWriter(os.Stdout)
+func writeMapFile(validPath string, mapper mapperData) {
 	fPath := path.Join(validPath, validatorControllerMapName+".go")
-	f, err := openFile(fPath)
-	if err != nil {
-		return
-	}
-
-	fileStr := strings.Replace(MAPPERTPL, "{{mapList}}", strings.Join(mapLines, "\n"), -1)
-	fileStr = strings.Replace(fileStr, "{{constList}}", strings.Join(constLines, "\n"), -1)
-
-	if _, err := f.WriteString(fileStr); err != nil {
-		beeLogger.Log.Errorf("Could not write model file to '%s': %s", fPath, err)
-		return
-	}
-
-	bu.CloseFile(f)
-	fmt.Fprintf(w, "\t%s%screate%s\t %s%s\n", "\x1b[32m", "\x1b[1m", "\x1b[21m", fPath, "\x1b[0m")
-	bu.FormatSourceCode(fPath)
+	fileStr := templates.MustExecute("validation/mapper.go.tpl", mapper)
+	bu.WriteGeneratedFile(fPath, fileStr)
 }
 
-func genMethodCode(module string, funcName string, parameters []swagger.Parameter) string {
-	var (
-		methodList     string
-		parameterRules string
-	)
-	parameterRules = ""
+func genMethodCode(module string, funcName string, parameters []validParam, imports map[string]string) string {
+	var parameterRules string
 	for _, parameter := range parameters {
-		param := strings.ToLower(bu.CamelCase(parameter.Name)[0:1]) + bu.CamelCase(parameter.Name)[1:]
-		switch parameter.Type {
-		case "int":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt", parameter.Name)
-		case "int64":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt64", parameter.Name)
-		case "int32":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt32", parameter.Name)
-		case "int16":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt16", parameter.Name)
-		case "int8":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt8", parameter.Name)
-		case "uint":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetInt", parameter.Name)
-		case "uint64":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetUint64", parameter.Name)
-		case "uint32":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetUint32", parameter.Name)
-		case "uint16":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetUint16", parameter.Name)
-		case "uint8":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetUint8", parameter.Name)
-		case "bool":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetBool", parameter.Name)
-		case "float64":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetFloat", parameter.Name)
-		case "float32":
-			parameterRules += fmt.Sprintf("%s, _ := v.%s(\"%s\")\n", param, "GetFloat", parameter.Name)
-		case "string":
-			parameterRules += fmt.Sprintf("%s := v.%s(\"%s\")\n", param, "GetString", parameter.Name)
-		default:
-			fmt.Println(parameter.Type + ":" + param)
-		}
-		parameterRules += fmt.Sprintf("valid.Required(%s, \"%s\")\n", param, parameter.Name)
+		parameterRules += genParamCode(parameter, imports)
 	}
 
-	methodList += fmt.Sprintf(`
+	return fmt.Sprintf(`
             func (v %sValid) %s(input *context.BeegoInput) {
                 valid := validation.Validation{}
                 v.Input = input
                 %s
                 v.ErrorHandle(valid)
             }`, module, funcName, parameterRules)
-
-	return methodList
 }
 
-func openFile(fpath string) (f *os.File, err error) {
-	if bu.IsExist(fpath) {
-		beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
-		if bu.AskForConfirmation() {
-			f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-			if err != nil {
-				beeLogger.Log.Warnf("%s", err)
-				return f, err
-			}
-		} else {
-			beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
-			return f, errors.New(fmt.Sprintf("Skipped create file '%s'", fpath))
-		}
-	} else {
-		f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-		if err != nil {
-			beeLogger.Log.Warnf("%s", err)
-			return f, err
-		}
-	}
-	return f, err
-}
-
-// analyseNewNamespace returns version and the others params
-func analyseNewNamespace(ce *ast.CallExpr) (first string, others []ast.Expr) {
-	for i, p := range ce.Args {
-		if i == 0 {
-			switch pp := p.(type) {
-			case *ast.BasicLit:
-				first = strings.Trim(pp.Value, `"`)
-			}
-			continue
-		}
-		others = append(others, p)
-	}
-	return
-}
-
-func analyseNSInclude(baseurl string, ce *ast.CallExpr) string {
-	cname := ""
-	for _, p := range ce.Args {
-		var x *ast.SelectorExpr
-		var p1 interface{} = p
-		if ident, ok := p1.(*ast.Ident); ok {
-			if assign, ok := ident.Obj.Decl.(*ast.AssignStmt); ok {
-				if len(assign.Rhs) > 0 {
-					p1 = assign.Rhs[0].(*ast.UnaryExpr)
-				}
-			}
-		}
-		if _, ok := p1.(*ast.UnaryExpr); ok {
-			x = p1.(*ast.UnaryExpr).X.(*ast.CompositeLit).Type.(*ast.SelectorExpr)
-		} else {
-			beeLogger.Log.Warnf("Couldn't determine type\n")
-			continue
-		}
-		if v, ok := importList[fmt.Sprint(x.X)]; ok {
-			cname = v + x.Sel.Name
-		}
-		if apis, ok := controllerList[cname]; ok {
-			for rt, item := range apis {
-				funcName := funcList[cname][rt]
-				if baseurl != "" {
-					rt = baseurl + rt
-				}
-				if len(rootApi.Paths) == 0 {
-					rootApi.Paths = make(map[string]*swagger.Item)
-				}
-				rt = urlReplace(rt)
-				rootApi.Paths[rt] = item
-				moduleName := bu.CamelCase(strings.TrimSuffix(x.Sel.Name, "Controller"))
-				if _, ok := moduleList[rt]; !ok {
-					moduleList[rt] = make(map[string]string)
-				}
-				moduleList[rt]["module"] = moduleName
-				moduleList[rt]["funcName"] = funcName
-			}
-		}
-	}
-	return cname
-}
-
-func analyseControllerPkg(vendorPath, localName, pkgpath string) {
-	pkgpath = strings.Trim(pkgpath, "\"")
+func analyseControllerPkg(vendorPath, pkgpath string) {
 	if isSystemPackage(pkgpath) {
 		return
 	}
-	if pkgpath == "github.com/astaxie/beego" {
+	if pkgpath == bu.BeegoImportPath {
 		return
 	}
-	if localName != "" {
-		importList[localName] = pkgpath
-	} else {
-		pps := strings.Split(pkgpath, "/")
-		importList[pps[len(pps)-1]] = pkgpath
-	}
-	goPaths := bu.GetGOPATHs()
-	if len(goPaths) == 0 {
-		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
-	}
 	pkgRealpath := ""
 
 	wg, _ := filepath.EvalSymlinks(filepath.Join(vendorPath, pkgpath))
 	if utils.FileExists(wg) {
 		pkgRealpath = wg
 	} else {
-		wGoPath := goPaths
-		for _, wg := range wGoPath {
-			wg, _ = filepath.EvalSymlinks(filepath.Join(wg, "src", pkgpath))
-			if utils.FileExists(wg) {
-				pkgRealpath = wg
-				break
-			}
-		}
+		pkgRealpath = bu.ResolvePackageDir(filepath.Dir(vendorPath), pkgpath)
+	}
+	if pkgRealpath == "" {
+		beeLogger.Log.Fatalf("Package '%s' does not exist in the module, GOPATH or vendor path", pkgpath)
 	}
 
 	fileSet := token.NewFileSet()
@@ -401,13 +228,23 @@ func analyseControllerPkg(vendorPath, localName, pkgpath string) {
 	}
 	for _, pkg := range astPkgs {
 		for _, fl := range pkg.Files {
+			// Packages of the body models referenced by the @Param annotations
+			imports := make(map[string]string)
+			for _, im := range fl.Imports {
+				pkg := strings.Trim(im.Path.Value, `"`)
+				if im.Name != nil {
+					imports[im.Name.Name] = pkg
+				} else {
+					imports[path.Base(pkg)] = pkg
+				}
+			}
 			for _, d := range fl.Decls {
 				switch specDecl := d.(type) {
 				case *ast.FuncDecl:
 					if specDecl.Recv != nil && len(specDecl.Recv.List) > 0 {
 						if t, ok := specDecl.Recv.List[0].Type.(*ast.StarExpr); ok {
 							// Parse controller method
-							_ = parserComments(specDecl, fmt.Sprint(t.X), pkgpath)
+							_ = parserComments(specDecl, fmt.Sprint(t.X), pkgpath, imports)
 						}
 					}
 				}
@@ -437,12 +274,10 @@ func isSystemPackage(pkgpath string) bool {
 }
 
 // parse the func comments
-func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
+func parserComments(f *ast.FuncDecl, controllerName, pkgpath string, imports map[string]string) error {
 	var routerPath string
 	var HTTPMethod string
-	opts := swagger.Operation{
-		Responses: make(map[string]swagger.Response),
-	}
+	var parameters []validParam
 	funcName := f.Name.String()
 	comments := f.Doc
 	funcParamMap := buildParamMap(f.Type.Params)
@@ -464,8 +299,9 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 					HTTPMethod = "GET"
 				}
 			} else if strings.HasPrefix(t, "@Param") {
-				para := swagger.Parameter{}
-				p := getparams(strings.TrimSpace(t[len("@Param "):]))
+				para := validParam{}
+				p, rules := splitRulesToken(getparams(strings.TrimSpace(t[len("@Param "):])))
+				para.Rules, para.Message = ParseRules(rules)
 				if len(p) < 4 {
 					beeLogger.Log.Fatal(controllerName + "_" + funcName + "'s comments @Param should have at least 4 params")
 				}
@@ -481,17 +317,24 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 				}
 
 				para.In = p[1]
-				pp := strings.Split(p[2], ".")
-				typ := pp[len(pp)-1]
-				if len(pp) >= 2 {
-					if p[1] == "body" && strings.HasPrefix(p[2], "[]") {
-						p[2] = p[2][2:]
+				typ := p[2]
+				if typ == "auto" {
+					typ = paramType
+				}
+				setParamType(&para.Parameter, typ, pkgpath, controllerName)
+				if para.Type == astTypeArray {
+					para.ItemType = basicTypes[strings.TrimPrefix(typ, "[]")]
+				}
+				if _, ok := basicTypes[strings.TrimPrefix(typ, "[]")]; !ok && para.In == "body" {
+					// The body is decoded into its model and validated with the valid tags of the struct
+					para.ModelPkg, para.ModelType = pkgpath, strings.TrimPrefix(typ, "[]")
+					if pp := strings.SplitN(para.ModelType, ".", 2); len(pp) == 2 {
+						para.ModelPkg, para.ModelType = imports[pp[0]], pp[1]
 					}
-				} else {
-					if typ == "auto" {
-						typ = paramType
+					if para.ModelPkg == "" {
+						beeLogger.Log.Warnf("[%s.%s] Unknown package of the body type '%s'", controllerName, funcName, typ)
+						para.ModelType = ""
 					}
-					setParamType(&para, typ, pkgpath, controllerName)
 				}
 				switch len(p) {
 				case 5:
@@ -504,61 +347,38 @@ func parserComments(f *ast.FuncDecl, controllerName, pkgpath string) error {
 				default:
 					para.Description = strings.Trim(p[3], `" `)
 				}
-				opts.Parameters = append(opts.Parameters, para)
+				parameters = append(parameters, para)
 			}
 		}
 	}
 
-	if routerPath != "" {
-		// Go over function parameters which were not mapped and create swagger params for them
-		for name, typ := range funcParamMap {
-			para := swagger.Parameter{}
-			para.Name = name
-			setParamType(&para, typ, pkgpath, controllerName)
-			if paramInPath(name, routerPath) {
-				para.In = "path"
-			} else {
-				para.In = "query"
-			}
-			opts.Parameters = append(opts.Parameters, para)
-		}
+	if comments == nil {
+		return nil
+	}
 
-		var item *swagger.Item
-		if itemList, ok := controllerList[pkgpath+controllerName]; ok {
-			if it, ok := itemList[routerPath]; !ok {
-				item = &swagger.Item{}
-			} else {
-				item = it
-			}
+	// Go over function parameters which were not mapped and create swagger params for them
+	for name, typ := range funcParamMap {
+		para := validParam{}
+		para.Name = name
+		setParamType(&para.Parameter, typ, pkgpath, controllerName)
+		if paramInPath(name, routerPath) {
+			para.In = "path"
 		} else {
-			controllerList[pkgpath+controllerName] = make(map[string]*swagger.Item)
-			item = &swagger.Item{}
+			para.In = "query"
 		}
-		for _, hm := range strings.Split(HTTPMethod, ",") {
-			switch hm {
-			case "GET":
-				item.Get = &opts
-			case "POST":
-				item.Post = &opts
-			case "PUT":
-				item.Put = &opts
-			case "PATCH":
-				item.Patch = &opts
-			case "DELETE":
-				item.Delete = &opts
-			case "HEAD":
-				item.Head = &opts
-			case "OPTIONS":
-				item.Options = &opts
-			}
-		}
-		controllerList[pkgpath+controllerName][routerPath] = item
+		parameters = append(parameters, para)
+	}
 
-		if _, ok := funcList[pkgpath+controllerName]; !ok {
-			funcList[pkgpath+controllerName] = make(map[string]string)
-		}
-		funcList[pkgpath+controllerName][routerPath] = funcName
+	if _, ok := controllerParams[pkgpath+controllerName]; !ok {
+		controllerParams[pkgpath+controllerName] = make(map[string][]validParam)
+		controllerRouters[pkgpath+controllerName] = make(map[string]routes.Annotation)
+	}
+	controllerParams[pkgpath+controllerName][funcName] = parameters
+	annotation := routes.Annotation{}
+	if routerPath != "" {
+		annotation = routes.Annotation{Path: routerPath, Methods: strings.Split(HTTPMethod, ",")}
 	}
+	controllerRouters[pkgpath+controllerName][funcName] = annotation
 
 	return nil
 }
@@ -717,20 +537,24 @@ package validator
 import (
     "github.com/astaxie/beego/context"
     "github.com/astaxie/beego/validation"
+    {{- range .Imports}}
+    {{.}}
+    {{- end}}
 )
 
-type {{moduleName}}Valid struct {
+type {{.Name}}Valid struct {
     Validator
 }
 
-func New{{moduleName}}Valid() IValidator {
-    return &{{moduleName}}Valid{}
+func New{{.Name}}Valid() IValidator {
+    return &{{.Name}}Valid{}
 }
 
-{{methodList}}
+{{range .Methods}}{{.}}
+{{end}}
 
 func init() {
-    Register({{moduleName}}Validator, New{{moduleName}}Valid)
+    Register({{.Name}}Validator, New{{.Name}}Valid)
 }
 `
 
@@ -738,10 +562,20 @@ const MAPPERTPL = `
 package validator
 
 const (
-    {{constList}}
+    {{- range .Validators}}
+    {{.}} = "{{.}}"
+    {{- end}}
 )
 
 func init() {
-    {{mapList}}
+    {{- range .Routes}}
+    GlobalControllerValidator["{{.Method}} {{.Path}}"] = ValidComments{
+        Validator: {{.Validator}},
+        Method:    "{{.Func}}",
+    }
+    {{- if .ByPath}}
+    GlobalControllerValidator["{{.Path}}"] = GlobalControllerValidator["{{.Method}} {{.Path}}"]
+    {{- end}}
+    {{- end}}
 }
 `
@@ -0,0 +1,367 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	beeLogger "github.com/iwooyun/bee/logger"
+	"github.com/iwooyun/bee/logger/colors"
+)
+
+// GenFiles is the policy of the generators for the files they write,
+// set by the -force, -skip-existing and -dry-run flags
+var GenFiles struct {
+	Force        bool // Overwrite the existing files without asking
+	SkipExisting bool // Keep the existing files without asking
+	DryRun       bool // Print the diff of each file instead of writing it
+}
+
+// genStats counts the files handled by WriteGeneratedFile
+var genStats struct {
+	created, updated, unchanged, skipped int
+}
+
+// diffContext is the number of unchanged lines around the changes of a diff
+const diffContext = 3
+
+// Merger merges the content generated for the existing file fpath into its current content,
+// and reports whether it could. An unmerged file is overwritten following GenFiles.
+type Merger func(fpath, current, generated string) (string, bool)
+
+// WriteGeneratedFile writes the generated content to fpath, formatted with gofmt when it
+// is a Go source file, and reports whether the file was written. An existing file with a
+// different content is overwritten or kept following GenFiles, the user is asked by default.
+// In a dry run, the unified diff of the file is printed and nothing is written.
+func WriteGeneratedFile(fpath, content string) bool {
+	return WriteMergedFile(fpath, content, nil)
+}
+
+// WriteMergedFile is WriteGeneratedFile with the existing file merged by merge, e.g.
+// MergeRegions, instead of overwritten
+func WriteMergedFile(fpath, content string, merge Merger) bool {
+	old, err := ioutil.ReadFile(fpath)
+	exists := err == nil
+	merged := false
+	if exists && merge != nil {
+		if m, ok := merge(fpath, string(old), content); ok {
+			content, merged = m, true
+		}
+	}
+
+	if strings.HasSuffix(fpath, ".go") {
+		if src, err := format.Source([]byte(content)); err != nil {
+			beeLogger.Log.Warnf("Error while running gofmt on '%s': %s", fpath, err)
+		} else {
+			content = string(src)
+		}
+	}
+
+	switch {
+	case exists && string(old) == content:
+		genStats.unchanged++
+		printGenStatus("identical", "\x1b[34m", fpath)
+		return false
+	case GenFiles.DryRun:
+		fmt.Print(unifiedDiff(fpath, string(old), content, exists))
+		if exists {
+			genStats.updated++
+		} else {
+			genStats.created++
+		}
+		return false
+	case exists && GenFiles.SkipExisting:
+		genStats.skipped++
+		printGenStatus("skip", "\x1b[33m", fpath)
+		return false
+	case exists && !GenFiles.Force && !merged:
+		beeLogger.Log.Warnf("'%s' already exists. Do you want to overwrite it? [Yes|No] ", fpath)
+		if !AskForConfirmation() {
+			genStats.skipped++
+			beeLogger.Log.Warnf("Skipped create file '%s'", fpath)
+			return false
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		beeLogger.Log.Errorf("Could not create directory of '%s': %s", fpath, err)
+		return false
+	}
+	if err := ioutil.WriteFile(fpath, []byte(content), 0666); err != nil {
+		beeLogger.Log.Errorf("Could not write file to '%s': %s", fpath, err)
+		return false
+	}
+	switch {
+	case merged:
+		genStats.updated++
+		printGenStatus("merge", "\x1b[33m", fpath)
+	case exists:
+		genStats.updated++
+		printGenStatus("update", "\x1b[33m", fpath)
+	default:
+		genStats.created++
+		printGenStatus("create", "\x1b[32m", fpath)
+	}
+	return true
+}
+
+// MergeRegions replaces the generated regions of the current content of fpath by those of
+// the same name of the generated content. A region spans the lines from a line
+// "// bee:generated begin <name>" to a line "// bee:generated end <name>", everything
+// outside of the regions is left as is. Files without regions are not merged.
+func MergeRegions(fpath, current, generated string) (string, bool) {
+	curLines, newLines := splitLines(current), splitLines(generated)
+	curRegions, err := findRegions(curLines)
+	if err != nil {
+		beeLogger.Log.Warnf("Could not merge the generated regions of '%s': %s", fpath, err)
+		return "", false
+	}
+	if len(curRegions) == 0 {
+		return "", false
+	}
+	newRegions, err := findRegions(newLines)
+	if err != nil {
+		beeLogger.Log.Warnf("Could not merge the generated regions of '%s': %s", fpath, err)
+		return "", false
+	}
+
+	byName := make(map[string]region, len(newRegions))
+	for _, r := range newRegions {
+		byName[r.name] = r
+	}
+	var sb strings.Builder
+	last := 0
+	for _, r := range curRegions {
+		nr, ok := byName[r.name]
+		if !ok {
+			continue
+		}
+		delete(byName, r.name)
+		sb.WriteString(strings.Join(curLines[last:r.begin], ""))
+		sb.WriteString(strings.Join(newLines[nr.begin:nr.end+1], ""))
+		last = r.end + 1
+	}
+	sb.WriteString(strings.Join(curLines[last:], ""))
+	for _, r := range newRegions {
+		if _, ok := byName[r.name]; ok {
+			beeLogger.Log.Warnf("Generated region '%s' not found in '%s', its update is left out", r.name, fpath)
+		}
+	}
+	return sb.String(), true
+}
+
+const (
+	regionBegin = "// bee:generated begin "
+	regionEnd   = "// bee:generated end "
+)
+
+// region is a generated region of a file, from its line begin to its line end
+type region struct {
+	name       string
+	begin, end int
+}
+
+// findRegions returns the generated regions of the lines of a file
+func findRegions(lines []string) (regions []region, err error) {
+	open := -1
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, regionBegin):
+			if open >= 0 {
+				return nil, fmt.Errorf("region '%s' begins inside region '%s', line %d", strings.TrimPrefix(line, regionBegin), regions[open].name, i+1)
+			}
+			open = len(regions)
+			regions = append(regions, region{name: strings.TrimPrefix(line, regionBegin), begin: i})
+		case strings.HasPrefix(line, regionEnd):
+			name := strings.TrimPrefix(line, regionEnd)
+			if open < 0 || regions[open].name != name {
+				return nil, fmt.Errorf("region '%s' ends without beginning, line %d", name, i+1)
+			}
+			regions[open].end = i
+			open = -1
+		}
+	}
+	if open >= 0 {
+		return nil, fmt.Errorf("region '%s' does not end", regions[open].name)
+	}
+	return
+}
+
+// GenUnattended reports whether the generators run without asking the user,
+// i.e. with either -force, -skip-existing or -dry-run
+func GenUnattended() bool {
+	return GenFiles.Force || GenFiles.SkipExisting || GenFiles.DryRun
+}
+
+// MkdirGenerated creates the directory dir of generated files, unless in a dry run
+func MkdirGenerated(dir string) error {
+	if GenFiles.DryRun {
+		return nil
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// PrintGenSummary logs the number of files created, updated, unchanged and skipped by
+// WriteGeneratedFile, if any
+func PrintGenSummary() {
+	if genStats.created+genStats.updated+genStats.unchanged+genStats.skipped == 0 {
+		return
+	}
+	summary := fmt.Sprintf("%d created, %d updated, %d unchanged, %d skipped",
+		genStats.created, genStats.updated, genStats.unchanged, genStats.skipped)
+	if GenFiles.DryRun {
+		beeLogger.Log.Infof("Dry run, no file written: %s", summary)
+		return
+	}
+	beeLogger.Log.Infof("Files: %s", summary)
+}
+
+func printGenStatus(status, color, fpath string) {
+	w := colors.NewColorWriter(os.Stdout)
+	fmt.Fprintf(w, "\t%s%s%s%s\t %s%s\n", color, "\x1b[1m", status, "\x1b[21m", fpath, "\x1b[0m")
+}
+
+// unifiedDiff returns the unified diff turning the content old of fpath into new
+func unifiedDiff(fpath, old, new string, exists bool) string {
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	if exists {
+		fmt.Fprintf(&sb, "--- %s\n", fpath)
+	} else {
+		sb.WriteString("--- /dev/null\n")
+	}
+	fmt.Fprintf(&sb, "+++ %s\n", fpath)
+
+	// a hunk spans the changes separated by less than 2*diffContext unchanged lines
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end, equal := i, 0
+		for end < len(ops) && equal < 2*diffContext {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+			end++
+		}
+		if equal > diffContext {
+			end -= equal - diffContext
+		}
+
+		aStart, bStart, aCount, bCount := ops[start].a, ops[start].b, 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// hunkRange returns the range of a hunk header, start being the 0-based index of its first line
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diffOp is a line of a diff: unchanged (' '), removed ('-') or added ('+'),
+// along with the indexes of the next lines of both sides
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// diffLines returns the shortest edit script turning the lines a into the lines b,
+// computed from their longest common subsequence
+func diffLines(a, b []string) (ops []diffOp) {
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return
+}
+
+// splitLines splits s into lines, keeping their line feed
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
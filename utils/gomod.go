@@ -0,0 +1,299 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// BeegoImportPath is the import path of the Beego framework
+const BeegoImportPath = "github.com/astaxie/beego"
+
+var goVersionRegex = regexp.MustCompile(`^go([0-9]+\.[0-9]+)`)
+
+// GoModule describes a Go module, as declared by its go.mod file
+type GoModule struct {
+	Dir     string            // Directory containing the go.mod file
+	Path    string            // Module import path
+	Require map[string]string // Required module path => version
+	Replace map[string]string // Replaced module path => local directory or module@version
+}
+
+// ModulesEnabled reports whether the go tool runs in module-aware mode,
+// i.e. GO111MODULE is not set to "off".
+func ModulesEnabled() bool {
+	return os.Getenv("GO111MODULE") != "off"
+}
+
+// FindGoMod looks for a go.mod file in dir and its parents.
+// It returns the path of the file, or an empty string if none is found.
+func FindGoMod(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		file := filepath.Join(dir, "go.mod")
+		if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// GetModule returns the module that contains dir,
+// or nil if modules are disabled or dir is not inside a module.
+func GetModule(dir string) *GoModule {
+	if !ModulesEnabled() {
+		return nil
+	}
+	file := FindGoMod(dir)
+	if file == "" {
+		return nil
+	}
+	mod, err := ParseGoMod(file)
+	if err != nil {
+		return nil
+	}
+	return mod
+}
+
+// IsInModule checks whether the path is inside of a Go module or not
+func IsInModule(thePath string) bool {
+	return GetModule(thePath) != nil
+}
+
+// ParseGoMod reads the module path, requirements and replacements of a go.mod file
+func ParseGoMod(file string) (*GoModule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mod := &GoModule{
+		Dir:     filepath.Dir(file),
+		Require: make(map[string]string),
+		Replace: make(map[string]string),
+	}
+	block := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			mod.addDirective(block, fields)
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		mod.addDirective(fields[0], fields[1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if mod.Path == "" {
+		return nil, fmt.Errorf("no module directive found in '%s'", file)
+	}
+	return mod, nil
+}
+
+func (mod *GoModule) addDirective(verb string, args []string) {
+	for i := range args {
+		args[i] = strings.Trim(args[i], "\"`")
+	}
+	switch verb {
+	case "module":
+		if len(args) > 0 {
+			mod.Path = args[0]
+		}
+	case "require":
+		if len(args) > 1 {
+			mod.Require[args[0]] = args[1]
+		}
+	case "replace":
+		// replace old [version] => new [version]
+		for i, arg := range args {
+			if arg != "=>" || i == 0 || i+1 >= len(args) {
+				continue
+			}
+			target := args[i+1]
+			if i+2 < len(args) {
+				target += "@" + args[i+2]
+			} else if !filepath.IsAbs(target) {
+				target = filepath.Join(mod.Dir, target)
+			}
+			mod.Replace[args[0]] = target
+		}
+	}
+}
+
+// ImportPath returns the import path of a directory inside the module
+func (mod *GoModule) ImportPath(dir string) string {
+	rel, err := filepath.Rel(mod.Dir, dir)
+	if err != nil || rel == "." {
+		return mod.Path
+	}
+	return path.Join(mod.Path, filepath.ToSlash(rel))
+}
+
+// BeegoVersion returns the Beego version required by the module,
+// without its leading "v", or an empty string if Beego is not required.
+func (mod *GoModule) BeegoVersion() string {
+	return strings.TrimPrefix(mod.Require[BeegoImportPath], "v")
+}
+
+// PackageDir returns the directory holding the sources of the package pkgpath,
+// as seen from the module: the module itself, its vendor directory, a replaced
+// directory or the module cache. It returns an empty string if none matches.
+func (mod *GoModule) PackageDir(pkgpath string) string {
+	if pkgpath == mod.Path || strings.HasPrefix(pkgpath, mod.Path+"/") {
+		return filepath.Join(mod.Dir, filepath.FromSlash(strings.TrimPrefix(pkgpath, mod.Path)))
+	}
+	if dir := filepath.Join(mod.Dir, "vendor", filepath.FromSlash(pkgpath)); IsExist(dir) {
+		return dir
+	}
+
+	// Find the longest required module path which provides the package
+	modPath := ""
+	for p := range mod.Require {
+		if (pkgpath == p || strings.HasPrefix(pkgpath, p+"/")) && len(p) > len(modPath) {
+			modPath = p
+		}
+	}
+	if modPath == "" {
+		return ""
+	}
+	sub := filepath.FromSlash(strings.TrimPrefix(pkgpath, modPath))
+
+	var root string
+	if target, ok := mod.Replace[modPath]; ok {
+		if i := strings.LastIndex(target, "@"); i > 0 {
+			root = moduleCacheDir(target[:i], target[i+1:])
+		} else {
+			root = target
+		}
+	} else {
+		root = moduleCacheDir(modPath, mod.Require[modPath])
+	}
+	if root == "" {
+		return ""
+	}
+	if dir := filepath.Join(root, sub); IsExist(dir) {
+		return dir
+	}
+	return ""
+}
+
+// moduleCacheDir returns the directory of a module version in the module cache
+func moduleCacheDir(modPath, version string) string {
+	cache := os.Getenv("GOMODCACHE")
+	if cache == "" {
+		gps := GetGOPATHs()
+		if len(gps) == 0 {
+			return ""
+		}
+		cache = filepath.Join(gps[0], "pkg", "mod")
+	}
+	return filepath.Join(cache, filepath.FromSlash(escapeModulePath(modPath)+"@"+escapeModulePath(version)))
+}
+
+// escapeModulePath escapes upper case letters the way the module cache does,
+// e.g. github.com/Masterminds/semver => github.com/!masterminds/semver
+func escapeModulePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// GetPackagePath returns the import path of the given directory.
+// The enclosing Go module is used if there is one, GOPATH otherwise.
+func GetPackagePath(dir string) (string, error) {
+	if mod := GetModule(dir); mod != nil {
+		return mod.ImportPath(dir), nil
+	}
+	for _, gopath := range GetGOPATHs() {
+		gsrcpath, _ := filepath.EvalSymlinks(filepath.Join(gopath, "src"))
+		if gsrcpath == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(dir), strings.ToLower(gsrcpath+string(filepath.Separator))) {
+			return filepath.ToSlash(dir[len(gsrcpath)+1:]), nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is neither inside a Go module nor inside GOPATH", dir)
+}
+
+// ResolvePackageDir returns the directory holding the sources of the package pkgpath,
+// looking it up from the module enclosing dir first and from GOPATH afterwards.
+// It returns an empty string if the package cannot be found.
+func ResolvePackageDir(dir, pkgpath string) string {
+	if mod := GetModule(dir); mod != nil {
+		if pkgDir := mod.PackageDir(pkgpath); pkgDir != "" {
+			return pkgDir
+		}
+	}
+	for _, gopath := range GetGOPATHs() {
+		pkgDir, _ := filepath.EvalSymlinks(filepath.Join(gopath, "src", filepath.FromSlash(pkgpath)))
+		if pkgDir != "" && IsExist(pkgDir) {
+			return pkgDir
+		}
+	}
+	return ""
+}
+
+// NeedGoMod reports whether a new application created at appPath
+// should be initialized as a Go module of its own.
+func NeedGoMod(appPath string) bool {
+	return ModulesEnabled() && !IsInGOPATH(appPath) && !IsInModule(appPath)
+}
+
+// WriteGoMod creates the go.mod file of a new application
+func WriteGoMod(appPath, modulePath string) {
+	goVersion := "1.12"
+	if v := goVersionRegex.FindStringSubmatch(runtime.Version()); v != nil {
+		goVersion = v[1]
+	}
+	WriteGeneratedFile(filepath.Join(appPath, "go.mod"), fmt.Sprintf("module %s\n\ngo %s\n\nrequire %s v1.12.0\n", modulePath, goVersion, BeegoImportPath))
+}
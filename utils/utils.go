@@ -302,13 +302,24 @@ func Tmpl(text string, data interface{}) {
 	}
 }
 
+// CheckEnv returns the path and the import path of a new application.
+// Inside a Go module the application is created in the current directory and
+// its import path derives from the module path. Outside of GOPATH, with modules
+// enabled, it is created in the current directory as the root of a new module.
 func CheckEnv(appname string) (apppath, packpath string, err error) {
+	currpath, _ := os.Getwd()
+	currpath = filepath.Join(currpath, appname)
+	if mod := GetModule(filepath.Dir(currpath)); mod != nil {
+		return currpath, mod.ImportPath(currpath), nil
+	}
+
 	gps := GetGOPATHs()
 	if len(gps) == 0 {
+		if ModulesEnabled() {
+			return currpath, filepath.ToSlash(filepath.Clean(appname)), nil
+		}
 		beeLogger.Log.Fatal("GOPATH environment variable is not set or empty")
 	}
-	currpath, _ := os.Getwd()
-	currpath = filepath.Join(currpath, appname)
 	for _, gpath := range gps {
 		gsrcpath := filepath.Join(gpath, "src")
 		if strings.HasPrefix(strings.ToLower(currpath), strings.ToLower(gsrcpath)) {
@@ -317,6 +328,10 @@ func CheckEnv(appname string) (apppath, packpath string, err error) {
 		}
 	}
 
+	if ModulesEnabled() {
+		return currpath, filepath.ToSlash(filepath.Clean(appname)), nil
+	}
+
 	// In case of multiple paths in the GOPATH, by default
 	// we use the first path
 	gopath := gps[0]
@@ -426,6 +441,15 @@ func GetFileModTime(path string) int64 {
 	return fi.ModTime().Unix()
 }
 
+// SQLDriverName returns the name a database driver is registered with in database/sql,
+// SQLite being accepted as both sqlite and sqlite3
+func SQLDriverName(driver string) string {
+	if driver == "sqlite" {
+		return "sqlite3"
+	}
+	return driver
+}
+
 func defaultGOPATH() string {
 	env := "HOME"
 	if runtime.GOOS == "windows" {